@@ -1,29 +1,124 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"errors"
 	"flag"
+	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
+	"github.com/dever-labs/devx/internal/config"
 	"github.com/dever-labs/devx/internal/doctor"
+	"github.com/dever-labs/devx/internal/ui"
 )
 
 func runDoctor(ctx context.Context, args []string) error {
 	fs := flag.NewFlagSet("doctor", flag.ExitOnError)
-	fix := fs.Bool("fix", false, "Attempt fixes")
+	fix := fs.Bool("fix", false, "Apply fixes, asking before each")
+	yes := fs.Bool("yes", false, "With --fix, apply fixes without asking")
+	output := fs.String("output", "", ui.OutputUsage)
 	_ = fs.Parse(args)
+	if *yes && !*fix {
+		return fmt.Errorf("--yes only applies with --fix")
+	}
 
-	manifest, _, _, _ := loadProfile("")
+	printer, err := ui.NewPrinter(*output)
+	if err != nil {
+		return err
+	}
+	// Documents own stdout; fix progress and prompts go to stderr.
+	var progress io.Writer = os.Stdout
+	if printer.Structured() {
+		progress = os.Stderr
+	}
 
-	report := doctor.Run(ctx, doctor.Options{
-		Manifest: manifest,
-		Fix:      *fix,
-	})
+	manifest, profName, prof, _ := loadProfile("")
 
-	doctor.PrintReport(os.Stdout, report)
+	opts := doctor.Options{
+		Manifest:     manifest,
+		Fix:          *fix,
+		Out:          progress,
+		ManifestPath: manifestFile,
+		Profile:      prof,
+		ProfileName:  profName,
+		VerifyLock: func(ctx context.Context) error {
+			_, err := verifyLock(ctx, nil)
+			return err
+		},
+		UpdateLock: func(ctx context.Context) error {
+			_, err := updateLock(ctx, nil)
+			return err
+		},
+	}
+	if manifest != nil {
+		opts.Running = stackRunning(ctx, manifest, profName, prof)
+	}
+	if !*yes {
+		reader := bufio.NewReader(os.Stdin)
+		opts.Confirm = func(fix string) bool {
+			fmt.Fprintf(progress, "%s\nApply this fix? [y/N] ", fix)
+			answer, _ := reader.ReadString('\n')
+			answer = strings.ToLower(strings.TrimSpace(answer))
+			return answer == "y" || answer == "yes"
+		}
+	}
+
+	report := doctor.Run(ctx, opts)
+
+	doc := doctorDocument{
+		TypeMeta: ui.Meta("DoctorReport"),
+		OK:       !report.HasFailures(),
+		Checks:   report.Checks,
+	}
+	err = printer.Print(os.Stdout, doc, func(w io.Writer) {
+		doctor.PrintReport(w, report)
+		if fixable := len(report.Fixable()); fixable > 0 && !*fix {
+			fmt.Fprintf(w, "\nRun 'devx doctor --fix' to apply %d fix(es).\n", fixable)
+		}
+	})
+	if err != nil {
+		return err
+	}
 	if report.HasFailures() {
 		return errors.New("doctor found failures")
 	}
 	return nil
 }
+
+// doctorDocument is the output of `devx doctor --output json|yaml`; see
+// schemas/output/doctor.schema.json.
+type doctorDocument struct {
+	ui.TypeMeta
+	OK     bool           `json:"ok"`
+	Checks []doctor.Check `json:"checks"`
+}
+
+// stackRunning reports whether services of the compose stack are running, in
+// which case their published ports are expected to be taken.
+func stackRunning(ctx context.Context, manifest *config.Manifest, profName string, prof *config.Profile) bool {
+	if profileRuntime(prof) != "compose" || readState() == nil {
+		return false
+	}
+	composePath := filepath.Join(devxDir, composeFile)
+	if !fileExists(composePath) {
+		return false
+	}
+	rt, err := selectRuntime(ctx)
+	if err != nil {
+		return false
+	}
+	statuses, err := rt.Status(ctx, composePath, manifest.Project.Name)
+	if err != nil {
+		return false
+	}
+	for _, st := range statuses {
+		if st.State == "running" {
+			return true
+		}
+	}
+	return false
+}
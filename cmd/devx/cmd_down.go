@@ -5,20 +5,32 @@ import (
 	"flag"
 	"fmt"
 	"path/filepath"
+	"strings"
 
+	"github.com/dever-labs/devx/internal/config"
 	"github.com/dever-labs/devx/internal/k8s"
+	"github.com/dever-labs/devx/internal/runtime"
+	"github.com/dever-labs/devx/internal/runtime/kubernetes"
 )
 
 func runDown(ctx context.Context, args []string) error {
 	fs := flag.NewFlagSet("down", flag.ExitOnError)
 	volumes := fs.Bool("volumes", false, "Remove volumes")
+	cluster := clusterFlags(fs)
 	_ = fs.Parse(args)
+	if *cluster == (k8s.Kubectl{}) {
+		*cluster = clusterFromState()
+	}
 
 	manifest, profName, prof, err := loadProfile("")
 	if err != nil {
 		return err
 	}
 
+	if fs.NArg() > 0 {
+		return runDownServices(ctx, manifest, profName, prof, fs.Args(), *volumes, *cluster)
+	}
+
 	rt, err := selectRuntime(ctx)
 	if err != nil {
 		return err
@@ -27,7 +39,7 @@ func runDown(ctx context.Context, args []string) error {
 	enableTelemetry := telemetryFromState()
 	runtimeMode := profileRuntime(prof)
 	if runtimeMode == "k8s" {
-		return runDownK8s(ctx)
+		return runDownK8s(ctx, manifest.Project.Name, *cluster, *volumes)
 	}
 
 	composePath := filepath.Join(devxDir, composeFile)
@@ -35,7 +47,7 @@ func runDown(ctx context.Context, args []string) error {
 		if err := ensureDevxDir(); err != nil {
 			return err
 		}
-		if err := writeCompose(composePath, manifest, profName, prof, nil, enableTelemetry); err != nil {
+		if err := writeCompose(ctx, composePath, manifest, profName, prof, nil, enableTelemetry); err != nil {
 			return err
 		}
 	}
@@ -47,15 +59,52 @@ func runDown(ctx context.Context, args []string) error {
 		}
 	}
 
-	return rt.Down(ctx, composePath, manifest.Project.Name, *volumes)
+	return rt.Down(ctx, composePath, manifest.Project.Name, runtime.DownOptions{RemoveVolumes: *volumes})
 }
 
-func runDownK8s(ctx context.Context) error {
-	path := filepath.Join(devxDir, k8sFile)
-	if !fileExists(path) {
-		return fmt.Errorf("%s not found; run 'devx render k8s --write' or 'devx up --profile <k8s profile>'", path)
+// runDownServices stops the named services and the dependencies that no other
+// running service still needs.
+func runDownServices(ctx context.Context, manifest *config.Manifest, profName string, prof *config.Profile, names []string, removeVolumes bool, cluster k8s.Kubectl) error {
+	rt, path, err := prepareRuntime(ctx, manifest, profName, prof)
+	if err != nil {
+		return err
+	}
+	if k8sRuntime, ok := rt.(*kubernetes.Runtime); ok {
+		k8sRuntime.Cluster = cluster
+	}
+
+	statuses, err := rt.Status(ctx, path, manifest.Project.Name)
+	if err != nil {
+		return err
+	}
+	var running []string
+	for _, status := range statuses {
+		if status.State == "running" {
+			running = append(running, status.Name)
+		}
+	}
+
+	stop, err := servicesToStop(prof, names, running)
+	if err != nil {
+		return err
+	}
+
+	active := subsetProfile(prof, stop)
+	if len(active.Hooks.BeforeDown) > 0 {
+		fmt.Println("Running beforeDown hooks...")
+		if err := runHooks(ctx, rt, path, manifest.Project.Name, active.Hooks.BeforeDown); err != nil {
+			return err
+		}
 	}
-	if err := k8s.Delete(ctx, path); err != nil {
+
+	fmt.Printf("Stopping %s\n", strings.Join(stop, ", "))
+	return rt.Down(ctx, path, manifest.Project.Name, runtime.DownOptions{RemoveVolumes: removeVolumes, Services: stop})
+}
+
+// runDownK8s deletes the objects devx applied for the project, found by their
+// ownership labels.
+func runDownK8s(ctx context.Context, project string, cluster k8s.Kubectl, volumes bool) error {
+	if err := cluster.Delete(ctx, project, volumes); err != nil {
 		return err
 	}
 	fmt.Println("Kubernetes resources deleted")
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"path/filepath"
 )
 
 func runExec(ctx context.Context, args []string) error {
@@ -31,24 +30,11 @@ func runExec(ctx context.Context, args []string) error {
 		return err
 	}
 
-	if profileRuntime(prof) == "k8s" {
-		return errors.New("exec for k8s runtime is not supported yet")
-	}
-
-	rt, err := selectRuntime(ctx)
+	rt, composePath, err := prepareRuntime(ctx, manifest, profName, prof)
 	if err != nil {
 		return err
 	}
 
-	enableTelemetry := telemetryFromState()
-	composePath := filepath.Join(devxDir, composeFile)
-	if err := ensureDevxDir(); err != nil {
-		return err
-	}
-	if err := writeCompose(composePath, manifest, profName, prof, nil, enableTelemetry); err != nil {
-		return err
-	}
-
 	code, err := rt.Exec(ctx, composePath, manifest.Project.Name, service, cmdArgs)
 	if err != nil {
 		return err
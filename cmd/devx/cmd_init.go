@@ -4,6 +4,8 @@ import (
 	"flag"
 	"fmt"
 	"os"
+
+	"github.com/dever-labs/devx/internal/doctor"
 )
 
 func runInit(args []string) error {
@@ -24,7 +26,7 @@ func runInit(args []string) error {
 		return err
 	}
 
-	if err := ensureGitignore(); err != nil {
+	if err := doctor.EnsureGitignore("."); err != nil {
 		return err
 	}
 
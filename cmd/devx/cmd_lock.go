@@ -3,45 +3,229 @@ package main
 import (
 	"context"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
 
+	"github.com/dever-labs/devx/internal/config"
 	"github.com/dever-labs/devx/internal/lock"
-	"github.com/dever-labs/devx/internal/runtime"
+	"github.com/dever-labs/devx/internal/registry"
+	"github.com/dever-labs/devx/internal/ui"
+	"github.com/dever-labs/devx/internal/util"
 )
 
 func runLock(ctx context.Context, args []string) error {
-	if len(args) == 0 || args[0] != "update" {
-		return errors.New("lock requires 'update'")
+	if len(args) == 0 {
+		return errors.New("lock requires 'update' or 'verify'")
 	}
+	switch args[0] {
+	case "update":
+		return runLockUpdate(ctx, args[1:])
+	case "verify":
+		return runLockVerify(ctx, args[1:])
+	default:
+		return errors.New("lock requires 'update' or 'verify'")
+	}
+}
 
-	manifest, profName, prof, err := loadProfile("")
+// runLockUpdate resolves the digests of the images used by the given
+// profiles, or by every profile, from their registries and merges them into
+// devx.lock.
+func runLockUpdate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("lock update", flag.ExitOnError)
+	output := fs.String("output", "", ui.OutputUsage)
+	_ = fs.Parse(args)
+
+	printer, err := ui.NewPrinter(*output)
 	if err != nil {
 		return err
 	}
 
-	rt, err := selectRuntime(ctx)
+	doc, err := updateLock(ctx, fs.Args())
 	if err != nil {
 		return err
 	}
+	return printer.Print(os.Stdout, doc, func(w io.Writer) {
+		if doc.MigratedFrom != 0 {
+			fmt.Fprintf(w, "Migrated %s from version %d to %d\n", lockFile, doc.MigratedFrom, lock.Version)
+		}
+		fmt.Fprintf(w, "Locked %d images in %s\n", len(doc.Images), lockFile)
+	})
+}
 
-	resolver, ok := rt.(runtime.DigestResolver)
-	if !ok {
-		return errors.New("runtime does not support digest resolution")
+// updateLock updates devx.lock for the given profiles. Entries no profile uses
+// any more are dropped when every profile is updated.
+func updateLock(ctx context.Context, profiles []string) (lockUpdateDocument, error) {
+	doc := lockUpdateDocument{TypeMeta: ui.Meta("LockUpdate"), Lockfile: lockFile, Images: []lockedImage{}}
+	images, err := profileImages(ctx, profiles)
+	if err != nil {
+		return doc, err
 	}
 
-	lf := lock.New()
-	images, err := collectImages(manifest, profName, prof)
-	if err != nil {
-		return err
+	lf, err := lock.Load(lockFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		lf = lock.New()
+	} else if err != nil {
+		return doc, err
 	}
 
+	if len(profiles) == 0 {
+		used := map[string]bool{}
+		for _, image := range images {
+			used[image] = true
+		}
+		for image := range lf.Images {
+			if !used[image] {
+				delete(lf.Images, image)
+			}
+		}
+	}
+
+	client := registry.New()
 	for _, image := range images {
-		digest, err := resolver.ResolveImageDigest(ctx, image)
+		entry, err := client.Resolve(ctx, image)
 		if err != nil {
-			return fmt.Errorf("lock update failed for %s: %w", image, err)
+			return doc, fmt.Errorf("lock update failed for %s: %w", image, err)
 		}
-		lf.Images[image] = digest
+		lf.Images[image] = entry
+		doc.Images = append(doc.Images, lockedImage{Image: image, Digest: entry.Digest, Platforms: entry.Platforms})
+	}
+
+	if err := lock.Save(lockFile, lf); err != nil {
+		return doc, err
+	}
+	if lf.Version < lock.Version {
+		doc.MigratedFrom = lf.Version
+	}
+	return doc, nil
+}
+
+// runLockVerify fails when an image used by the given profiles, or by every
+// profile, is missing from devx.lock or disagrees with it.
+func runLockVerify(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("lock verify", flag.ExitOnError)
+	output := fs.String("output", "", ui.OutputUsage)
+	_ = fs.Parse(args)
+
+	printer, err := ui.NewPrinter(*output)
+	if err != nil {
+		return err
+	}
+
+	// A document listing issues is printed even though verification failed,
+	// so that CI can read them; the exit code still reports the failure.
+	doc, err := verifyLock(ctx, fs.Args())
+	if err != nil && (!printer.Structured() || len(doc.Issues) == 0) {
+		return err
+	}
+	if perr := printer.Print(os.Stdout, doc, func(w io.Writer) {
+		fmt.Fprintf(w, "%s pins all %d images\n", lockFile, doc.Images)
+	}); perr != nil {
+		return perr
+	}
+	return err
+}
+
+// verifyLock checks devx.lock against the images of the given profiles. The
+// returned document describes the result when the error does.
+func verifyLock(ctx context.Context, profiles []string) (lockVerifyDocument, error) {
+	doc := lockVerifyDocument{TypeMeta: ui.Meta("LockVerify"), Lockfile: lockFile}
+	images, err := profileImages(ctx, profiles)
+	if err != nil {
+		return doc, err
 	}
+	doc.Images = len(images)
 
-	return lock.Save(lockFile, lf)
+	lf, err := lock.Load(lockFile)
+	if errors.Is(err, fs.ErrNotExist) {
+		doc.Issues = []lock.Issue{{Reason: lock.IssueNoLockfile, Message: lockFile + " not found"}}
+		return doc, fmt.Errorf("%s not found; run 'devx lock update'", lockFile)
+	}
+	if err != nil {
+		return doc, err
+	}
+
+	if lf.Version < lock.Version {
+		msg := fmt.Sprintf("%s is version %d, which pins per-machine digests", lockFile, lf.Version)
+		doc.Issues = []lock.Issue{{Reason: lock.IssueOutdatedVersion, Message: msg}}
+		return doc, fmt.Errorf("%s; run 'devx lock update' to migrate it", msg)
+	}
+	doc.Issues = lock.Verify(lf, images)
+	if len(doc.Issues) > 0 {
+		messages := make([]string, len(doc.Issues))
+		for i, issue := range doc.Issues {
+			messages[i] = issue.Message
+		}
+		return doc, fmt.Errorf("%s is out of date; run 'devx lock update':\n- %s", lockFile, strings.Join(messages, "\n- "))
+	}
+	doc.OK = true
+	doc.Issues = []lock.Issue{}
+	return doc, nil
+}
+
+// lockUpdateDocument and lockVerifyDocument are the outputs of `devx lock
+// update|verify --output json|yaml`; see schemas/output/lock.schema.json.
+type lockUpdateDocument struct {
+	ui.TypeMeta
+	Lockfile     string        `json:"lockfile"`
+	MigratedFrom int           `json:"migratedFrom,omitempty"`
+	Images       []lockedImage `json:"images"`
+}
+
+type lockedImage struct {
+	Image     string            `json:"image"`
+	Digest    string            `json:"digest"`
+	Platforms map[string]string `json:"platforms,omitempty"`
+}
+
+type lockVerifyDocument struct {
+	ui.TypeMeta
+	Lockfile string       `json:"lockfile"`
+	OK       bool         `json:"ok"`
+	Images   int          `json:"images"`
+	Issues   []lock.Issue `json:"issues"`
+}
+
+// profileImages returns the images, including the base images of builds, used
+// by the named profiles or by every profile when none are named.
+func profileImages(ctx context.Context, profiles []string) ([]string, error) {
+	manifest, err := config.Load(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.Validate(manifest); err != nil {
+		return nil, err
+	}
+
+	if len(profiles) == 0 {
+		profiles = util.SortedKeys(manifest.Profiles)
+	}
+
+	seen := map[string]bool{}
+	var images []string
+	for _, name := range profiles {
+		prof, err := config.ProfileByName(manifest, name)
+		if err != nil {
+			return nil, err
+		}
+		if err := config.ValidateProfile(manifest, name); err != nil {
+			return nil, err
+		}
+		imgs, err := collectImages(ctx, manifest, name, prof)
+		if err != nil {
+			return nil, fmt.Errorf("profile '%s': %w", name, err)
+		}
+		for _, image := range imgs {
+			if !seen[image] {
+				seen[image] = true
+				images = append(images, image)
+			}
+		}
+	}
+	sort.Strings(images)
+	return images, nil
 }
@@ -4,16 +4,27 @@ import (
 	"context"
 	"errors"
 	"flag"
-	"path/filepath"
+	"fmt"
+	"os"
+	"time"
 
+	"github.com/dever-labs/devx/internal/compose"
+	"github.com/dever-labs/devx/internal/logs"
 	"github.com/dever-labs/devx/internal/runtime"
+	"github.com/dever-labs/devx/internal/telemetry"
 )
 
 func runLogs(ctx context.Context, args []string) error {
 	fs := flag.NewFlagSet("logs", flag.ExitOnError)
 	follow := fs.Bool("follow", false, "Follow logs")
 	since := fs.String("since", "", "Show logs since")
-	jsonOut := fs.Bool("json", false, "JSON output")
+	until := fs.String("until", "", "Show logs until, from the telemetry Loki")
+	query := fs.String("query", "", "LogQL query to run on the telemetry Loki")
+	limit := fs.Int("limit", 1000, "With --query or --until, the maximum number of lines")
+	jsonOut := fs.Bool("json", false, "Print one JSON record per line")
+	grep := fs.String("grep", "", "Only show lines matching this regular expression")
+	level := fs.String("level", "", "Only show lines of this level or more severe: trace, debug, info, warn, error or fatal")
+	noColor := fs.Bool("no-color", false, "Do not colour service names")
 	_ = fs.Parse(args)
 
 	var service string
@@ -21,27 +32,37 @@ func runLogs(ctx context.Context, args []string) error {
 		service = fs.Arg(0)
 	}
 
-	manifest, profName, prof, err := loadProfile("")
+	filter, err := logs.NewFilter(*grep, *level)
 	if err != nil {
 		return err
 	}
 
-	if profileRuntime(prof) == "k8s" {
-		return errors.New("logs for k8s runtime are not supported yet")
-	}
-
-	rt, err := selectRuntime(ctx)
+	manifest, profName, prof, err := loadProfile("")
 	if err != nil {
 		return err
 	}
 
-	enableTelemetry := telemetryFromState()
-	composePath := filepath.Join(devxDir, composeFile)
-	if err := ensureDevxDir(); err != nil {
+	rt, composePath, err := prepareRuntime(ctx, manifest, profName, prof)
+	if err != nil {
 		return err
 	}
-	if err := writeCompose(composePath, manifest, profName, prof, nil, enableTelemetry); err != nil {
-		return err
+
+	printer := &logs.Printer{Out: os.Stdout, JSON: *jsonOut, Color: !*jsonOut && colorOutput(*noColor)}
+
+	if *query != "" || *until != "" {
+		q := lokiQuery{
+			query:  *query,
+			since:  *since,
+			until:  *until,
+			limit:  *limit,
+			follow: *follow,
+		}
+		if q.query == "" {
+			q.query = defaultLogQuery(manifest.Project.Name, service)
+		} else if service != "" {
+			return fmt.Errorf("select the service in the query instead, e.g. {compose_service=\"%s\"}", service)
+		}
+		return queryLogs(ctx, rt, composePath, manifest.Project.Name, q, printer, filter)
 	}
 
 	reader, err := rt.Logs(ctx, composePath, manifest.Project.Name, runtime.LogsOptions{
@@ -55,5 +76,103 @@ func runLogs(ctx context.Context, args []string) error {
 	}
 	defer reader.Close()
 
-	return streamLogs(reader, *jsonOut)
+	return streamLogs(reader, manifest.Project.Name, printer, filter)
+}
+
+type lokiQuery struct {
+	query  string
+	since  string
+	until  string
+	limit  int
+	follow bool
+}
+
+// queryLogs reads logs from the telemetry Loki, which keeps them across
+// container recreates: the lines of a time range, or a live tail with
+// --follow.
+func queryLogs(ctx context.Context, rt runtime.Runtime, composePath string, projectName string, q lokiQuery, printer *logs.Printer, filter logs.Filter) error {
+	statuses, err := rt.Status(ctx, composePath, projectName)
+	if err != nil {
+		return err
+	}
+	url := publishedURL(statuses, compose.LokiService, compose.LokiPort)
+	if url == "" {
+		return errors.New("--query and --until read the telemetry Loki, which is not running; start the stack with telemetry")
+	}
+
+	now := time.Now()
+	loki := telemetry.NewLoki(url)
+	emit := func(line telemetry.LogLine) error {
+		entry := lokiEntry(line)
+		if !filter.Match(entry) {
+			return nil
+		}
+		return printer.Print(entry)
+	}
+
+	if q.follow {
+		if q.until != "" {
+			return errors.New("--until cannot be combined with --follow")
+		}
+		// Unlike Loki's default of an hour back, a tail starts now.
+		start, err := logTime(q.since, now, now)
+		if err != nil {
+			return err
+		}
+		return loki.Tail(ctx, q.query, start, emit)
+	}
+
+	start, err := logTime(q.since, now, now.Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	end, err := logTime(q.until, now, now)
+	if err != nil {
+		return err
+	}
+	if !start.Before(end) {
+		return errors.New("--since must be before --until")
+	}
+	lines, err := loki.QueryRange(ctx, q.query, start, end, q.limit)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if err := emit(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultLogQuery selects the logs of a compose project, or of one of its
+// services, by the labels the telemetry Alloy sets.
+func defaultLogQuery(projectName string, service string) string {
+	if service != "" {
+		return fmt.Sprintf("{compose_project=%q, compose_service=%q}", projectName, service)
+	}
+	return fmt.Sprintf("{compose_project=%q}", projectName)
+}
+
+func lokiEntry(line telemetry.LogLine) logs.Entry {
+	stream := line.Labels["logstream"]
+	if stream == "" {
+		stream = line.Labels["stream"]
+	}
+	return logs.NewEntry(line.Labels["compose_service"], line.Time, stream, line.Line)
+}
+
+// logTime parses a --since or --until value: a duration before now such as
+// 10m, or an RFC 3339 time. An empty value selects def.
+func logTime(value string, now time.Time, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time '%s' (want a duration such as 10m or an RFC 3339 time)", value)
 }
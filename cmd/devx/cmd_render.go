@@ -8,11 +8,21 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/dever-labs/devx/internal/config"
 	"github.com/dever-labs/devx/internal/k8s"
 	"github.com/dever-labs/devx/internal/lock"
+	"github.com/dever-labs/devx/internal/util"
 )
 
 func runRender(ctx context.Context, args []string) error {
+	if len(args) > 0 {
+		switch args[0] {
+		case "kustomize":
+			return runRenderKustomize(args[1:])
+		case "helm":
+			return runRenderHelm(args[1:])
+		}
+	}
 	if len(args) == 0 || args[0] != "compose" {
 		return runRenderK8s(ctx, args)
 	}
@@ -29,7 +39,7 @@ func runRender(ctx context.Context, args []string) error {
 
 	lockfile, _ := lock.Load(lockFile)
 
-	composed, err := buildCompose(manifest, profName, prof, lockfile, !*noTelemetry)
+	composed, err := buildCompose(ctx, manifest, profName, prof, lockfile, !*noTelemetry)
 	if err != nil {
 		return err
 	}
@@ -39,7 +49,7 @@ func runRender(ctx context.Context, args []string) error {
 			return err
 		}
 		composePath := filepath.Join(devxDir, composeFile)
-		return writeCompose(composePath, manifest, profName, prof, lockfile, !*noTelemetry)
+		return writeCompose(ctx, composePath, manifest, profName, prof, lockfile, !*noTelemetry)
 	}
 
 	fmt.Print(composed)
@@ -48,7 +58,7 @@ func runRender(ctx context.Context, args []string) error {
 
 func runRenderK8s(ctx context.Context, args []string) error {
 	if len(args) == 0 || args[0] != "k8s" {
-		return errors.New("render requires 'compose' or 'k8s'")
+		return errors.New("render requires 'compose', 'k8s', 'kustomize' or 'helm'")
 	}
 
 	fs := flag.NewFlagSet("render-k8s", flag.ExitOnError)
@@ -62,7 +72,7 @@ func runRenderK8s(ctx context.Context, args []string) error {
 		return err
 	}
 
-	output, err := k8s.Render(manifest, profName, prof, *namespace)
+	output, err := renderK8s(ctx, manifest, profName, prof, *namespace)
 	if err != nil {
 		return err
 	}
@@ -77,3 +87,103 @@ func runRenderK8s(ctx context.Context, args []string) error {
 	fmt.Print(output)
 	return nil
 }
+
+func runRenderKustomize(args []string) error {
+	fs := flag.NewFlagSet("render-kustomize", flag.ExitOnError)
+	profile := fs.String("profile", "", "Profile to use as the base")
+	namespace := fs.String("namespace", "", "Kubernetes namespace")
+	out := fs.String("out", "", "Output directory")
+	_ = fs.Parse(args)
+	if *out == "" {
+		return errors.New("render kustomize requires --out")
+	}
+
+	manifest, profName, prof, err := loadProfile(*profile)
+	if err != nil {
+		return err
+	}
+	opts := k8s.RenderOptions{Namespace: *namespace, OmitSecrets: true}
+	base, err := k8s.Resources(manifest, profName, prof, opts)
+	if err != nil {
+		return err
+	}
+
+	overlays := map[string][]k8s.Resource{}
+	for _, name := range util.SortedKeys(manifest.Profiles) {
+		overlayProf, err := config.ProfileByName(manifest, name)
+		if err != nil {
+			return err
+		}
+		if name != profName && profileRuntime(overlayProf) != "k8s" {
+			continue
+		}
+		if err := config.ValidateProfile(manifest, name); err != nil {
+			return err
+		}
+		resources, err := k8s.Resources(manifest, name, overlayProf, opts)
+		if err != nil {
+			return fmt.Errorf("profile '%s': %w", name, err)
+		}
+		overlays[name] = resources
+	}
+
+	files, err := k8s.Kustomize(base, overlays)
+	if err != nil {
+		return err
+	}
+	if err := writeTree(*out, files, "base", "overlays"); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote kustomize base (%s) and %d overlays to %s\n", profName, len(overlays), *out)
+	return nil
+}
+
+func runRenderHelm(args []string) error {
+	fs := flag.NewFlagSet("render-helm", flag.ExitOnError)
+	profile := fs.String("profile", "", "Profile to use")
+	namespace := fs.String("namespace", "", "Kubernetes namespace")
+	out := fs.String("out", "", "Output directory")
+	_ = fs.Parse(args)
+	if *out == "" {
+		return errors.New("render helm requires --out")
+	}
+
+	manifest, profName, prof, err := loadProfile(*profile)
+	if err != nil {
+		return err
+	}
+	resources, err := k8s.Resources(manifest, profName, prof, k8s.RenderOptions{Namespace: *namespace, OmitSecrets: true})
+	if err != nil {
+		return err
+	}
+
+	files, err := k8s.HelmChart(manifest.Project.Name, resources)
+	if err != nil {
+		return err
+	}
+	if err := writeTree(*out, files, "templates"); err != nil {
+		return err
+	}
+	fmt.Printf("Wrote Helm chart for profile %s to %s\n", profName, *out)
+	return nil
+}
+
+// writeTree writes files below dir. The generated subdirectories are removed
+// first so objects dropped from the manifest do not linger.
+func writeTree(dir string, files map[string][]byte, generated ...string) error {
+	for _, sub := range generated {
+		if err := os.RemoveAll(filepath.Join(dir, sub)); err != nil {
+			return err
+		}
+	}
+	for _, name := range util.SortedKeys(files) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, files[name], 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -4,37 +4,39 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"io"
 	"os"
-	"path/filepath"
 	"sort"
+	"time"
 
 	"github.com/dever-labs/devx/internal/ui"
 )
 
 func runStatus(ctx context.Context, args []string) error {
 	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	output := fs.String("output", "", ui.OutputUsage)
+	watch := fs.Bool("watch", false, "Show a live dashboard with keybindings for the selected service")
+	interval := fs.Duration("interval", 2*time.Second, "With --watch, the refresh interval")
 	_ = fs.Parse(args)
 
-	manifest, profName, prof, err := loadProfile("")
+	printer, err := ui.NewPrinter(*output)
 	if err != nil {
 		return err
 	}
-
-	if profileRuntime(prof) == "k8s" {
-		return errors.New("status for k8s runtime is not supported yet")
+	if *watch {
+		if printer.Structured() {
+			return errors.New("--watch cannot be combined with --output")
+		}
+		return watchStatus(ctx, *interval)
 	}
 
-	rt, err := selectRuntime(ctx)
+	manifest, profName, prof, err := loadProfile("")
 	if err != nil {
 		return err
 	}
 
-	enableTelemetry := telemetryFromState()
-	composePath := filepath.Join(devxDir, composeFile)
-	if err := ensureDevxDir(); err != nil {
-		return err
-	}
-	if err := writeCompose(composePath, manifest, profName, prof, nil, enableTelemetry); err != nil {
+	rt, composePath, err := prepareRuntime(ctx, manifest, profName, prof)
+	if err != nil {
 		return err
 	}
 
@@ -47,11 +49,57 @@ func runStatus(ctx context.Context, args []string) error {
 		return statuses[i].Name < statuses[j].Name
 	})
 
-	headers := []string{"Service", "State", "Health", "Ports"}
-	rows := make([][]string, 0, len(statuses))
+	doc := statusDocument{
+		TypeMeta: ui.Meta("Status"),
+		Project:  manifest.Project.Name,
+		Profile:  profName,
+		Runtime:  rt.Name(),
+		Services: []serviceStatus{},
+	}
 	for _, st := range statuses {
-		rows = append(rows, []string{st.Name, st.State, st.Health, st.Ports})
+		svc := serviceStatus{Name: st.Name, State: st.State, Health: st.Health, Restarts: st.Restarts, Publishers: []publisher{}}
+		if !st.StartedAt.IsZero() {
+			startedAt := st.StartedAt
+			svc.StartedAt = &startedAt
+		}
+		for _, pub := range st.Publishers {
+			svc.Publishers = append(svc.Publishers, publisher(pub))
+		}
+		doc.Services = append(doc.Services, svc)
 	}
-	ui.PrintTable(os.Stdout, headers, rows)
-	return nil
+
+	return printer.Print(os.Stdout, doc, func(w io.Writer) {
+		headers := []string{"Service", "State", "Health", "Ports"}
+		rows := make([][]string, 0, len(statuses))
+		for _, st := range statuses {
+			rows = append(rows, []string{st.Name, st.State, st.Health, st.Ports})
+		}
+		ui.PrintTable(w, headers, rows)
+	})
+}
+
+// statusDocument is the output of `devx status --output json|yaml`; see
+// schemas/output/status.schema.json.
+type statusDocument struct {
+	ui.TypeMeta
+	Project  string          `json:"project"`
+	Profile  string          `json:"profile"`
+	Runtime  string          `json:"runtime"`
+	Services []serviceStatus `json:"services"`
+}
+
+type serviceStatus struct {
+	Name       string      `json:"name"`
+	State      string      `json:"state"`
+	Health     string      `json:"health"`
+	StartedAt  *time.Time  `json:"startedAt,omitempty"`
+	Restarts   int         `json:"restarts"`
+	Publishers []publisher `json:"publishers"`
+}
+
+type publisher struct {
+	URL           string `json:"url"`
+	TargetPort    int    `json:"targetPort"`
+	PublishedPort int    `json:"publishedPort"`
+	Protocol      string `json:"protocol"`
 }
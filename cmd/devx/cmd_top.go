@@ -0,0 +1,339 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dever-labs/devx/internal/compose"
+	"github.com/dever-labs/devx/internal/logs"
+	"github.com/dever-labs/devx/internal/runtime"
+	"github.com/dever-labs/devx/internal/telemetry"
+	"github.com/dever-labs/devx/internal/ui"
+)
+
+// logTail is how many lines of history the dashboard shows before following
+// the logs of a service.
+const logTail = 100
+
+// shellCommand starts bash where the image has it and sh otherwise.
+var shellCommand = []string{"sh", "-c", "if command -v bash >/dev/null 2>&1; then exec bash; else exec sh; fi"}
+
+func runTop(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("top", flag.ExitOnError)
+	interval := fs.Duration("interval", 2*time.Second, "Refresh interval")
+	_ = fs.Parse(args)
+
+	return watchStatus(ctx, *interval)
+}
+
+// topView is the state of the status dashboard.
+type topView struct {
+	rt          runtime.Runtime
+	composePath string
+	project     string
+	profile     string
+	// telemetry is set when the profile runs the telemetry stack, whose
+	// Prometheus provides CPU and memory usage.
+	telemetry bool
+
+	statuses []runtime.ServiceStatus
+	usage    map[string]telemetry.Usage
+	selected int
+	message  string
+}
+
+// watchStatus shows the services as a dashboard that refreshes every interval
+// and acts on the selected service, until the user quits.
+func watchStatus(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		return errors.New("--interval must be positive")
+	}
+	if !ui.IsTerminal(os.Stdout) {
+		return errors.New("status --watch requires a terminal")
+	}
+
+	manifest, profName, prof, err := loadProfile("")
+	if err != nil {
+		return err
+	}
+	rt, composePath, err := prepareRuntime(ctx, manifest, profName, prof)
+	if err != nil {
+		return err
+	}
+
+	v := &topView{
+		rt:          rt,
+		composePath: composePath,
+		project:     manifest.Project.Name,
+		profile:     profName,
+		telemetry:   profileRuntime(prof) == "compose" && telemetryFromState(),
+	}
+
+	// Ctrl+C quits the dashboard, or returns to it from the logs.
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	defer signal.Stop(sigs)
+
+	term := ui.OpenTerminal()
+	defer term.Close()
+
+	var next time.Time
+	for {
+		select {
+		case <-sigs:
+			return nil
+		default:
+		}
+		if !time.Now().Before(next) {
+			v.refresh(ctx)
+			next = time.Now().Add(interval)
+			if err := v.dashboard(term.Keys()).Render(os.Stdout); err != nil {
+				return err
+			}
+		}
+
+		key, err := term.ReadKey()
+		if err != nil {
+			return err
+		}
+		switch key {
+		case "":
+			continue
+		case "q":
+			return nil
+		case "up", "k":
+			v.move(-1)
+		case "down", "j":
+			v.move(1)
+		case "l":
+			v.tailLogs(ctx, term, sigs)
+			next = time.Time{}
+		case "r":
+			v.restart(ctx)
+			next = time.Time{}
+		case "e":
+			v.shell(ctx, term, sigs)
+			next = time.Time{}
+		default:
+			continue
+		}
+		if err := v.dashboard(term.Keys()).Render(os.Stdout); err != nil {
+			return err
+		}
+	}
+}
+
+func (v *topView) refresh(ctx context.Context) {
+	statuses, err := v.rt.Status(ctx, v.composePath, v.project)
+	if err != nil {
+		v.message = fmt.Sprintf("status failed: %v", err)
+		return
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	v.statuses = statuses
+	v.move(0)
+
+	// Usage is best effort: Prometheus has no samples for the first minute.
+	v.usage = nil
+	if v.telemetry {
+		if url := publishedURL(statuses, compose.PrometheusService, compose.PrometheusPort); url != "" {
+			v.usage, _ = telemetry.NewPrometheus(url).ServiceUsage(ctx, v.project)
+		}
+	}
+}
+
+func (v *topView) move(delta int) {
+	v.selected += delta
+	if v.selected >= len(v.statuses) {
+		v.selected = len(v.statuses) - 1
+	}
+	if v.selected < 0 {
+		v.selected = 0
+	}
+}
+
+func (v *topView) service() string {
+	if v.selected < len(v.statuses) {
+		return v.statuses[v.selected].Name
+	}
+	return ""
+}
+
+func (v *topView) dashboard(keys bool) ui.Dashboard {
+	headers, rows := statusRows(v.statuses, v.usage, v.telemetry, time.Now())
+	help := "Ctrl+C quit"
+	if keys {
+		help = "↑/↓ select  l logs  r restart  e shell  q quit"
+	}
+	return ui.Dashboard{
+		Title:    fmt.Sprintf("devx status: %s (profile %s, %s) at %s", v.project, v.profile, v.rt.Name(), time.Now().Format("15:04:05")),
+		Headers:  headers,
+		Rows:     rows,
+		Selected: v.selected,
+		Message:  v.message,
+		Help:     help,
+	}
+}
+
+// tailLogs follows the logs of the selected service on the normal screen until
+// Ctrl+C.
+func (v *topView) tailLogs(ctx context.Context, term *ui.Terminal, sigs chan os.Signal) {
+	service := v.service()
+	if service == "" {
+		return
+	}
+	term.Suspend()
+	defer term.Resume()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		select {
+		case <-sigs:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	fmt.Printf("Logs of %s; press Ctrl+C to return\n", service)
+	reader, err := v.rt.Logs(ctx, v.composePath, v.project, runtime.LogsOptions{Service: service, Follow: true, Tail: logTail})
+	if err != nil {
+		v.message = fmt.Sprintf("logs failed: %v", err)
+		return
+	}
+	defer reader.Close()
+	_ = streamLogs(reader, v.project, &logs.Printer{Out: os.Stdout, Color: colorOutput(false)}, logs.Filter{})
+	v.message = ""
+}
+
+func (v *topView) restart(ctx context.Context) {
+	service := v.service()
+	if service == "" {
+		return
+	}
+	restarter, ok := v.rt.(runtime.Restarter)
+	if !ok {
+		v.message = fmt.Sprintf("the %s runtime cannot restart services", v.rt.Name())
+		return
+	}
+	v.message = fmt.Sprintf("Restarting %s...", service)
+	_ = v.dashboard(true).Render(os.Stdout)
+	if err := restarter.Restart(ctx, v.composePath, v.project, []string{service}); err != nil {
+		v.message = fmt.Sprintf("restart of %s failed: %v", service, err)
+		return
+	}
+	v.message = fmt.Sprintf("Restarted %s", service)
+}
+
+// shell runs an interactive shell in the selected service.
+func (v *topView) shell(ctx context.Context, term *ui.Terminal, sigs chan os.Signal) {
+	service := v.service()
+	if service == "" {
+		return
+	}
+	attacher, ok := v.rt.(runtime.Attacher)
+	if !ok {
+		v.message = fmt.Sprintf("the %s runtime cannot open a shell", v.rt.Name())
+		return
+	}
+	term.Suspend()
+	err := attacher.Attach(ctx, v.composePath, v.project, service, shellCommand)
+	term.Resume()
+	// Ctrl+C inside the shell must not quit the dashboard.
+	for len(sigs) > 0 {
+		<-sigs
+	}
+	v.message = ""
+	if err != nil {
+		v.message = fmt.Sprintf("shell in %s failed: %v", service, err)
+	}
+}
+
+// statusRows builds the dashboard table. CPU and memory columns are shown
+// when the telemetry stack runs, even before it has samples.
+func statusRows(statuses []runtime.ServiceStatus, usage map[string]telemetry.Usage, withUsage bool, now time.Time) ([]string, [][]string) {
+	headers := []string{"Service", "State", "Health", "Uptime", "Restarts"}
+	if withUsage {
+		headers = append(headers, "CPU", "Memory")
+	}
+	headers = append(headers, "URLs")
+
+	rows := make([][]string, 0, len(statuses))
+	for _, st := range statuses {
+		uptime := "-"
+		if st.State == "running" && !st.StartedAt.IsZero() {
+			uptime = formatUptime(now.Sub(st.StartedAt))
+		}
+		row := []string{st.Name, st.State, dash(st.Health), uptime, strconv.Itoa(st.Restarts)}
+		if withUsage {
+			cpu, memory := "-", "-"
+			if u, ok := usage[st.Name]; ok {
+				cpu = fmt.Sprintf("%.1f%%", u.CPU)
+				memory = formatBytes(u.Memory)
+			}
+			row = append(row, cpu, memory)
+		}
+		row = append(row, dash(strings.Join(serviceURLs(st), " ")))
+		rows = append(rows, row)
+	}
+	return headers, rows
+}
+
+// serviceURLs returns the distinct localhost URLs of the published ports of a
+// service. Runtimes report a binding per address family.
+func serviceURLs(st runtime.ServiceStatus) []string {
+	var urls []string
+	seen := map[int]bool{}
+	for _, pub := range st.Publishers {
+		if pub.PublishedPort == 0 || seen[pub.PublishedPort] {
+			continue
+		}
+		seen[pub.PublishedPort] = true
+		urls = append(urls, fmt.Sprintf("http://localhost:%d", pub.PublishedPort))
+	}
+	return urls
+}
+
+// formatUptime rounds d to its two largest units, e.g. 45s, 12m3s, 5h20m or
+// 3d4h.
+func formatUptime(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%ds", int(d.Minutes()), int(d.Seconds())%60)
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
+
+func formatBytes(n float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB"}
+	i := 0
+	for n >= 1024 && i < len(units)-1 {
+		n /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f%s", n, units[i])
+	}
+	return fmt.Sprintf("%.1f%s", n, units[i])
+}
+
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
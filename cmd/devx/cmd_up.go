@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/dever-labs/devx/internal/config"
 	"github.com/dever-labs/devx/internal/k8s"
@@ -19,6 +20,9 @@ func runUp(ctx context.Context, args []string) error {
 	build := fs.Bool("build", false, "Build images")
 	pull := fs.Bool("pull", false, "Always pull images")
 	noTelemetry := fs.Bool("no-telemetry", false, "Disable telemetry stack")
+	watchMode := fs.Bool("watch", false, "Watch services and rebuild, sync or restart them on changes")
+	timeout := fs.Duration("timeout", 5*time.Minute, "Time to wait for k8s rollouts")
+	cluster := clusterFlags(fs)
 	_ = fs.Parse(args)
 
 	manifest, profName, prof, err := loadProfile(*profile)
@@ -26,6 +30,17 @@ func runUp(ctx context.Context, args []string) error {
 		return err
 	}
 
+	// With service arguments only those services and their transitive
+	// dependencies are started; active is the part of the profile that runs.
+	active := prof
+	var selected []string
+	if fs.NArg() > 0 {
+		if selected, err = selectServices(prof, fs.Args()); err != nil {
+			return err
+		}
+		active = subsetProfile(prof, selected)
+	}
+
 	rt, err := selectRuntime(ctx)
 	if err != nil {
 		return err
@@ -39,26 +54,38 @@ func runUp(ctx context.Context, args []string) error {
 
 	runtimeMode := profileRuntime(prof)
 	if runtimeMode == "k8s" {
-		return runUpK8s(ctx, manifest, profName, prof)
+		if *watchMode {
+			return fmt.Errorf("--watch supports compose profiles only")
+		}
+		return runUpK8s(ctx, manifest, profName, prof, active, selected, *cluster, *timeout)
 	}
 
 	composePath := filepath.Join(devxDir, composeFile)
 	enableTelemetry := !*noTelemetry
-	if err := writeCompose(composePath, manifest, profName, prof, lockfile, enableTelemetry); err != nil {
+	if err := writeCompose(ctx, composePath, manifest, profName, prof, lockfile, enableTelemetry); err != nil {
 		return err
 	}
 
-	if err := rt.Up(ctx, composePath, manifest.Project.Name, runtime.UpOptions{Build: *build, Pull: *pull}); err != nil {
+	services := selected
+	if len(selected) > 0 {
+		extras, err := extraComposeServices(composePath, prof)
+		if err != nil {
+			return err
+		}
+		services = append(services, extras...)
+	}
+
+	if err := rt.Up(ctx, composePath, manifest.Project.Name, runtime.UpOptions{Build: *build, Pull: *pull, Services: services}); err != nil {
 		return err
 	}
 
-	if err := waitForHealth(prof); err != nil {
+	if err := waitForHealth(ctx, rt, composePath, manifest.Project.Name, active); err != nil {
 		return err
 	}
 
-	if len(prof.Hooks.AfterUp) > 0 {
+	if len(active.Hooks.AfterUp) > 0 {
 		fmt.Println("Running afterUp hooks...")
-		if err := runHooks(ctx, rt, composePath, manifest.Project.Name, prof.Hooks.AfterUp); err != nil {
+		if err := runHooks(ctx, rt, composePath, manifest.Project.Name, active.Hooks.AfterUp); err != nil {
 			return err
 		}
 	}
@@ -69,11 +96,19 @@ func runUp(ctx context.Context, args []string) error {
 
 	fmt.Println("Environment is up")
 	printLinks(ctx, rt, composePath, manifest.Project.Name)
+	printConnections(active)
+
+	if *watchMode {
+		return watchServices(ctx, rt, composePath, manifest, active, nil, 500*time.Millisecond)
+	}
 	return nil
 }
 
-func runUpK8s(ctx context.Context, manifest *config.Manifest, profName string, prof *config.Profile) error {
-	output, err := k8s.Render(manifest, profName, prof, "")
+// runUpK8s writes the manifest of the whole profile to .devx/k8s.yaml, so that
+// later commands see every service, applies the active part of it and waits
+// for the rollouts.
+func runUpK8s(ctx context.Context, manifest *config.Manifest, profName string, prof *config.Profile, active *config.Profile, selected []string, cluster k8s.Kubectl, timeout time.Duration) error {
+	output, err := renderK8s(ctx, manifest, profName, prof, "")
 	if err != nil {
 		return err
 	}
@@ -86,14 +121,32 @@ func runUpK8s(ctx context.Context, manifest *config.Manifest, profName string, p
 		return err
 	}
 
-	if err := k8s.Apply(ctx, path); err != nil {
+	applied := output
+	opts := k8s.ApplyOptions{Prune: true, Project: manifest.Project.Name}
+	if len(selected) > 0 {
+		// Pruning against a subset would delete every service left out.
+		if applied, err = renderK8s(ctx, manifest, profName, active, ""); err != nil {
+			return err
+		}
+		opts.Prune = false
+	}
+	if err := cluster.Apply(ctx, applied, opts); err != nil {
 		return err
 	}
 
-	if err := writeState(state{Profile: profName, Runtime: "k8s", Telemetry: false}); err != nil {
+	if err := writeState(state{Profile: profName, Runtime: "k8s", Telemetry: false, KubeContext: cluster.Context, Kubeconfig: cluster.Kubeconfig}); err != nil {
 		fmt.Fprintf(os.Stderr, "warning: failed to write state: %v\n", err)
 	}
-
 	fmt.Println("Kubernetes resources applied")
+
+	workloads, err := k8s.Workloads(applied)
+	if err != nil {
+		return err
+	}
+	if err := cluster.WaitForRollout(ctx, workloads, timeout, os.Stdout); err != nil {
+		return err
+	}
+
+	printExposedLinks(active)
 	return nil
 }
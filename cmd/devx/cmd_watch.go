@@ -0,0 +1,230 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/dever-labs/devx/internal/config"
+	"github.com/dever-labs/devx/internal/graph"
+	"github.com/dever-labs/devx/internal/runtime"
+	"github.com/dever-labs/devx/internal/util"
+	"github.com/dever-labs/devx/internal/watch"
+)
+
+func runWatch(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("watch", flag.ExitOnError)
+	profile := fs.String("profile", "", "Profile to use")
+	debounce := fs.Duration("debounce", 500*time.Millisecond, "Quiet period before acting on changes")
+	_ = fs.Parse(args)
+
+	manifest, profName, prof, err := loadProfile(*profile)
+	if err != nil {
+		return err
+	}
+	if profileRuntime(prof) == "k8s" {
+		return fmt.Errorf("devx watch supports compose profiles only")
+	}
+
+	rt, composePath, err := prepareRuntime(ctx, manifest, profName, prof)
+	if err != nil {
+		return err
+	}
+	return watchServices(ctx, rt, composePath, manifest, prof, fs.Args(), *debounce)
+}
+
+// watchServices runs the watch loop for the named services (all services when
+// names is empty) until interrupted.
+func watchServices(ctx context.Context, rt runtime.Runtime, composePath string, manifest *config.Manifest, prof *config.Profile, names []string, debounce time.Duration) error {
+	rules, err := watchRules(prof, names)
+	if err != nil {
+		return err
+	}
+	if len(rules) == 0 {
+		return fmt.Errorf("nothing to watch: add build or watch to a service")
+	}
+
+	g, err := graph.Build(prof)
+	if err != nil {
+		return err
+	}
+	order, err := graph.TopoSort(g)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	for _, rule := range rules {
+		fmt.Printf("Watching %s for %s (%s)\n", rule.Path, rule.Service, rule.Action)
+	}
+	fmt.Println("Press Ctrl+C to stop")
+
+	w := &watch.Watcher{Rules: rules, Debounce: debounce}
+	return w.Run(ctx, func(changes []watch.Change) error {
+		applyChanges(ctx, rt, composePath, manifest.Project.Name, g, order, changes)
+		return nil
+	})
+}
+
+// watchRules builds the watch rules of the named services: the declared watch
+// entries plus a rebuild rule for the build context.
+func watchRules(prof *config.Profile, names []string) ([]watch.Rule, error) {
+	if len(names) == 0 {
+		names = util.SortedKeys(prof.Services)
+	}
+
+	var rules []watch.Rule
+	for _, name := range names {
+		svc, ok := prof.Services[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown service '%s'", name)
+		}
+		if svc.Build != nil && svc.Build.Context != "" {
+			rules = append(rules, watch.Rule{
+				Service: name,
+				Path:    filepath.Clean(svc.Build.Context),
+				Action:  config.WatchRebuild,
+			})
+		}
+		for _, rule := range svc.Watch {
+			rules = append(rules, watch.Rule{
+				Service: name,
+				Path:    filepath.Clean(rule.Path),
+				Action:  rule.Action,
+				Target:  rule.Target,
+				Include: rule.Include,
+				Exclude: rule.Exclude,
+			})
+		}
+	}
+	return rules, nil
+}
+
+// applyChanges handles a batch of changes service by service, in dependency
+// order. A service that needs a rebuild is not also restarted or synced, and
+// the services that depend on a rebuilt service are restarted afterwards so
+// they reconnect to the new container. Failures are reported and the watch
+// loop carries on.
+func applyChanges(ctx context.Context, rt runtime.Runtime, composePath string, projectName string, g *graph.Graph, order []string, changes []watch.Change) {
+	byService := map[string][]watch.Change{}
+	for _, change := range changes {
+		byService[change.Rule.Service] = append(byService[change.Rule.Service], change)
+	}
+
+	var rebuilt []string
+	handled := map[string]bool{}
+	for _, service := range order {
+		serviceChanges, ok := byService[service]
+		if !ok {
+			continue
+		}
+		action, err := applyServiceChanges(ctx, rt, composePath, projectName, service, serviceChanges)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "[watch] %s: %v\n", service, err)
+			continue
+		}
+		if action == config.WatchRebuild {
+			rebuilt = append(rebuilt, service)
+		}
+		if action != config.WatchSync {
+			handled[service] = true
+		}
+	}
+	if len(rebuilt) == 0 {
+		return
+	}
+
+	dependents := map[string]bool{}
+	for _, name := range graph.Dependents(g, rebuilt) {
+		if !handled[name] {
+			dependents[name] = true
+		}
+	}
+	var restart []string
+	for _, service := range order {
+		if dependents[service] {
+			restart = append(restart, service)
+		}
+	}
+	if len(restart) == 0 {
+		return
+	}
+
+	fmt.Printf("[watch] restarting dependents of %s: %s\n", strings.Join(rebuilt, ", "), strings.Join(restart, ", "))
+	restarter, ok := rt.(runtime.Restarter)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "[watch] runtime %s does not support restart\n", rt.Name())
+		return
+	}
+	if err := restarter.Restart(ctx, composePath, projectName, restart); err != nil {
+		fmt.Fprintf(os.Stderr, "[watch] restarting dependents: %v\n", err)
+	}
+}
+
+// applyServiceChanges rebuilds, restarts or syncs one service and returns the
+// action it took.
+func applyServiceChanges(ctx context.Context, rt runtime.Runtime, composePath string, projectName string, service string, changes []watch.Change) (string, error) {
+	actions := map[string]bool{}
+	for _, change := range changes {
+		actions[change.Rule.Action] = true
+	}
+
+	switch {
+	case actions[config.WatchRebuild]:
+		fmt.Printf("[watch] %s: rebuilding (%s)\n", service, describeChanges(changes))
+		return config.WatchRebuild, rt.Up(ctx, composePath, projectName, runtime.UpOptions{Build: true, Services: []string{service}})
+	case actions[config.WatchRestart]:
+		fmt.Printf("[watch] %s: restarting (%s)\n", service, describeChanges(changes))
+		restarter, ok := rt.(runtime.Restarter)
+		if !ok {
+			return "", fmt.Errorf("runtime %s does not support restart", rt.Name())
+		}
+		return config.WatchRestart, restarter.Restart(ctx, composePath, projectName, []string{service})
+	}
+
+	syncer, ok := rt.(runtime.Syncer)
+	if !ok {
+		return "", fmt.Errorf("runtime %s does not support sync", rt.Name())
+	}
+	for _, change := range changes {
+		target := path.Join(change.Rule.Target, change.Rel)
+		if change.Removed {
+			fmt.Printf("[watch] %s: removing %s\n", service, target)
+			code, err := rt.Exec(ctx, composePath, projectName, service, []string{"rm", "-rf", target})
+			if err != nil {
+				return "", err
+			}
+			if code != 0 {
+				return "", fmt.Errorf("removing %s exited with code %d", target, code)
+			}
+			continue
+		}
+		fmt.Printf("[watch] %s: syncing %s\n", service, target)
+		src := filepath.Join(change.Rule.Path, filepath.FromSlash(change.Rel))
+		if err := syncer.Sync(ctx, composePath, projectName, service, src, target); err != nil {
+			return "", err
+		}
+	}
+	return config.WatchSync, nil
+}
+
+func describeChanges(changes []watch.Change) string {
+	const max = 3
+	var files []string
+	for i, change := range changes {
+		if i == max {
+			files = append(files, fmt.Sprintf("and %d more", len(changes)-max))
+			break
+		}
+		files = append(files, change.Rel)
+	}
+	return strings.Join(files, ", ")
+}
@@ -4,24 +4,35 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"flag"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/dever-labs/devx/internal/compose"
 	"github.com/dever-labs/devx/internal/config"
+	"github.com/dever-labs/devx/internal/deps"
 	"github.com/dever-labs/devx/internal/graph"
+	"github.com/dever-labs/devx/internal/k8s"
 	"github.com/dever-labs/devx/internal/lock"
+	"github.com/dever-labs/devx/internal/logs"
+	"github.com/dever-labs/devx/internal/plugins"
 	devxruntime "github.com/dever-labs/devx/internal/runtime"
 	"github.com/dever-labs/devx/internal/runtime/docker"
+	"github.com/dever-labs/devx/internal/runtime/kubernetes"
 	"github.com/dever-labs/devx/internal/runtime/podman"
+	"github.com/dever-labs/devx/internal/secrets"
+	"github.com/dever-labs/devx/internal/ui"
+	"github.com/dever-labs/devx/internal/util"
+	"gopkg.in/yaml.v3"
 )
 
 func loadProfile(profile string) (*config.Manifest, string, *config.Profile, error) {
@@ -54,16 +65,19 @@ func loadProfile(profile string) (*config.Manifest, string, *config.Profile, err
 	return manifest, profName, prof, nil
 }
 
-func writeCompose(path string, manifest *config.Manifest, profName string, prof *config.Profile, lockfile *lock.Lockfile, enableTelemetry bool) error {
-	composed, err := buildCompose(manifest, profName, prof, lockfile, enableTelemetry)
+func writeCompose(ctx context.Context, path string, manifest *config.Manifest, profName string, prof *config.Profile, lockfile *lock.Lockfile, enableTelemetry bool) error {
+	composed, err := buildCompose(ctx, manifest, profName, prof, lockfile, enableTelemetry)
 	if err != nil {
 		return err
 	}
 	if err := os.WriteFile(path, []byte(composed), 0600); err != nil {
 		return err
 	}
+	if err := writeSecrets(ctx, filepath.Dir(path), manifest, prof); err != nil {
+		return err
+	}
 
-	assets := compose.TelemetryAssets(enableTelemetry)
+	assets := compose.TelemetryAssets(enableTelemetry, manifest.Telemetry.Traces)
 	if len(assets) == 0 {
 		return nil
 	}
@@ -82,7 +96,7 @@ func writeCompose(path string, manifest *config.Manifest, profName string, prof
 	return nil
 }
 
-func buildCompose(manifest *config.Manifest, profName string, prof *config.Profile, lockfile *lock.Lockfile, enableTelemetry bool) (string, error) {
+func buildCompose(ctx context.Context, manifest *config.Manifest, profName string, prof *config.Profile, lockfile *lock.Lockfile, enableTelemetry bool) (string, error) {
 	g, err := graph.Build(prof)
 	if err != nil {
 		return "", err
@@ -96,45 +110,266 @@ func buildCompose(manifest *config.Manifest, profName string, prof *config.Profi
 		Lockfile:       lockfile,
 	}
 
-	return compose.Render(manifest, profName, prof, rewrite, enableTelemetry)
+	fragments, err := renderPlugins(ctx, manifest, profName, prof)
+	if err != nil {
+		return "", err
+	}
+
+	return compose.Render(manifest, profName, prof, rewrite, enableTelemetry, fragments)
 }
 
-func profileRuntime(prof *config.Profile) string {
-	if prof == nil || prof.Runtime == "" {
-		return "compose"
+// renderK8s resolves the secrets referenced by prof and renders its k8s manifest.
+func renderK8s(ctx context.Context, manifest *config.Manifest, profName string, prof *config.Profile, namespace string) (string, error) {
+	values, err := secrets.ResolveAll(ctx, ".", manifest, config.ReferencedSecrets(prof))
+	if err != nil {
+		return "", err
 	}
-	return prof.Runtime
+	return k8s.Render(manifest, profName, prof, k8s.RenderOptions{Namespace: namespace, SecretValues: values})
 }
 
-func ensureDevxDir() error {
-	return os.MkdirAll(devxDir, 0755)
+// writeSecrets writes what the rendered compose file reads secrets from: the
+// output of command-sourced secrets, mounted as files, and an env file for
+// every service and dep with secret-backed env vars. Each secret is resolved
+// once.
+func writeSecrets(ctx context.Context, baseDir string, manifest *config.Manifest, prof *config.Profile) error {
+	values := map[string][]byte{}
+	resolve := func(name string) ([]byte, error) {
+		if value, ok := values[name]; ok {
+			return value, nil
+		}
+		value, err := secrets.Resolve(ctx, ".", manifest.Secrets[name])
+		if err != nil {
+			return nil, fmt.Errorf("secret '%s': %w", name, err)
+		}
+		values[name] = value
+		return value, nil
+	}
+	write := func(rel string, data []byte) error {
+		path := filepath.Join(baseDir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return err
+		}
+		return os.WriteFile(path, data, 0600)
+	}
+
+	for _, name := range config.ReferencedSecrets(prof) {
+		if manifest.Secrets[name].Command == "" {
+			continue
+		}
+		value, err := resolve(name)
+		if err != nil {
+			return err
+		}
+		if err := write(compose.SecretsDir+"/"+name, value); err != nil {
+			return err
+		}
+	}
+
+	refs := map[string]map[string]string{}
+	for name, svc := range prof.Services {
+		refs[name] = svc.SecretEnv
+	}
+	for name, dep := range prof.Deps {
+		refs[name] = dep.SecretEnv
+	}
+	for _, name := range util.SortedKeys(refs) {
+		if len(refs[name]) == 0 {
+			continue
+		}
+		env := map[string]string{}
+		for _, key := range util.SortedKeys(refs[name]) {
+			value, err := resolve(refs[name][key])
+			if err != nil {
+				return err
+			}
+			env[key] = string(value)
+		}
+		if err := write(compose.SecretEnvFile(name), compose.EnvFileContent(env)); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func ensureGitignore() error {
-	path := ".gitignore"
-	entry := ".devx/"
+// renderPlugins runs every devx-provider-* binary on PATH against the profile
+// and returns the compose fragments they produce.
+func renderPlugins(ctx context.Context, manifest *config.Manifest, profName string, prof *config.Profile) ([]plugins.Fragment, error) {
+	found, err := plugins.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(found) == 0 {
+		return nil, nil
+	}
 
-	if !fileExists(path) {
-		return os.WriteFile(path, []byte(entry+"\n"), 0644)
+	req, err := plugins.NewRenderRequest(manifest, profName, prof)
+	if err != nil {
+		return nil, err
 	}
+	return plugins.RenderAll(ctx, found, req)
+}
 
-	data, err := os.ReadFile(path)
+// prepareRuntime selects the runtime for prof and writes the file it operates on:
+// .devx/compose.yaml for compose profiles, .devx/k8s.yaml for k8s profiles.
+func prepareRuntime(ctx context.Context, manifest *config.Manifest, profName string, prof *config.Profile) (devxruntime.Runtime, string, error) {
+	if err := ensureDevxDir(); err != nil {
+		return nil, "", err
+	}
+
+	if profileRuntime(prof) == "k8s" {
+		path := filepath.Join(devxDir, k8sFile)
+		output, err := renderK8s(ctx, manifest, profName, prof, "")
+		if err != nil {
+			return nil, "", err
+		}
+		if err := os.WriteFile(path, []byte(output), 0600); err != nil {
+			return nil, "", err
+		}
+		rt := kubernetes.New()
+		rt.Cluster = clusterFromState()
+		if ok, _ := rt.Detect(ctx); !ok {
+			return nil, "", fmt.Errorf("kubectl not found in PATH")
+		}
+		return rt, path, nil
+	}
+
+	rt, err := selectRuntime(ctx)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
+	composePath := filepath.Join(devxDir, composeFile)
+	if err := writeCompose(ctx, composePath, manifest, profName, prof, nil, telemetryFromState()); err != nil {
+		return nil, "", err
+	}
+	return rt, composePath, nil
+}
 
-	if strings.Contains(string(data), entry) {
-		return nil
+// selectServices returns the named services and deps together with
+// everything they transitively depend on.
+func selectServices(prof *config.Profile, names []string) ([]string, error) {
+	g, err := graph.Build(prof)
+	if err != nil {
+		return nil, err
 	}
+	return graph.Closure(g, names)
+}
 
-	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+// subsetProfile returns a copy of prof restricted to the named services and
+// deps. Exec hooks are kept only when their service is included.
+func subsetProfile(prof *config.Profile, names []string) *config.Profile {
+	include := map[string]bool{}
+	for _, name := range names {
+		include[name] = true
+	}
+
+	sub := *prof
+	sub.Services = map[string]config.Service{}
+	for name, svc := range prof.Services {
+		if include[name] {
+			sub.Services[name] = svc
+		}
+	}
+	sub.Deps = map[string]config.Dep{}
+	for name, dep := range prof.Deps {
+		if include[name] {
+			sub.Deps[name] = dep
+		}
+	}
+
+	filter := func(hooks []config.Hook) []config.Hook {
+		var out []config.Hook
+		for _, h := range hooks {
+			if h.Exec == "" || include[h.Service] {
+				out = append(out, h)
+			}
+		}
+		return out
+	}
+	sub.Hooks.AfterUp = filter(prof.Hooks.AfterUp)
+	sub.Hooks.BeforeDown = filter(prof.Hooks.BeforeDown)
+	return &sub
+}
+
+// servicesToStop returns the named services plus those of their transitive
+// dependencies that no other running service still needs.
+func servicesToStop(prof *config.Profile, names []string, running []string) ([]string, error) {
+	g, err := graph.Build(prof)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	closure, err := graph.Closure(g, names)
+	if err != nil {
+		return nil, err
+	}
+
+	named := map[string]bool{}
+	for _, name := range names {
+		named[name] = true
+	}
+	inClosure := map[string]bool{}
+	for _, name := range closure {
+		inClosure[name] = true
+	}
+	var others []string
+	for _, name := range running {
+		if _, ok := g.Nodes[name]; ok && !inClosure[name] {
+			others = append(others, name)
+		}
+	}
+	needed, err := graph.Closure(g, others)
+	if err != nil {
+		return nil, err
+	}
+	keep := map[string]bool{}
+	for _, name := range needed {
+		keep[name] = true
 	}
-	defer f.Close()
 
-	_, err = f.WriteString("\n" + entry + "\n")
-	return err
+	var stop []string
+	for _, name := range closure {
+		if named[name] || !keep[name] {
+			stop = append(stop, name)
+		}
+	}
+	return stop, nil
+}
+
+// extraComposeServices returns the services of a rendered compose file that do
+// not come from prof, such as the telemetry stack and plugin fragments.
+func extraComposeServices(path string, prof *config.Profile) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// Only the names are needed; plugin services may use compose syntax
+	// compose.File does not model.
+	var file struct {
+		Services map[string]yaml.Node `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	var extras []string
+	for _, name := range util.SortedKeys(file.Services) {
+		_, isService := prof.Services[name]
+		_, isDep := prof.Deps[name]
+		if !isService && !isDep {
+			extras = append(extras, name)
+		}
+	}
+	return extras, nil
+}
+
+func profileRuntime(prof *config.Profile) string {
+	if prof == nil || prof.Runtime == "" {
+		return "compose"
+	}
+	return prof.Runtime
+}
+
+func ensureDevxDir() error {
+	return os.MkdirAll(devxDir, 0755)
 }
 
 func fileExists(path string) bool {
@@ -165,6 +400,24 @@ func readState() *state {
 	return &s
 }
 
+// clusterFlags registers --context and --kubeconfig, which select the cluster
+// of k8s profiles.
+func clusterFlags(fs *flag.FlagSet) *k8s.Kubectl {
+	cluster := &k8s.Kubectl{}
+	fs.StringVar(&cluster.Context, "context", "", "Kubernetes context (k8s profiles)")
+	fs.StringVar(&cluster.Kubeconfig, "kubeconfig", "", "Path to the kubeconfig file (k8s profiles)")
+	return cluster
+}
+
+// clusterFromState returns the cluster recorded by the last `devx up`.
+func clusterFromState() k8s.Kubectl {
+	st := readState()
+	if st == nil {
+		return k8s.Kubectl{}
+	}
+	return k8s.Kubectl{Context: st.KubeContext, Kubeconfig: st.Kubeconfig}
+}
+
 func telemetryFromState() bool {
 	st := readState()
 	if st == nil {
@@ -173,87 +426,164 @@ func telemetryFromState() bool {
 	return st.Telemetry
 }
 
-func streamLogs(reader io.Reader, jsonOut bool) error {
+// streamLogs parses the lines of a runtime log stream and prints the entries
+// filter keeps.
+func streamLogs(reader io.Reader, projectName string, printer *logs.Printer, filter logs.Filter) error {
 	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
 	for scanner.Scan() {
-		line := scanner.Text()
-		if !jsonOut {
-			fmt.Println(line)
+		entry := logs.Parse(scanner.Text(), projectName)
+		if !filter.Match(entry) {
 			continue
 		}
-		entry := map[string]string{"line": line}
-		data, err := json.Marshal(entry)
-		if err != nil {
+		if err := printer.Print(entry); err != nil {
 			return err
 		}
-		fmt.Println(string(data))
 	}
 	return scanner.Err()
 }
 
-func waitForHealth(profile *config.Profile) error {
+// colorOutput reports whether to colour stdout: it is a terminal and colours
+// are not turned off with NO_COLOR or a flag.
+func colorOutput(noColor bool) bool {
+	return !noColor && os.Getenv("NO_COLOR") == "" && ui.IsTerminal(os.Stdout)
+}
+
+// waitForHealth blocks until every service with a health check is ready.
+// Probe-based checks use the health state reported by the runtime; logMatch
+// checks watch the service's logs for a matching line.
+func waitForHealth(ctx context.Context, rt devxruntime.Runtime, composePath string, projectName string, profile *config.Profile) error {
 	if profile == nil {
 		return nil
 	}
 
-	type check struct {
-		name string
-		url  string
-	}
-
-	var checks []check
+	timeout := 2 * time.Minute
+	probed := map[string]bool{}
+	logMatches := map[string]*regexp.Regexp{}
 	for name, svc := range profile.Services {
-		if svc.Health == nil || svc.Health.HttpGet == "" {
+		switch svc.Health.Type() {
+		case "":
 			continue
+		case config.HealthLogMatch:
+			logMatches[name] = regexp.MustCompile(svc.Health.LogMatch)
+		default:
+			probed[name] = true
+		}
+		if d, err := time.ParseDuration(svc.Health.StartPeriod); err == nil && d > 0 && 2*time.Minute+d > timeout {
+			timeout = 2*time.Minute + d
 		}
-		checks = append(checks, check{name: name, url: svc.Health.HttpGet})
 	}
-
-	if len(checks) == 0 {
+	if len(probed) == 0 && len(logMatches) == 0 {
 		return nil
 	}
 
-	deadline := time.Now().Add(2 * time.Minute)
-	pending := map[string]string{}
-	for _, c := range checks {
-		pending[c.name] = c.url
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	errs := make(chan error, len(logMatches)+1)
+	for name, pattern := range logMatches {
+		go func(name string, pattern *regexp.Regexp) {
+			errs <- waitForLogMatch(ctx, rt, composePath, projectName, name, pattern)
+		}(name, pattern)
 	}
+	go func() {
+		errs <- waitForProbes(ctx, rt, composePath, projectName, probed)
+	}()
 
-	for len(pending) > 0 && time.Now().Before(deadline) {
-		for name, url := range pending {
-			if checkHTTP(url) {
-				delete(pending, name)
+	var failures []string
+	for i := 0; i < len(logMatches)+1; i++ {
+		if err := <-errs; err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		sort.Strings(failures)
+		return fmt.Errorf("health checks failed: %s", strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+// waitForProbes polls the runtime status until every pending service reports
+// healthy. A service that reports unhealthy or has exited fails immediately.
+func waitForProbes(ctx context.Context, rt devxruntime.Runtime, composePath string, projectName string, pending map[string]bool) error {
+	for len(pending) > 0 {
+		statuses, err := rt.Status(ctx, composePath, projectName)
+		if err == nil {
+			for _, status := range statuses {
+				if !pending[status.Name] {
+					continue
+				}
+				switch {
+				case status.Health == "healthy":
+					delete(pending, status.Name)
+				case status.Health == "unhealthy":
+					return fmt.Errorf("%s (unhealthy)", status.Name)
+				case status.State == "exited" || status.State == "dead":
+					return fmt.Errorf("%s (%s)", status.Name, status.State)
+				}
 			}
 		}
 		if len(pending) == 0 {
 			break
 		}
-		time.Sleep(2 * time.Second)
-	}
 
-	if len(pending) > 0 {
-		var parts []string
-		for name, url := range pending {
-			parts = append(parts, fmt.Sprintf("%s (%s)", name, url))
+		select {
+		case <-ctx.Done():
+			var names []string
+			for name := range pending {
+				names = append(names, name+" (timed out)")
+			}
+			sort.Strings(names)
+			return fmt.Errorf("%s", strings.Join(names, ", "))
+		case <-time.After(2 * time.Second):
 		}
-		return fmt.Errorf("health checks failed: %s", strings.Join(parts, ", "))
 	}
-
 	return nil
 }
 
-func checkHTTP(url string) bool {
-	client := http.Client{Timeout: 2 * time.Second}
-	resp, err := client.Get(url)
+// waitForLogMatch follows the logs of a service until a line matches pattern.
+func waitForLogMatch(ctx context.Context, rt devxruntime.Runtime, composePath string, projectName string, service string, pattern *regexp.Regexp) error {
+	reader, err := rt.Logs(ctx, composePath, projectName, devxruntime.LogsOptions{Service: service, Follow: true})
 	if err != nil {
-		return false
+		return fmt.Errorf("%s (%v)", service, err)
+	}
+	defer reader.Close()
+
+	if logMatches(reader, projectName, pattern) {
+		return nil
 	}
-	defer resp.Body.Close()
-	return resp.StatusCode >= 200 && resp.StatusCode < 300
+	if ctx.Err() != nil {
+		return fmt.Errorf("%s (no log line matched %q)", service, pattern.String())
+	}
+	return fmt.Errorf("%s (logs ended before a line matched %q)", service, pattern.String())
 }
 
-func collectImages(manifest *config.Manifest, profileName string, prof *config.Profile) ([]string, error) {
-	composed, err := buildCompose(manifest, profileName, prof, nil, true)
+// logMatches reads log lines until the message of one matches pattern. The
+// runtime's service prefix and timestamp are not part of the message, so
+// patterns can be anchored.
+func logMatches(reader io.Reader, projectName string, pattern *regexp.Regexp) bool {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if pattern.MatchString(logs.Parse(scanner.Text(), projectName).Message) {
+			return true
+		}
+	}
+	return false
+}
+
+// collectImages returns the images a profile runs, followed by the base images
+// its builds start from. Telemetry and plugin services only run under compose,
+// so k8s profiles are rendered without them. A build without a Dockerfile is
+// reported and skipped, so it does not block locking the other profiles.
+func collectImages(ctx context.Context, manifest *config.Manifest, profileName string, prof *config.Profile) ([]string, error) {
+	var composed string
+	var err error
+	if profileRuntime(prof) == "compose" {
+		composed, err = buildCompose(ctx, manifest, profileName, prof, nil, true)
+	} else {
+		composed, err = compose.Render(manifest, profileName, prof, compose.RewriteOptions{RegistryPrefix: manifest.Registry.Prefix}, false, nil)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -263,7 +593,15 @@ func collectImages(manifest *config.Manifest, profileName string, prof *config.P
 		return nil, err
 	}
 
-	return imgs, nil
+	bases, missing, err := compose.BaseImages(prof, compose.RewriteOptions{RegistryPrefix: manifest.Registry.Prefix})
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range missing {
+		fmt.Fprintf(os.Stderr, "warning: profile '%s' service '%s' has no Dockerfile; its base images are not locked\n", profileName, name)
+	}
+
+	return append(imgs, bases...), nil
 }
 
 func selectRuntime(ctx context.Context) (devxruntime.Runtime, error) {
@@ -281,16 +619,17 @@ func selectRuntime(ctx context.Context) (devxruntime.Runtime, error) {
 // printLinks queries the running stack for actual host-port bindings and prints
 // http://localhost:<port> for every published port. Using the runtime (not the
 // compose YAML) ensures randomly-assigned ports are reflected correctly.
+type link struct {
+	label string
+	url   string
+}
+
 func printLinks(ctx context.Context, rt devxruntime.Runtime, composePath, projectName string) {
 	statuses, err := rt.Status(ctx, composePath, projectName)
 	if err != nil {
 		return
 	}
 
-	type link struct {
-		label string
-		url   string
-	}
 	var links []link
 	seen := map[string]bool{}
 
@@ -310,6 +649,38 @@ func printLinks(ctx context.Context, rt devxruntime.Runtime, composePath, projec
 		}
 	}
 
+	printLinkTable(links)
+}
+
+// publishedURL returns the localhost URL of the host port a service publishes
+// for containerPort, or "" if the service is not running or not published.
+func publishedURL(statuses []devxruntime.ServiceStatus, service string, containerPort int) string {
+	for _, st := range statuses {
+		if st.Name != service {
+			continue
+		}
+		for _, pub := range st.Publishers {
+			if pub.TargetPort == containerPort && pub.PublishedPort != 0 {
+				return fmt.Sprintf("http://localhost:%d", pub.PublishedPort)
+			}
+		}
+	}
+	return ""
+}
+
+// printExposedLinks prints the external URL of every service with an expose
+// block, as routed by the rendered Ingress or HTTPRoute.
+func printExposedLinks(prof *config.Profile) {
+	var links []link
+	for _, name := range util.SortedKeys(prof.Services) {
+		if expose := prof.Services[name].Expose; expose != nil {
+			links = append(links, link{label: serviceLabel(name), url: expose.URL()})
+		}
+	}
+	printLinkTable(links)
+}
+
+func printLinkTable(links []link) {
 	if len(links) == 0 {
 		return
 	}
@@ -328,6 +699,73 @@ func printLinks(ctx context.Context, rt devxruntime.Runtime, composePath, projec
 	}
 }
 
+// printConnections prints a host-side connection string for every dep that
+// publishes its port, using the connection template of the dep's kind.
+func printConnections(prof *config.Profile) {
+	type conn struct {
+		name string
+		url  string
+	}
+	var conns []conn
+
+	for _, name := range util.SortedKeys(prof.Deps) {
+		dep := prof.Deps[name]
+		kind, ok := deps.Lookup(dep.Kind)
+		if !ok {
+			continue
+		}
+		containerPort := kind.DefaultPort
+		if kind.HostPort != 0 {
+			containerPort = kind.HostPort
+		}
+		port := publishedPort(dep.Ports, containerPort)
+		if port == 0 {
+			continue
+		}
+		url, err := kind.Connection("localhost", port, dep.Env)
+		if err != nil || url == "" {
+			continue
+		}
+		conns = append(conns, conn{name: name, url: url})
+	}
+
+	if len(conns) == 0 {
+		return
+	}
+
+	fmt.Println("\nDependencies:")
+	maxLen := 0
+	for _, c := range conns {
+		if len(c.name) > maxLen {
+			maxLen = len(c.name)
+		}
+	}
+	for _, c := range conns {
+		fmt.Printf("  %-*s  %s\n", maxLen, c.name, c.url)
+	}
+}
+
+// publishedPort returns the host port that maps to containerPort in a list of
+// "host:container" port specs, or 0 when it is not published.
+func publishedPort(ports []string, containerPort int) int {
+	for _, spec := range ports {
+		parts := strings.Split(spec, ":")
+		if len(parts) < 2 {
+			continue
+		}
+		target, err := strconv.Atoi(strings.SplitN(parts[len(parts)-1], "/", 2)[0])
+		if err != nil || target != containerPort {
+			continue
+		}
+		host, err := strconv.Atoi(parts[len(parts)-2])
+		if err != nil {
+			continue
+		}
+		return host
+	}
+	return 0
+}
+
 // wellKnownLabels maps telemetry service name suffixes to display labels.
 var wellKnownLabels = map[string]string{
 	"grafana":     "Grafana",
@@ -22,6 +22,10 @@ type state struct {
 	Profile   string `json:"profile"`
 	Runtime   string `json:"runtime"`
 	Telemetry bool   `json:"telemetry"`
+	// KubeContext and Kubeconfig record the cluster a k8s profile was
+	// brought up on, for later commands.
+	KubeContext string `json:"kubeContext,omitempty"`
+	Kubeconfig  string `json:"kubeconfig,omitempty"`
 }
 
 func main() {
@@ -42,8 +46,12 @@ func main() {
 		err = runUp(ctx, args)
 	case "down":
 		err = runDown(ctx, args)
+	case "watch":
+		err = runWatch(ctx, args)
 	case "status":
 		err = runStatus(ctx, args)
+	case "top":
+		err = runTop(ctx, args)
 	case "logs":
 		err = runLogs(ctx, args)
 	case "exec":
@@ -76,14 +84,19 @@ func printUsage() {
 	fmt.Println("devx - cross-platform dev orchestrator")
 	fmt.Println("\nUsage:")
 	fmt.Println("  devx init")
-	fmt.Println("  devx up [--profile local|ci|k8s] [--build] [--pull] [--no-telemetry]")
-	fmt.Println("  devx down [--volumes]")
-	fmt.Println("  devx status")
-	fmt.Println("  devx logs [service] [--follow] [--since 10m] [--json]")
+	fmt.Println("  devx up [--profile local|ci|k8s] [--build] [--pull] [--no-telemetry] [--watch] [--timeout 5m] [--context ctx] [--kubeconfig path] [service...]")
+	fmt.Println("  devx watch [--profile name] [--debounce 500ms] [service...]")
+	fmt.Println("  devx down [--volumes] [--context ctx] [--kubeconfig path] [service...]")
+	fmt.Println("  devx status [--output table|json|yaml|template=...] [--watch [--interval 2s]]")
+	fmt.Println("  devx top [--interval 2s]")
+	fmt.Println("  devx logs [service] [--follow] [--since 10m] [--until time] [--query logql] [--limit n] [--json] [--grep re] [--level warn] [--no-color]")
 	fmt.Println("  devx exec <service> -- <cmd...>")
-	fmt.Println("  devx doctor [--fix]")
+	fmt.Println("  devx doctor [--fix [--yes]] [--output table|json|yaml|template=...]")
 	fmt.Println("  devx render compose [--write] [--no-telemetry]")
 	fmt.Println("  devx render k8s [--profile name] [--namespace ns] [--write]")
-	fmt.Println("  devx lock update")
+	fmt.Println("  devx render kustomize --out dir [--profile name] [--namespace ns]")
+	fmt.Println("  devx render helm --out dir [--profile name] [--namespace ns]")
+	fmt.Println("  devx lock update [--output format] [profile...]")
+	fmt.Println("  devx lock verify [--output format] [profile...]")
 	fmt.Println("  devx version")
 }
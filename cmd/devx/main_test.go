@@ -1,9 +1,17 @@
 package main
 
 import (
+	"context"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/dever-labs/devx/internal/config"
+	"github.com/dever-labs/devx/internal/runtime"
+	"github.com/dever-labs/devx/internal/telemetry"
 )
 
 const validManifest = `version: 1
@@ -113,3 +121,234 @@ func TestLoadProfile_NoManifest(t *testing.T) {
 		t.Fatal("expected error when devx.yaml is missing")
 	}
 }
+
+func subsetTestProfile() *config.Profile {
+	return &config.Profile{
+		Services: map[string]config.Service{
+			"api":    {Image: "api", DependsOn: []config.Dependency{{Name: "db"}, {Name: "cache"}}},
+			"worker": {Image: "worker", DependsOn: []config.Dependency{{Name: "db"}}},
+		},
+		Deps: map[string]config.Dep{
+			"db":    {Kind: "postgres"},
+			"cache": {Kind: "redis"},
+		},
+		Hooks: config.Hooks{
+			AfterUp: []config.Hook{
+				{Exec: "migrate up", Service: "api"},
+				{Exec: "seed", Service: "worker"},
+				{Run: "./scripts/setup.sh"},
+			},
+		},
+	}
+}
+
+func TestSubsetProfile(t *testing.T) {
+	prof := subsetTestProfile()
+
+	selected, err := selectServices(prof, []string{"api"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(selected, ",") != "api,cache,db" {
+		t.Fatalf("unexpected selection %v", selected)
+	}
+
+	sub := subsetProfile(prof, selected)
+	if _, ok := sub.Services["worker"]; ok || len(sub.Services) != 1 || len(sub.Deps) != 2 {
+		t.Fatalf("unexpected subset services %v deps %v", sub.Services, sub.Deps)
+	}
+	if len(sub.Hooks.AfterUp) != 2 || sub.Hooks.AfterUp[0].Service != "api" || sub.Hooks.AfterUp[1].Run == "" {
+		t.Fatalf("unexpected subset hooks %+v", sub.Hooks.AfterUp)
+	}
+	if len(prof.Services) != 2 {
+		t.Fatalf("subsetProfile must not modify the profile")
+	}
+}
+
+func TestServicesToStop(t *testing.T) {
+	prof := subsetTestProfile()
+
+	stop, err := servicesToStop(prof, []string{"api"}, []string{"api", "worker", "db", "cache", "devx-telemetry-grafana"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(stop, ",") != "api,cache" {
+		t.Fatalf("expected shared db to keep running, got %v", stop)
+	}
+
+	stop, err = servicesToStop(prof, []string{"api"}, []string{"api", "db", "cache"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(stop, ",") != "api,cache,db" {
+		t.Fatalf("expected unused deps to stop, got %v", stop)
+	}
+}
+
+func TestStatusRows(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	statuses := []runtime.ServiceStatus{
+		{
+			Name: "api", State: "running", Health: "healthy", StartedAt: now.Add(-90 * time.Minute), Restarts: 2,
+			Publishers: []runtime.Publisher{
+				{URL: "0.0.0.0", TargetPort: 80, PublishedPort: 8080},
+				{URL: "::", TargetPort: 80, PublishedPort: 8080},
+				{TargetPort: 9000},
+			},
+		},
+		{Name: "db", State: "exited", StartedAt: now.Add(-time.Hour)},
+		{Name: "devx-telemetry-prometheus", State: "running", Publishers: []runtime.Publisher{{TargetPort: 9090, PublishedPort: 53211}}},
+	}
+	usage := map[string]telemetry.Usage{"api": {CPU: 12.345, Memory: 52428800}}
+
+	headers, rows := statusRows(statuses, usage, true, now)
+	if got := strings.Join(headers, ","); got != "Service,State,Health,Uptime,Restarts,CPU,Memory,URLs" {
+		t.Fatalf("unexpected headers %s", got)
+	}
+	want := [][]string{
+		{"api", "running", "healthy", "1h30m", "2", "12.3%", "50.0MiB", "http://localhost:8080"},
+		{"db", "exited", "-", "-", "0", "-", "-", "-"},
+		{"devx-telemetry-prometheus", "running", "-", "-", "0", "-", "-", "http://localhost:53211"},
+	}
+	for i := range want {
+		if strings.Join(rows[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d: expected %v, got %v", i, want[i], rows[i])
+		}
+	}
+
+	headers, _ = statusRows(statuses, nil, false, now)
+	if got := strings.Join(headers, ","); got != "Service,State,Health,Uptime,Restarts,URLs" {
+		t.Errorf("unexpected headers without telemetry %s", got)
+	}
+
+	if got := publishedURL(statuses, "devx-telemetry-prometheus", 9090); got != "http://localhost:53211" {
+		t.Errorf("unexpected prometheus URL %q", got)
+	}
+	if got := publishedURL(statuses, "api", 9000); got != "" {
+		t.Errorf("expected no URL for an unpublished port, got %q", got)
+	}
+}
+
+func TestFormatUptime(t *testing.T) {
+	cases := map[time.Duration]string{
+		45 * time.Second:                      "45s",
+		12*time.Minute + 3*time.Second:        "12m3s",
+		5*time.Hour + 20*time.Minute:          "5h20m",
+		76*time.Hour + 30*time.Minute:         "3d4h",
+		59*time.Second + 600*time.Millisecond: "1m0s",
+	}
+	for d, want := range cases {
+		if got := formatUptime(d); got != want {
+			t.Errorf("formatUptime(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
+
+func TestLogTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"":                     now.Add(-time.Hour),
+		"10m":                  now.Add(-10 * time.Minute),
+		"2024-05-01T09:30:00Z": time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC),
+	}
+	for value, want := range cases {
+		got, err := logTime(value, now, now.Add(-time.Hour))
+		if err != nil || !got.Equal(want) {
+			t.Errorf("logTime(%q) = %v, %v; want %v", value, got, err, want)
+		}
+	}
+	if _, err := logTime("yesterday", now, now); err == nil {
+		t.Error("expected an invalid time error")
+	}
+
+	if got := defaultLogQuery("shop", "api"); got != `{compose_project="shop", compose_service="api"}` {
+		t.Errorf("unexpected query %s", got)
+	}
+	if got := defaultLogQuery("shop", ""); got != `{compose_project="shop"}` {
+		t.Errorf("unexpected query %s", got)
+	}
+}
+
+func TestLogMatches(t *testing.T) {
+	pattern := regexp.MustCompile(`^ready on :\d+$`)
+	lines := "api-1  | 2024-05-01T10:00:00.123456789Z starting\napi-1  | 2024-05-01T10:00:01.5Z ready on :8080\n"
+	if !logMatches(strings.NewReader(lines), "shop", pattern) {
+		t.Error("expected the anchored pattern to match the message after the prefix and timestamp")
+	}
+	if logMatches(strings.NewReader("api-1  | 2024-05-01T10:00:00Z not ready on :8080\n"), "shop", pattern) {
+		t.Error("expected no match")
+	}
+}
+
+func TestWriteSecrets(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "db-password.txt")
+	if err := os.WriteFile(secretFile, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DEVX_TEST_TOKEN", "tok")
+
+	manifest := &config.Manifest{Secrets: map[string]config.Secret{
+		"db-password": {File: secretFile},
+		"token":       {Env: "DEVX_TEST_TOKEN"},
+	}}
+	prof := &config.Profile{
+		Services: map[string]config.Service{
+			"api": {Image: "api", SecretEnv: map[string]string{"DB_PASSWORD": "db-password", "TOKEN": "token"}},
+			"web": {Image: "web"},
+		},
+		Deps: map[string]config.Dep{
+			"db": {Kind: "postgres", SecretEnv: map[string]string{"POSTGRES_PASSWORD": "db-password"}},
+		},
+	}
+	out := filepath.Join(dir, ".devx")
+	if err := writeSecrets(context.Background(), out, manifest, prof); err != nil {
+		t.Fatalf("writeSecrets failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(out, "secrets", "env", "api.env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "DB_PASSWORD='s3cret'\nTOKEN='tok'\n" {
+		t.Errorf("unexpected api env file %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(out, "secrets", "env", "db.env")); string(data) != "POSTGRES_PASSWORD='s3cret'\n" {
+		t.Errorf("unexpected db env file %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(out, "secrets", "env", "web.env")); !os.IsNotExist(err) {
+		t.Errorf("expected no env file for a service without secrets, got %v", err)
+	}
+
+	prof.Services["api"].SecretEnv["TOKEN"] = "missing"
+	manifest.Secrets["missing"] = config.Secret{Env: "DEVX_TEST_UNSET"}
+	if err := writeSecrets(context.Background(), out, manifest, prof); err == nil || !strings.Contains(err.Error(), "secret 'missing'") {
+		t.Errorf("expected an unresolvable secret error, got %v", err)
+	}
+}
+
+func TestCollectImages(t *testing.T) {
+	manifest := &config.Manifest{Version: 1, Project: config.Project{Name: "my-app"}}
+	prof := &config.Profile{Services: map[string]config.Service{
+		"api": {Image: "registry.example.com/api:1"},
+		"web": {Image: "web:1", Build: &config.Build{Context: filepath.Join(t.TempDir(), "missing")}},
+	}}
+
+	composed, err := collectImages(context.Background(), manifest, "local", prof)
+	if err != nil {
+		t.Fatalf("collect failed: %v", err)
+	}
+	if !strings.Contains(strings.Join(composed, " "), "grafana/grafana") {
+		t.Errorf("expected telemetry images for a compose profile, got %v", composed)
+	}
+
+	// k8s profiles never run the telemetry stack.
+	prof.Runtime = "k8s"
+	clustered, err := collectImages(context.Background(), manifest, "cluster", prof)
+	if err != nil {
+		t.Fatalf("collect failed: %v", err)
+	}
+	if strings.Join(clustered, " ") != "registry.example.com/api:1" {
+		t.Errorf("expected only the service image for a k8s profile, got %v", clustered)
+	}
+}
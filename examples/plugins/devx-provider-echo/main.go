@@ -11,6 +11,13 @@ type Response struct {
 	Version string `json:"version"`
 }
 
+// Request mirrors plugins.RenderRequest; devx writes it to stdin on render.
+type Request struct {
+	Project     string         `json:"project"`
+	ProfileName string         `json:"profileName"`
+	Profile     map[string]any `json:"profile"`
+}
+
 func main() {
 	if len(os.Args) < 2 {
 		return
@@ -21,6 +28,11 @@ func main() {
 		resp := Response{Name: "echo", Version: "0.1.0"}
 		_ = json.NewEncoder(os.Stdout).Encode(resp)
 	case "render":
+		var req Request
+		if err := json.NewDecoder(os.Stdin).Decode(&req); err != nil {
+			fmt.Fprintf(os.Stderr, "invalid render request: %v\n", err)
+			os.Exit(1)
+		}
 		fmt.Println("{}")
 	}
 }
@@ -2,11 +2,18 @@ package compose
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/dever-labs/devx/internal/config"
+	"github.com/dever-labs/devx/internal/deps"
 	"github.com/dever-labs/devx/internal/lock"
+	"github.com/dever-labs/devx/internal/plugins"
 	"github.com/dever-labs/devx/internal/util"
 	"gopkg.in/yaml.v3"
 )
@@ -15,49 +22,158 @@ type File struct {
 	Services map[string]Service `yaml:"services"`
 	Networks map[string]Network `yaml:"networks,omitempty"`
 	Volumes  map[string]Volume  `yaml:"volumes,omitempty"`
+	Secrets  map[string]Secret  `yaml:"secrets,omitempty"`
 }
 
 type Network struct{}
 
 type Volume struct{}
 
+// Secret is a top-level compose secret, read from a file or from an
+// environment variable of the `docker compose` process.
+type Secret struct {
+	File        string `yaml:"file,omitempty"`
+	Environment string `yaml:"environment,omitempty"`
+}
+
+// SecretsDir is the directory, relative to the compose file, where secrets
+// sourced from a command are materialised before `docker compose` runs.
+const SecretsDir = "secrets"
+
 type Service struct {
 	Image       string            `yaml:"image,omitempty"`
 	Build       *Build            `yaml:"build,omitempty"`
 	Ports       []string          `yaml:"ports,omitempty"`
 	Environment map[string]string `yaml:"environment,omitempty"`
+	EnvFile     []string          `yaml:"env_file,omitempty"`
 	Command     []string          `yaml:"command,omitempty"`
 	WorkingDir  string            `yaml:"working_dir,omitempty"`
 	Volumes     []string          `yaml:"volumes,omitempty"`
-	DependsOn   []string          `yaml:"depends_on,omitempty"`
+	DependsOn   DependsOn         `yaml:"depends_on,omitempty"`
 	Labels      map[string]string `yaml:"labels,omitempty"`
 	Healthcheck *Healthcheck      `yaml:"healthcheck,omitempty"`
 	Networks    []string          `yaml:"networks,omitempty"`
+	Secrets     []string          `yaml:"secrets,omitempty"`
 	Privileged  bool              `yaml:"privileged,omitempty"`
+	Deploy      *Deploy           `yaml:"deploy,omitempty"`
+}
+
+type Deploy struct {
+	Resources DeployResources `yaml:"resources"`
+}
+
+type DeployResources struct {
+	Limits       *ResourceSpec `yaml:"limits,omitempty"`
+	Reservations *ResourceSpec `yaml:"reservations,omitempty"`
+}
+
+type ResourceSpec struct {
+	CPUs   string `yaml:"cpus,omitempty"`
+	Memory string `yaml:"memory,omitempty"`
+}
+
+// deploy maps a resources block to compose deploy.resources: requests become
+// reservations and limits stay limits.
+func deploy(res *config.Resources) *Deploy {
+	if res == nil {
+		return nil
+	}
+	out := &Deploy{}
+	out.Resources.Reservations = resourceSpec(res.CPU, res.Memory)
+	if res.Limits != nil {
+		out.Resources.Limits = resourceSpec(res.Limits.CPU, res.Limits.Memory)
+	}
+	if out.Resources.Reservations == nil && out.Resources.Limits == nil {
+		return nil
+	}
+	return out
+}
+
+func resourceSpec(cpu string, memory string) *ResourceSpec {
+	if cpu == "" && memory == "" {
+		return nil
+	}
+	spec := &ResourceSpec{}
+	if memory != "" {
+		spec.Memory = config.MemoryBytes(memory)
+	}
+	if cpu != "" {
+		spec.CPUs = config.CPUCores(cpu)
+	}
+	return spec
+}
+
+// DependsOn is the long-form compose depends_on map. The short list form is
+// accepted when decoding plugin fragments.
+type DependsOn map[string]Dependency
+
+type Dependency struct {
+	Condition string `yaml:"condition"`
+}
+
+func (d *DependsOn) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.SequenceNode {
+		var names []string
+		if err := node.Decode(&names); err != nil {
+			return err
+		}
+		*d = DependsOn{}
+		for _, name := range names {
+			(*d)[name] = Dependency{Condition: "service_started"}
+		}
+		return nil
+	}
+	var long map[string]Dependency
+	if err := node.Decode(&long); err != nil {
+		return err
+	}
+	*d = long
+	return nil
+}
+
+// Compose names for the config.Condition* values.
+var dependsOnConditions = map[string]string{
+	"":                        "service_started",
+	config.ConditionStarted:   "service_started",
+	config.ConditionHealthy:   "service_healthy",
+	config.ConditionCompleted: "service_completed_successfully",
+}
+
+func dependsOn(deps []config.Dependency) DependsOn {
+	if len(deps) == 0 {
+		return nil
+	}
+	out := make(DependsOn, len(deps))
+	for _, dep := range deps {
+		out[dep.Name] = Dependency{Condition: dependsOnConditions[dep.Condition]}
+	}
+	return out
 }
 
 type Build struct {
 	Context    string `yaml:"context"`
 	Dockerfile string `yaml:"dockerfile,omitempty"`
+	// DockerfileInline replaces Dockerfile when its base images are rewritten.
+	DockerfileInline string `yaml:"dockerfile_inline,omitempty"`
 }
 
 type Healthcheck struct {
-	Test     []string `yaml:"test"`
-	Interval string   `yaml:"interval,omitempty"`
-	Retries  int      `yaml:"retries,omitempty"`
+	Test        []string `yaml:"test"`
+	Interval    string   `yaml:"interval,omitempty"`
+	Timeout     string   `yaml:"timeout,omitempty"`
+	Retries     int      `yaml:"retries,omitempty"`
+	StartPeriod string   `yaml:"start_period,omitempty"`
 }
 
 type RewriteOptions struct {
 	RegistryPrefix string
 	Lockfile       *lock.Lockfile
+	// BaseDir is the directory build contexts are relative to; empty means
+	// the working directory.
+	BaseDir string
 }
 
-var depImages = map[string]string{
-	"postgres": "postgres",
-	"redis":    "redis",
-}
-
-func Render(manifest *config.Manifest, profileName string, profile *config.Profile, rewrite RewriteOptions, enableTelemetry bool) (string, error) {
+func Render(manifest *config.Manifest, profileName string, profile *config.Profile, rewrite RewriteOptions, enableTelemetry bool, fragments []plugins.Fragment) (string, error) {
 	if manifest == nil || profile == nil {
 		return "", fmt.Errorf("manifest and profile are required")
 	}
@@ -66,28 +182,46 @@ func Render(manifest *config.Manifest, profileName string, profile *config.Profi
 		Services: map[string]Service{},
 		Networks: map[string]Network{"devx_default": {}},
 		Volumes:  map[string]Volume{},
+		Secrets:  map[string]Secret{},
+	}
+
+	for _, name := range config.ReferencedSecrets(profile) {
+		secret, ok := manifest.Secrets[name]
+		if !ok {
+			return "", fmt.Errorf("secret '%s' is not defined", name)
+		}
+		file.Secrets[name] = composeSecret(name, secret)
 	}
 
 	for _, name := range util.SortedKeys(profile.Deps) {
 		dep := profile.Deps[name]
-		image := depImages[dep.Kind]
-		if dep.Version != "" {
-			image = image + ":" + dep.Version
+		kind, ok := deps.Lookup(dep.Kind)
+		if !ok {
+			return "", fmt.Errorf("dep '%s' kind '%s' is not supported", name, dep.Kind)
 		}
-		image = rewriteImage(image, rewrite)
 
+		env, secretNames, envFile := secretEnv(name, kind.MergeEnv(name, dep.Env), dep.SecretEnv)
 		svc := Service{
-			Image:       image,
-			Environment: dep.Env,
+			Image:       rewriteImage(kind.ImageRef(dep.Version), rewrite),
+			Environment: escapeMap(env),
+			EnvFile:     envFile,
+			Secrets:     secretNames,
 			Ports:       dep.Ports,
+			Command:     escapeList(kind.Command),
 			DependsOn:   nil,
-			Labels:      labels(manifest, profileName, name),
+			Labels:      escapeMap(labels(manifest, profileName, name)),
 			Networks:    []string{"devx_default"},
+			Healthcheck: depHealthcheck(kind),
+			Deploy:      deploy(dep.Resources),
 		}
 
-		if dep.Volume != "" {
-			svc.Volumes = []string{dep.Volume}
-			volumeName := strings.SplitN(dep.Volume, ":", 2)[0]
+		volume, err := kind.VolumeSpec(dep.Volume)
+		if err != nil {
+			return "", fmt.Errorf("dep '%s' volume: %w", name, err)
+		}
+		if volume != "" {
+			svc.Volumes = []string{volume}
+			volumeName := strings.SplitN(volume, ":", 2)[0]
 			if volumeName != "" {
 				file.Volumes[volumeName] = Volume{}
 			}
@@ -111,39 +245,64 @@ func Render(manifest *config.Manifest, profileName string, profile *config.Profi
 
 	for _, name := range util.SortedKeys(profile.Services) {
 		svc := profile.Services[name]
+		env, secretNames, envFile := secretEnv(name, svc.Env, svc.SecretEnv)
+		if enableTelemetry && manifest.Telemetry.Traces {
+			env = otelEnv(env, manifest.Project.Name, profileName, name)
+		}
 		service := Service{
 			Image:       rewriteImage(svc.Image, rewrite),
 			Ports:       svc.Ports,
-			Environment: svc.Env,
-			Command:     svc.Command,
+			Environment: escapeMap(env),
+			EnvFile:     envFile,
+			Secrets:     secretNames,
+			Command:     escapeList(svc.Command),
 			WorkingDir:  svc.Workdir,
-			Volumes:     svc.Mount,
-			DependsOn:   svc.DependsOn,
-			Labels:      labels(manifest, profileName, name),
+			Volumes:     append(append([]string{}, svc.Mount...), svc.Volumes...),
+			DependsOn:   dependsOn(svc.DependsOn),
+			Labels:      escapeMap(labels(manifest, profileName, name)),
 			Networks:    []string{"devx_default"},
+			Deploy:      deploy(svc.Resources),
+		}
+
+		for _, spec := range svc.Volumes {
+			vol, err := config.ParseVolume(spec)
+			if err != nil {
+				return "", fmt.Errorf("service '%s' volume '%s' %w", name, spec, err)
+			}
+			file.Volumes[vol.Name] = Volume{}
+		}
+		if len(service.Volumes) == 0 {
+			service.Volumes = nil
 		}
 
 		if svc.Build != nil {
 			service.Build = &Build{Context: svc.Build.Context, Dockerfile: svc.Build.Dockerfile}
 			service.Image = ""
+			if err := rewriteBaseImages(service.Build, rewrite); err != nil {
+				return "", fmt.Errorf("service '%s' build: %w", name, err)
+			}
 		}
 
-		if svc.Health != nil && svc.Health.HttpGet != "" {
-			service.Healthcheck = &Healthcheck{
-				Test: []string{"CMD-SHELL", fmt.Sprintf("wget -qO- %s >/dev/null 2>&1 || exit 1", svc.Health.HttpGet)},
-			}
-			if svc.Health.Interval != "" {
-				service.Healthcheck.Interval = svc.Health.Interval
-			}
-			if svc.Health.Retries > 0 {
-				service.Healthcheck.Retries = svc.Health.Retries
-			}
+		healthcheck, err := renderHealthcheck(svc.Health, svc.Ports)
+		if err != nil {
+			return "", fmt.Errorf("service '%s' health: %w", name, err)
 		}
+		service.Healthcheck = healthcheck
 
 		file.Services[name] = service
 	}
 
-	data, err := yaml.Marshal(file)
+	var doc yaml.Node
+	if err := doc.Encode(file); err != nil {
+		return "", err
+	}
+	for _, fragment := range fragments {
+		if err := mergeFragment(&doc, manifest, profileName, fragment, rewrite); err != nil {
+			return "", err
+		}
+	}
+
+	data, err := yaml.Marshal(&doc)
 	if err != nil {
 		return "", err
 	}
@@ -151,6 +310,275 @@ func Render(manifest *config.Manifest, profileName string, profile *config.Profi
 	return string(data), nil
 }
 
+// fragmentSections are the top-level compose keys a provider plugin's
+// fragment may set.
+var fragmentSections = map[string]bool{"services": true, "networks": true, "volumes": true, "secrets": true, "configs": true}
+
+// mergeFragment adds the services, networks, volumes, secrets and configs of
+// a provider plugin's compose fragment to doc, the rendered compose file. The
+// fragment is merged as YAML nodes, so every compose key a plugin sets is kept
+// as written. Services get devx labels and the default network unless the
+// fragment sets its own.
+func mergeFragment(doc *yaml.Node, manifest *config.Manifest, profileName string, fragment plugins.Fragment, rewrite RewriteOptions) error {
+	if len(bytes.TrimSpace(fragment.Data)) == 0 {
+		return nil
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(fragment.Data, &root); err != nil {
+		return fmt.Errorf("plugin %s returned an invalid compose fragment: %w", fragment.Plugin, err)
+	}
+	if len(root.Content) == 0 {
+		return nil
+	}
+	extra := root.Content[0]
+	if extra.Kind != yaml.MappingNode {
+		return fmt.Errorf("plugin %s returned an invalid compose fragment: expected a mapping", fragment.Plugin)
+	}
+
+	for i := 0; i+1 < len(extra.Content); i += 2 {
+		key, value := extra.Content[i].Value, extra.Content[i+1]
+		if key == "version" {
+			// Obsolete in compose files; ignored.
+			continue
+		}
+		if !fragmentSections[key] {
+			return fmt.Errorf("plugin %s compose fragment has unsupported key '%s'", fragment.Plugin, key)
+		}
+		if value.Kind != yaml.MappingNode {
+			return fmt.Errorf("plugin %s compose fragment: '%s' must be a mapping", fragment.Plugin, key)
+		}
+
+		section := mappingValue(doc, key)
+		if section == nil {
+			section = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			doc.Content = append(doc.Content, stringNode(key), section)
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			name, entry := value.Content[j].Value, value.Content[j+1]
+			if mappingValue(section, name) != nil {
+				return fmt.Errorf("plugin %s %s name collision: %s", fragment.Plugin, strings.TrimSuffix(key, "s"), name)
+			}
+			if key == "services" {
+				if err := adoptService(entry, manifest, profileName, name, rewrite); err != nil {
+					return fmt.Errorf("plugin %s %w", fragment.Plugin, err)
+				}
+			}
+			section.Content = append(section.Content, value.Content[j], entry)
+		}
+	}
+	return nil
+}
+
+// adoptService rewrites the image of a plugin service and adds the devx labels
+// and default network it does not set itself.
+func adoptService(svc *yaml.Node, manifest *config.Manifest, profileName string, name string, rewrite RewriteOptions) error {
+	if svc.Kind != yaml.MappingNode {
+		return fmt.Errorf("service '%s' must be a mapping", name)
+	}
+	if image := mappingValue(svc, "image"); image != nil && image.Kind == yaml.ScalarNode {
+		image.Value = rewriteImage(image.Value, rewrite)
+	}
+
+	devxLabels := labels(manifest, profileName, name)
+	existing := mappingValue(svc, "labels")
+	switch {
+	case existing == nil:
+		existing = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		svc.Content = append(svc.Content, stringNode("labels"), existing)
+		fallthrough
+	case existing.Kind == yaml.MappingNode:
+		for _, key := range util.SortedKeys(devxLabels) {
+			if mappingValue(existing, key) == nil {
+				existing.Content = append(existing.Content, stringNode(key), stringNode(devxLabels[key]))
+			}
+		}
+	case existing.Kind == yaml.SequenceNode:
+		set := map[string]bool{}
+		for _, item := range existing.Content {
+			set[strings.SplitN(item.Value, "=", 2)[0]] = true
+		}
+		for _, key := range util.SortedKeys(devxLabels) {
+			if !set[key] {
+				existing.Content = append(existing.Content, stringNode(key+"="+devxLabels[key]))
+			}
+		}
+	default:
+		return fmt.Errorf("service '%s' labels must be a mapping or a list", name)
+	}
+
+	// network_mode excludes networks.
+	if mappingValue(svc, "networks") == nil && mappingValue(svc, "network_mode") == nil {
+		networks := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: []*yaml.Node{stringNode("devx_default")}}
+		svc.Content = append(svc.Content, stringNode("networks"), networks)
+	}
+	return nil
+}
+
+// mappingValue returns the value of key in a mapping node, or nil.
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func stringNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+// renderHealthcheck turns a service health config into a compose healthcheck
+// run inside the container. logMatch has no in-container equivalent; devx
+// checks it from the host by reading the service logs.
+func renderHealthcheck(health *config.Health, ports []string) (*Healthcheck, error) {
+	var test []string
+	switch health.Type() {
+	case config.HealthHTTP:
+		port, path, err := health.HTTPTarget(ports)
+		if err != nil {
+			return nil, err
+		}
+		target := fmt.Sprintf("http://localhost:%d%s", port, path)
+		test = []string{"CMD-SHELL", fmt.Sprintf("wget -qO- %[1]s >/dev/null 2>&1 || curl -fsS %[1]s >/dev/null 2>&1 || exit 1", target)}
+	case config.HealthTCP:
+		test = []string{"CMD-SHELL", fmt.Sprintf("nc -z 127.0.0.1 %[1]d >/dev/null 2>&1 || bash -c ':> /dev/tcp/127.0.0.1/%[1]d' >/dev/null 2>&1 || exit 1", health.TCP)}
+	case config.HealthExec:
+		test = []string{"CMD-SHELL", escapeDollar(health.Exec)}
+	default:
+		return nil, nil
+	}
+
+	return &Healthcheck{
+		Test:        test,
+		Interval:    health.Interval,
+		Timeout:     health.Timeout,
+		Retries:     health.Retries,
+		StartPeriod: health.StartPeriod,
+	}, nil
+}
+
+// depHealthcheck runs the dep kind's readiness command. `$` is doubled so
+// that compose passes shell variable references through to the container.
+func depHealthcheck(kind deps.Kind) *Healthcheck {
+	if kind.Readiness == "" {
+		return nil
+	}
+	return &Healthcheck{
+		Test:        []string{"CMD-SHELL", escapeDollar(kind.Readiness)},
+		Interval:    "5s",
+		Timeout:     "5s",
+		Retries:     30,
+		StartPeriod: "10s",
+	}
+}
+
+// composeSecret maps a manifest secret to its compose source. Manifest file
+// paths are relative to devx.yaml while compose resolves them from .devx/;
+// command secrets are read from SecretsDir, where the caller writes them.
+func composeSecret(name string, secret config.Secret) Secret {
+	switch {
+	case secret.File != "":
+		if filepath.IsAbs(secret.File) {
+			return Secret{File: secret.File}
+		}
+		return Secret{File: filepath.ToSlash(filepath.Join("..", secret.File))}
+	case secret.Env != "":
+		return Secret{Environment: secret.Env}
+	default:
+		return Secret{File: "./" + SecretsDir + "/" + name}
+	}
+}
+
+// secretEnv moves the secret-backed env vars of a service out of its inline
+// environment: their values are read from the service's secret env file, as
+// on Kubernetes, where they come from secretKeyRefs. It returns the remaining
+// env, the sorted secret names to mount and the env file to load.
+func secretEnv(service string, env map[string]string, refs map[string]string) (map[string]string, []string, []string) {
+	if len(refs) == 0 {
+		return env, nil, nil
+	}
+
+	out := make(map[string]string, len(env))
+	for key, value := range env {
+		if _, ok := refs[key]; !ok {
+			out[key] = value
+		}
+	}
+	seen := map[string]bool{}
+	var names []string
+	for _, key := range util.SortedKeys(refs) {
+		name := refs[key]
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(out) == 0 {
+		out = nil
+	}
+	return out, names, []string{"./" + SecretEnvFile(service)}
+}
+
+// SecretEnvFile returns the path, relative to the compose file, of the env
+// file that holds the secret-backed env vars of a service or dep.
+func SecretEnvFile(service string) string {
+	return SecretsDir + "/env/" + service + ".env"
+}
+
+// EnvFileContent renders secret-backed env vars as a compose env file.
+func EnvFileContent(values map[string]string) []byte {
+	buf := &bytes.Buffer{}
+	for _, key := range util.SortedKeys(values) {
+		fmt.Fprintf(buf, "%s=%s\n", key, envFileValue(values[key]))
+	}
+	return buf.Bytes()
+}
+
+// envFileValue quotes a value for a compose env file. Single quotes keep it
+// literal, newlines included; a value that contains one is double-quoted with
+// escapes instead.
+func envFileValue(value string) string {
+	if !strings.Contains(value, "'") {
+		return "'" + value + "'"
+	}
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "$", `\$`)
+	return `"` + escape.Replace(value) + `"`
+}
+
+// escapeDollar doubles `$` so that compose, which interpolates the file it
+// reads, passes values devx has already resolved through literally.
+func escapeDollar(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
+
+func escapeMap(values map[string]string) map[string]string {
+	if values == nil {
+		return nil
+	}
+	out := make(map[string]string, len(values))
+	for key, value := range values {
+		out[key] = escapeDollar(value)
+	}
+	return out
+}
+
+func escapeList(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	out := make([]string, len(values))
+	for i, value := range values {
+		out[i] = escapeDollar(value)
+	}
+	return out
+}
+
 func labels(manifest *config.Manifest, profileName string, name string) map[string]string {
 	return map[string]string{
 		"devx.project": manifest.Project.Name,
@@ -174,6 +602,75 @@ func rewriteImage(image string, opts RewriteOptions) string {
 	return rewritten
 }
 
+// rewriteBaseImages applies the registry prefix and lockfile to the FROM lines
+// of a build's Dockerfile. When that changes any of them the rewritten
+// Dockerfile is inlined, leaving the one on disk untouched.
+func rewriteBaseImages(build *Build, rewrite RewriteOptions) error {
+	if rewrite.RegistryPrefix == "" && rewrite.Lockfile == nil {
+		return nil
+	}
+	data, err := os.ReadFile(dockerfilePath(rewrite.BaseDir, build.Context, build.Dockerfile))
+	if err != nil {
+		// The build itself reports a missing Dockerfile.
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	rewritten, changed := lock.RewriteBaseImages(data, func(image string) string {
+		return rewriteImage(image, rewrite)
+	})
+	if changed {
+		build.Dockerfile = ""
+		build.DockerfileInline = string(rewritten)
+	}
+	return nil
+}
+
+// BaseImages returns the base images named in the Dockerfiles of the
+// profile's build-based services, with the registry prefix applied but not
+// the lockfile, i.e. as they are keyed in devx.lock. As when rendering, a
+// missing Dockerfile is left for the build to report: its service is returned
+// in missing instead of failing.
+func BaseImages(profile *config.Profile, rewrite RewriteOptions) (images []string, missing []string, err error) {
+	seen := map[string]bool{}
+	for _, name := range util.SortedKeys(profile.Services) {
+		svc := profile.Services[name]
+		if svc.Build == nil {
+			continue
+		}
+		data, err := os.ReadFile(dockerfilePath(rewrite.BaseDir, svc.Build.Context, svc.Build.Dockerfile))
+		if errors.Is(err, fs.ErrNotExist) {
+			missing = append(missing, name)
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("service '%s' build: %w", name, err)
+		}
+		for _, image := range lock.BaseImages(data) {
+			image = rewriteImage(image, RewriteOptions{RegistryPrefix: rewrite.RegistryPrefix})
+			if !seen[image] {
+				seen[image] = true
+				images = append(images, image)
+			}
+		}
+	}
+	return images, missing, nil
+}
+
+func dockerfilePath(baseDir string, context string, dockerfile string) string {
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	if filepath.IsAbs(dockerfile) {
+		return dockerfile
+	}
+	if !filepath.IsAbs(context) {
+		context = filepath.Join(baseDir, context)
+	}
+	return filepath.Join(context, dockerfile)
+}
+
 func prefixRegistry(image string, prefix string) string {
 	if strings.HasPrefix(image, prefix+"/") {
 		return image
@@ -202,7 +699,12 @@ func splitRegistry(image string) (string, string) {
 }
 
 func CollectImages(data []byte) ([]string, error) {
-	var file File
+	// Plugin services may use compose syntax File does not model.
+	var file struct {
+		Services map[string]struct {
+			Image string `yaml:"image"`
+		} `yaml:"services"`
+	}
 	if err := yaml.Unmarshal(data, &file); err != nil {
 		return nil, err
 	}
@@ -1,10 +1,16 @@
 package compose
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/dever-labs/devx/internal/config"
+	"github.com/dever-labs/devx/internal/lock"
+	"github.com/dever-labs/devx/internal/plugins"
 	"gopkg.in/yaml.v3"
 )
 
@@ -20,7 +26,7 @@ func TestRenderCompose(t *testing.T) {
 			"api": {
 				Image:     "nginx:alpine",
 				Ports:     []string{"8080:80"},
-				DependsOn: []string{"db"},
+				DependsOn: []config.Dependency{{Name: "db", Condition: config.ConditionHealthy}},
 			},
 		},
 		Deps: map[string]config.Dep{
@@ -36,7 +42,7 @@ func TestRenderCompose(t *testing.T) {
 		},
 	}
 
-	out, err := Render(manifest, "local", profile, RewriteOptions{RegistryPrefix: "registry.local"}, false)
+	out, err := Render(manifest, "local", profile, RewriteOptions{RegistryPrefix: "registry.local"}, false, nil)
 	if err != nil {
 		t.Fatalf("render failed: %v", err)
 	}
@@ -53,7 +59,8 @@ services:
     ports:
       - "8080:80"
     depends_on:
-      - db
+      db:
+        condition: service_healthy
     labels:
       devx.project: my-app
       devx.profile: local
@@ -68,6 +75,12 @@ services:
       - "5432:5432"
     volumes:
       - "db-data:/var/lib/postgresql/data"
+    healthcheck:
+      test: ["CMD-SHELL", "pg_isready -U \"$${POSTGRES_USER:-postgres}\""]
+      interval: 5s
+      timeout: 5s
+      retries: 30
+      start_period: 10s
     labels:
       devx.project: my-app
       devx.profile: local
@@ -89,3 +102,546 @@ volumes:
 		t.Fatalf("compose output mismatch\nGot: %#v\nWant: %#v", got, want)
 	}
 }
+
+func TestRenderComposePluginFragment(t *testing.T) {
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "local"},
+	}
+	profile := &config.Profile{
+		Services: map[string]config.Service{
+			"api": {Image: "nginx:alpine"},
+		},
+	}
+
+	fragment := plugins.Fragment{
+		Plugin: "queue",
+		Data:   []byte(`{"services":{"queue":{"image":"nats:2"}},"volumes":{"queue-data":{}}}`),
+	}
+
+	out, err := Render(manifest, "local", profile, RewriteOptions{}, false, []plugins.Fragment{fragment})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	var got File
+	if err := yaml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal output failed: %v", err)
+	}
+
+	queue, ok := got.Services["queue"]
+	if !ok {
+		t.Fatalf("expected plugin service in output, got %v", got.Services)
+	}
+	if queue.Image != "nats:2" {
+		t.Fatalf("expected image nats:2, got %q", queue.Image)
+	}
+	if queue.Labels["devx.service"] != "queue" {
+		t.Fatalf("expected devx labels on plugin service, got %v", queue.Labels)
+	}
+	if !reflect.DeepEqual(queue.Networks, []string{"devx_default"}) {
+		t.Fatalf("expected default network on plugin service, got %v", queue.Networks)
+	}
+	if _, ok := got.Volumes["queue-data"]; !ok {
+		t.Fatalf("expected plugin volume in output")
+	}
+}
+
+func TestRenderComposePluginCollision(t *testing.T) {
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "local"},
+	}
+	profile := &config.Profile{
+		Services: map[string]config.Service{
+			"api": {Image: "nginx:alpine"},
+		},
+	}
+
+	fragment := plugins.Fragment{
+		Plugin: "dup",
+		Data:   []byte("services:\n  api:\n    image: busybox\n"),
+	}
+
+	if _, err := Render(manifest, "local", profile, RewriteOptions{}, false, []plugins.Fragment{fragment}); err == nil {
+		t.Fatalf("expected service name collision error")
+	}
+}
+
+func TestRenderComposePluginFragmentKeepsComposeKeys(t *testing.T) {
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "local"},
+	}
+	profile := &config.Profile{
+		Services: map[string]config.Service{"api": {Image: "nginx:alpine"}},
+	}
+	fragment := plugins.Fragment{
+		Plugin: "queue",
+		Data: []byte(`version: "3.8"
+services:
+  queue:
+    image: nats:2
+    restart: unless-stopped
+    entrypoint: ["/nats-server"]
+    command: --jetstream --store_dir /data
+    environment:
+      - NATS_DEBUG=1
+    cap_add: [NET_ADMIN]
+    labels:
+      - team=platform
+  sidecar:
+    image: busybox
+    network_mode: "service:queue"
+networks:
+  queue-net:
+    driver: bridge
+volumes:
+  queue-data:
+    external: true
+`),
+	}
+
+	out, err := Render(manifest, "local", profile, RewriteOptions{}, false, []plugins.Fragment{fragment})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	var got map[string]any
+	if err := yaml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal output failed: %v", err)
+	}
+
+	services := got["services"].(map[string]any)
+	queue := services["queue"].(map[string]any)
+	if queue["restart"] != "unless-stopped" || queue["command"] != "--jetstream --store_dir /data" {
+		t.Errorf("expected restart and command kept, got %v", queue)
+	}
+	if !reflect.DeepEqual(queue["entrypoint"], []any{"/nats-server"}) || !reflect.DeepEqual(queue["cap_add"], []any{"NET_ADMIN"}) {
+		t.Errorf("expected entrypoint and cap_add kept, got %v", queue)
+	}
+	if !reflect.DeepEqual(queue["environment"], []any{"NATS_DEBUG=1"}) {
+		t.Errorf("expected list environment kept, got %v", queue["environment"])
+	}
+	labels := queue["labels"].([]any)
+	if labels[0] != "team=platform" || !strings.Contains(fmt.Sprint(labels), "devx.service=queue") {
+		t.Errorf("expected devx labels added to the list, got %v", labels)
+	}
+	if _, ok := services["sidecar"].(map[string]any)["networks"]; ok {
+		t.Error("expected no networks on a service with network_mode")
+	}
+	if !reflect.DeepEqual(got["networks"].(map[string]any)["queue-net"], map[string]any{"driver": "bridge"}) {
+		t.Errorf("expected the network driver kept, got %v", got["networks"])
+	}
+	if !reflect.DeepEqual(got["volumes"].(map[string]any)["queue-data"], map[string]any{"external": true}) {
+		t.Errorf("expected the external volume kept, got %v", got["volumes"])
+	}
+	if _, ok := got["version"]; ok {
+		t.Error("expected the fragment's version to be dropped")
+	}
+	if _, ok := services["api"]; !ok {
+		t.Error("expected the profile's services kept")
+	}
+}
+
+func TestRenderComposePluginFragmentErrors(t *testing.T) {
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "local"},
+	}
+	profile := &config.Profile{
+		Services: map[string]config.Service{
+			"api": {Image: "nginx:alpine", Volumes: []string{"api-data:/data"}},
+		},
+	}
+
+	cases := map[string]string{
+		"networks:\n  devx_default: {}\n": "plugin p network name collision: devx_default",
+		"volumes:\n  api-data: {}\n":      "plugin p volume name collision: api-data",
+		"services:\n  api: {image: x}\n":  "plugin p service name collision: api",
+		"include: [other.yaml]\n":         "plugin p compose fragment has unsupported key 'include'",
+		"services: [queue]\n":             "plugin p compose fragment: 'services' must be a mapping",
+		"services:\n  queue: nats\n":      "plugin p service 'queue' must be a mapping",
+		"- services\n":                    "plugin p returned an invalid compose fragment: expected a mapping",
+	}
+	for data, want := range cases {
+		fragment := plugins.Fragment{Plugin: "p", Data: []byte(data)}
+		_, err := Render(manifest, "local", profile, RewriteOptions{}, false, []plugins.Fragment{fragment})
+		if err == nil || err.Error() != want {
+			t.Errorf("fragment %q: expected %q, got %v", data, want, err)
+		}
+	}
+
+	// Two plugins must not declare the same volume either.
+	first := plugins.Fragment{Plugin: "a", Data: []byte("volumes:\n  shared: {}\n")}
+	second := plugins.Fragment{Plugin: "b", Data: []byte("volumes:\n  shared: {}\n")}
+	if _, err := Render(manifest, "local", profile, RewriteOptions{}, false, []plugins.Fragment{first, second}); err == nil {
+		t.Error("expected a volume collision between plugins")
+	}
+}
+
+func TestRenderComposeSecrets(t *testing.T) {
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "local"},
+		Secrets: map[string]config.Secret{
+			"db-password": {File: "secrets/db-password.txt"},
+			"token":       {Command: "op read op://dev/token"},
+		},
+	}
+	profile := &config.Profile{
+		Services: map[string]config.Service{
+			"api": {
+				Image:     "nginx:alpine",
+				SecretEnv: map[string]string{"DB_PASSWORD": "db-password", "TOKEN": "token"},
+			},
+		},
+		Deps: map[string]config.Dep{
+			"db": {Kind: "postgres", SecretEnv: map[string]string{"POSTGRES_PASSWORD": "db-password"}},
+		},
+	}
+
+	out, err := Render(manifest, "local", profile, RewriteOptions{}, false, nil)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	var got File
+	if err := yaml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal output failed: %v", err)
+	}
+
+	wantSecrets := map[string]Secret{
+		"db-password": {File: "../secrets/db-password.txt"},
+		"token":       {File: "./secrets/token"},
+	}
+	if !reflect.DeepEqual(got.Secrets, wantSecrets) {
+		t.Fatalf("unexpected top-level secrets %#v", got.Secrets)
+	}
+
+	api := got.Services["api"]
+	if !reflect.DeepEqual(api.Secrets, []string{"db-password", "token"}) {
+		t.Fatalf("unexpected api secrets %v", api.Secrets)
+	}
+	if api.Environment != nil || !reflect.DeepEqual(api.EnvFile, []string{"./secrets/env/api.env"}) {
+		t.Fatalf("expected the secret env vars in the api env file, got %v %v", api.Environment, api.EnvFile)
+	}
+
+	db := got.Services["db"]
+	if _, ok := db.Environment["POSTGRES_PASSWORD"]; ok {
+		t.Fatalf("default POSTGRES_PASSWORD must be replaced by the secret, got %v", db.Environment)
+	}
+	if _, ok := db.Environment["POSTGRES_PASSWORD_FILE"]; ok {
+		t.Fatalf("expected no POSTGRES_PASSWORD_FILE next to the env file, got %v", db.Environment)
+	}
+	if !reflect.DeepEqual(db.EnvFile, []string{"./secrets/env/db.env"}) {
+		t.Fatalf("unexpected db env file %v", db.EnvFile)
+	}
+}
+
+func TestEnvFileContent(t *testing.T) {
+	got := string(EnvFileContent(map[string]string{
+		"TOKEN":  "a$b c",
+		"QUOTED": `it's "$HOME"\`,
+		"PEM":    "-----BEGIN-----\nabc\n-----END-----",
+	}))
+	want := "PEM='-----BEGIN-----\nabc\n-----END-----'\n" +
+		`QUOTED="it's \"\$HOME\"\\"` + "\n" +
+		"TOKEN='a$b c'\n"
+	if got != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, got)
+	}
+}
+
+func TestRenderComposeHealthchecks(t *testing.T) {
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "local"},
+	}
+	profile := &config.Profile{
+		Services: map[string]config.Service{
+			"api": {
+				Image:  "nginx:alpine",
+				Ports:  []string{"8080:80"},
+				Health: &config.Health{HttpGet: "http://localhost:8080/health", StartPeriod: "10s", Timeout: "3s"},
+			},
+			"worker": {Image: "worker", Health: &config.Health{TCP: 9000}},
+			"cli":    {Image: "cli", Health: &config.Health{Exec: "test -f /tmp/ready", Retries: 3}},
+			"jobs":   {Image: "jobs", Health: &config.Health{LogMatch: "ready"}},
+		},
+	}
+
+	out, err := Render(manifest, "local", profile, RewriteOptions{}, false, nil)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	var got File
+	if err := yaml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal output failed: %v", err)
+	}
+
+	api := got.Services["api"].Healthcheck
+	if api == nil || !strings.Contains(api.Test[1], "http://localhost:80/health") {
+		t.Fatalf("expected http check against the container port, got %+v", api)
+	}
+	if api.StartPeriod != "10s" || api.Timeout != "3s" {
+		t.Errorf("expected startPeriod and timeout, got %+v", api)
+	}
+	if worker := got.Services["worker"].Healthcheck; worker == nil || !strings.Contains(worker.Test[1], "127.0.0.1 9000") {
+		t.Errorf("expected tcp check, got %+v", worker)
+	}
+	if cli := got.Services["cli"].Healthcheck; cli == nil || cli.Test[1] != "test -f /tmp/ready" || cli.Retries != 3 {
+		t.Errorf("expected exec check, got %+v", cli)
+	}
+	if jobs := got.Services["jobs"].Healthcheck; jobs != nil {
+		t.Errorf("logMatch must not render a healthcheck, got %+v", jobs)
+	}
+}
+
+func TestRenderComposeResources(t *testing.T) {
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "local"},
+	}
+	profile := &config.Profile{
+		Services: map[string]config.Service{
+			"api": {
+				Image:     "api",
+				Resources: &config.Resources{CPU: "250m", Memory: "256Mi", Limits: &config.ResourceLimits{CPU: "1", Memory: "512Mi"}},
+			},
+			"worker": {Image: "worker"},
+		},
+		Deps: map[string]config.Dep{
+			"db": {Kind: "postgres", Resources: &config.Resources{Memory: "1Gi"}},
+		},
+	}
+
+	out, err := Render(manifest, "local", profile, RewriteOptions{}, false, nil)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	var got File
+	if err := yaml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal output failed: %v", err)
+	}
+
+	api := got.Services["api"].Deploy
+	want := &Deploy{Resources: DeployResources{
+		Limits:       &ResourceSpec{CPUs: "1", Memory: "536870912"},
+		Reservations: &ResourceSpec{CPUs: "0.25", Memory: "268435456"},
+	}}
+	if !reflect.DeepEqual(api, want) {
+		t.Errorf("expected %+v, got %+v", want, api)
+	}
+	if db := got.Services["db"].Deploy; db == nil || db.Resources.Limits != nil || db.Resources.Reservations.Memory != "1073741824" {
+		t.Errorf("expected dep memory reservation, got %+v", db)
+	}
+	if worker := got.Services["worker"].Deploy; worker != nil {
+		t.Errorf("expected no deploy block without resources, got %+v", worker)
+	}
+}
+
+func TestRenderComposePinsBaseImages(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "api"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	dockerfile := "FROM golang:1.22 AS build\nRUN go build\nFROM alpine:3.19\nCOPY --from=build /app /app\n"
+	if err := os.WriteFile(filepath.Join(dir, "api", "Dockerfile"), []byte(dockerfile), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "local"},
+	}
+	profile := &config.Profile{
+		Services: map[string]config.Service{
+			"api": {Build: &config.Build{Context: "api"}},
+		},
+	}
+
+	bases, missing, err := BaseImages(profile, RewriteOptions{BaseDir: dir})
+	if err != nil {
+		t.Fatalf("base images failed: %v", err)
+	}
+	if want := []string{"golang:1.22", "alpine:3.19"}; !reflect.DeepEqual(bases, want) || len(missing) != 0 {
+		t.Errorf("expected %v, got %v (missing %v)", want, bases, missing)
+	}
+
+	// A build whose context is not checked out is skipped and reported, as
+	// rendering leaves it to the build.
+	profile.Services["web"] = config.Service{Build: &config.Build{Context: "web"}}
+	bases, missing, err = BaseImages(profile, RewriteOptions{BaseDir: dir})
+	if err != nil || len(bases) != 2 || !reflect.DeepEqual(missing, []string{"web"}) {
+		t.Errorf("expected web to be reported missing, got %v %v (%v)", bases, missing, err)
+	}
+	if _, err := Render(manifest, "local", profile, RewriteOptions{Lockfile: &lock.Lockfile{}, BaseDir: dir}, false, nil); err != nil {
+		t.Errorf("expected render to leave the missing Dockerfile to the build, got %v", err)
+	}
+	delete(profile.Services, "web")
+
+	digest := "sha256:" + strings.Repeat("a", 64)
+	lf := &lock.Lockfile{Version: lock.Version, Images: map[string]lock.Entry{"alpine:3.19": {Digest: digest}}}
+	out, err := Render(manifest, "local", profile, RewriteOptions{Lockfile: lf, BaseDir: dir}, false, nil)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	var got File
+	if err := yaml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal output failed: %v", err)
+	}
+	build := got.Services["api"].Build
+	want := "FROM golang:1.22 AS build\nRUN go build\nFROM alpine@" + digest + "\nCOPY --from=build /app /app\n"
+	if build == nil || build.Context != "api" || build.Dockerfile != "" || build.DockerfileInline != want {
+		t.Errorf("expected pinned inline Dockerfile, got %+v", build)
+	}
+
+	out, err = Render(manifest, "local", profile, RewriteOptions{BaseDir: dir}, false, nil)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if strings.Contains(out, "dockerfile_inline") {
+		t.Errorf("expected the Dockerfile to be used as is without a lockfile:\n%s", out)
+	}
+}
+
+func TestRenderComposeTraces(t *testing.T) {
+	manifest := &config.Manifest{
+		Version:   1,
+		Project:   config.Project{Name: "my-app", DefaultProfile: "local"},
+		Telemetry: config.Telemetry{Traces: true},
+	}
+	profile := &config.Profile{
+		Services: map[string]config.Service{
+			"api":    {Image: "api", Env: map[string]string{"OTEL_SERVICE_NAME": "orders"}},
+			"worker": {Image: "worker"},
+		},
+	}
+
+	out, err := Render(manifest, "local", profile, RewriteOptions{}, true, nil)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	var got File
+	if err := yaml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal output failed: %v", err)
+	}
+
+	if _, ok := got.Services[telemetryName+"-tempo"]; !ok {
+		t.Fatal("expected a tempo service")
+	}
+	if _, ok := got.Services[telemetryName+"-grafana"].DependsOn[telemetryName+"-tempo"]; !ok {
+		t.Error("expected grafana to depend on tempo")
+	}
+
+	worker := got.Services["worker"].Environment
+	want := map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": "http://devx-telemetry-alloy:4318",
+		"OTEL_EXPORTER_OTLP_PROTOCOL": "http/protobuf",
+		"OTEL_SERVICE_NAME":           "worker",
+		"OTEL_RESOURCE_ATTRIBUTES":    "service.namespace=my-app,deployment.environment=local",
+	}
+	if !reflect.DeepEqual(worker, want) {
+		t.Errorf("expected %v, got %v", want, worker)
+	}
+	if name := got.Services["api"].Environment["OTEL_SERVICE_NAME"]; name != "orders" {
+		t.Errorf("expected the service's own OTEL_SERVICE_NAME to be kept, got %q", name)
+	}
+	if len(profile.Services["api"].Env) != 1 {
+		t.Errorf("expected the manifest env to be left alone, got %v", profile.Services["api"].Env)
+	}
+
+	// Without traces the services get no OpenTelemetry variables.
+	manifest.Telemetry.Traces = false
+	out, err = Render(manifest, "local", profile, RewriteOptions{}, true, nil)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if strings.Contains(out, "tempo") || strings.Contains(out, "OTEL_EXPORTER_OTLP_ENDPOINT") {
+		t.Errorf("expected no traces pipeline, got\n%s", out)
+	}
+}
+
+func TestTelemetryAssetsTraces(t *testing.T) {
+	paths := func(assets []Asset) map[string]string {
+		out := map[string]string{}
+		for _, a := range assets {
+			out[a.Path] = string(a.Content)
+		}
+		return out
+	}
+
+	plain := paths(TelemetryAssets(true, false))
+	if _, ok := plain["telemetry/tempo.yaml"]; ok {
+		t.Error("expected no tempo config without traces")
+	}
+	if strings.Contains(plain["telemetry/alloy-config.alloy"], "otelcol") {
+		t.Error("expected no OTLP receiver without traces")
+	}
+
+	traces := paths(TelemetryAssets(true, true))
+	for _, path := range []string{"telemetry/tempo.yaml", "telemetry/grafana/dashboards/traces.json"} {
+		if _, ok := traces[path]; !ok {
+			t.Errorf("expected asset %s", path)
+		}
+	}
+	if !strings.Contains(traces["telemetry/alloy-config.alloy"], `otelcol.receiver.otlp "default"`) {
+		t.Error("expected an OTLP receiver in the alloy config")
+	}
+	if !strings.Contains(traces["telemetry/grafana/provisioning/datasources/devx.yaml"], "type: tempo") {
+		t.Error("expected a tempo datasource")
+	}
+	if len(TelemetryAssets(false, true)) != 0 {
+		t.Error("expected no assets with telemetry disabled")
+	}
+}
+
+func TestRenderComposeEscapesDollar(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".env": "DB_PASSWORD=pa$word\n",
+		"devx.yaml": `version: 1
+project:
+  name: my-app
+  defaultProfile: local
+profiles:
+  local:
+    services:
+      api:
+        image: api
+        command: ["sh", "-c", "echo $$HOME"]
+        env:
+          DB_PASSWORD: ${DB_PASSWORD}
+          GREETING: hello $${USER}
+`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest, err := config.Load(filepath.Join(dir, "devx.yaml"))
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	profile := manifest.Profiles["local"]
+
+	out, err := Render(manifest, "local", &profile, RewriteOptions{}, false, nil)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	var got File
+	if err := yaml.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("unmarshal output failed: %v", err)
+	}
+	// Compose interpolates the file again, so every `$` is doubled.
+	api := got.Services["api"]
+	if api.Environment["DB_PASSWORD"] != "pa$$word" || api.Environment["GREETING"] != "hello $${USER}" {
+		t.Errorf("expected escaped env, got %v", api.Environment)
+	}
+	if api.Command[2] != "echo $$HOME" {
+		t.Errorf("expected escaped command, got %v", api.Command)
+	}
+}
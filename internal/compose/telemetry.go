@@ -18,15 +18,31 @@ const (
 	alloyImage      = "grafana/alloy:v1.1.1"
 	cAdvisorImage   = "gcr.io/cadvisor/cadvisor:v0.49.1"
 	dockerMetaImage = "python:3.12-alpine"
+	tempoImage      = "grafana/tempo:2.4.1"
 	telemetryName   = "devx-telemetry"
 )
 
-func TelemetryAssets(enable bool) []Asset {
+// The compose services of the telemetry Prometheus and Loki, and the
+// container ports of their HTTP APIs.
+const (
+	PrometheusService = telemetryName + "-prometheus"
+	PrometheusPort    = 9090
+	LokiService       = telemetryName + "-loki"
+	LokiPort          = 3100
+)
+
+// otlpEndpoint is the OTLP/HTTP receiver of the telemetry Alloy when traces
+// are enabled.
+const otlpEndpoint = "http://" + telemetryName + "-alloy:4318"
+
+// TelemetryAssets returns the configuration files of the telemetry stack;
+// traces adds those of the traces pipeline.
+func TelemetryAssets(enable bool, traces bool) []Asset {
 	if !enable {
 		return nil
 	}
 
-	return []Asset{
+	assets := []Asset{
 		{
 			Path:    "telemetry/loki-config.yaml",
 			Content: []byte(lokiConfig()),
@@ -37,11 +53,11 @@ func TelemetryAssets(enable bool) []Asset {
 		},
 		{
 			Path:    "telemetry/alloy-config.alloy",
-			Content: []byte(alloyConfig(telemetryName)),
+			Content: []byte(alloyConfig(telemetryName, traces)),
 		},
 		{
 			Path:    "telemetry/grafana/provisioning/datasources/devx.yaml",
-			Content: []byte(grafanaDatasourceConfig(telemetryName)),
+			Content: []byte(grafanaDatasourceConfig(telemetryName, traces)),
 		},
 		{
 			Path:    "telemetry/grafana/provisioning/dashboards/devx.yaml",
@@ -68,6 +84,13 @@ func TelemetryAssets(enable bool) []Asset {
 			Content: []byte(dockerMetaExporterScript()),
 		},
 	}
+	if traces {
+		assets = append(assets,
+			Asset{Path: "telemetry/tempo.yaml", Content: []byte(tempoConfig())},
+			Asset{Path: "telemetry/grafana/dashboards/traces.json", Content: []byte(grafanaTracesDashboard())},
+		)
+	}
+	return assets
 }
 
 func telemetryCompose(manifest *config.Manifest, profileName string, rewrite RewriteOptions) (map[string]Service, map[string]Volume) {
@@ -75,19 +98,22 @@ func telemetryCompose(manifest *config.Manifest, profileName string, rewrite Rew
 	volumes := map[string]Volume{}
 
 	grafanaName := telemetryName + "-grafana"
-	lokiName := telemetryName + "-loki"
-	promName := telemetryName + "-prometheus"
+	lokiName := LokiService
+	promName := PrometheusService
 	alloyName := telemetryName + "-alloy"
 	cAdvisorName := telemetryName + "-cadvisor"
 
 	grafanaPorts := []string{"3000"}
 
 	services[grafanaName] = Service{
-		Image:     rewriteImage(grafanaImage, rewrite),
-		Ports:     grafanaPorts,
-		DependsOn: []string{lokiName, promName},
-		Labels:    labels(manifest, profileName, grafanaName),
-		Networks:  []string{"devx_default"},
+		Image: rewriteImage(grafanaImage, rewrite),
+		Ports: grafanaPorts,
+		DependsOn: DependsOn{
+			lokiName: {Condition: "service_started"},
+			promName: {Condition: "service_started"},
+		},
+		Labels:   labels(manifest, profileName, grafanaName),
+		Networks: []string{"devx_default"},
 		Environment: map[string]string{
 			"GF_AUTH_ANONYMOUS_ENABLED":      "true",
 			"GF_AUTH_ANONYMOUS_ORG_ROLE":     "Admin",
@@ -102,8 +128,10 @@ func telemetryCompose(manifest *config.Manifest, profileName string, rewrite Rew
 		},
 	}
 
+	// Loki is published on a random loopback port for devx logs --query.
 	services[lokiName] = Service{
 		Image:    rewriteImage(lokiImage, rewrite),
+		Ports:    []string{"127.0.0.1::3100"},
 		Labels:   labels(manifest, profileName, lokiName),
 		Networks: []string{"devx_default"},
 		Command:  []string{"-config.file=/etc/loki/local-config.yaml"},
@@ -113,8 +141,11 @@ func telemetryCompose(manifest *config.Manifest, profileName string, rewrite Rew
 		},
 	}
 
+	// Prometheus is published on a random loopback port for devx status
+	// --watch, which finds it through the runtime.
 	services[promName] = Service{
 		Image:    rewriteImage(prometheusImage, rewrite),
+		Ports:    []string{"127.0.0.1::9090"},
 		Labels:   labels(manifest, profileName, promName),
 		Networks: []string{"devx_default"},
 		Volumes: []string{
@@ -165,6 +196,20 @@ func telemetryCompose(manifest *config.Manifest, profileName string, rewrite Rew
 		},
 	}
 
+	if manifest.Telemetry.Traces {
+		// Tempo keeps traces in the container: they last until it is
+		// recreated, and the image's user cannot write to a fresh volume.
+		tempoName := telemetryName + "-tempo"
+		services[tempoName] = Service{
+			Image:    rewriteImage(tempoImage, rewrite),
+			Labels:   labels(manifest, profileName, tempoName),
+			Networks: []string{"devx_default"},
+			Command:  []string{"-config.file=/etc/tempo/tempo.yaml"},
+			Volumes:  []string{"./telemetry/tempo.yaml:/etc/tempo/tempo.yaml:ro"},
+		}
+		services[grafanaName].DependsOn[tempoName] = Dependency{Condition: "service_started"}
+	}
+
 	volumes[telemetryName+"-grafana-data"] = Volume{}
 	volumes[telemetryName+"-loki-data"] = Volume{}
 	volumes[telemetryName+"-prometheus-data"] = Volume{}
@@ -172,6 +217,28 @@ func telemetryCompose(manifest *config.Manifest, profileName string, rewrite Rew
 	return services, volumes
 }
 
+// otelEnv returns env with the OpenTelemetry SDK variables that send the
+// traces of a service to the telemetry Alloy. Variables the service sets
+// itself are kept.
+func otelEnv(env map[string]string, project string, profileName string, service string) map[string]string {
+	out := make(map[string]string, len(env)+4)
+	for key, value := range env {
+		out[key] = value
+	}
+	defaults := map[string]string{
+		"OTEL_EXPORTER_OTLP_ENDPOINT": otlpEndpoint,
+		"OTEL_EXPORTER_OTLP_PROTOCOL": "http/protobuf",
+		"OTEL_SERVICE_NAME":           service,
+		"OTEL_RESOURCE_ATTRIBUTES":    fmt.Sprintf("service.namespace=%s,deployment.environment=%s", project, profileName),
+	}
+	for key, value := range defaults {
+		if _, ok := out[key]; !ok {
+			out[key] = value
+		}
+	}
+	return out
+}
+
 func lokiConfig() string {
 	return `auth_enabled: false
 
@@ -225,8 +292,8 @@ scrape_configs:
 `, depName+"-prometheus", depName, depName, depName)
 }
 
-func alloyConfig(depName string) string {
-	return fmt.Sprintf(`discovery.docker "containers" {
+func alloyConfig(depName string, traces bool) string {
+	config := fmt.Sprintf(`discovery.docker "containers" {
   host             = "unix:///var/run/docker.sock"
   refresh_interval = "5s"
 }
@@ -271,21 +338,114 @@ loki.write "local" {
   }
 }
 `, depName)
+	if !traces {
+		return config
+	}
+
+	// Services send OTLP to Alloy, which batches the spans for Tempo.
+	return config + fmt.Sprintf(`
+otelcol.receiver.otlp "default" {
+  grpc {
+    endpoint = "0.0.0.0:4317"
+  }
+  http {
+    endpoint = "0.0.0.0:4318"
+  }
+  output {
+    traces = [otelcol.processor.batch.default.input]
+  }
 }
 
-func grafanaDatasourceConfig(depName string) string {
-	return `apiVersion: 1
+otelcol.processor.batch "default" {
+  output {
+    traces = [otelcol.exporter.otlp.tempo.input]
+  }
+}
+
+otelcol.exporter.otlp "tempo" {
+  client {
+    endpoint = "%s-tempo:4317"
+    tls {
+      insecure = true
+    }
+  }
+}
+`, depName)
+}
+
+func tempoConfig() string {
+	return `server:
+  http_listen_port: 3200
+
+distributor:
+  receivers:
+    otlp:
+      protocols:
+        grpc:
+          endpoint: 0.0.0.0:4317
+
+storage:
+  trace:
+    backend: local
+    local:
+      path: /tmp/tempo/blocks
+    wal:
+      path: /tmp/tempo/wal
+
+compactor:
+  compaction:
+    block_retention: 24h
+`
+}
+
+func grafanaDatasourceConfig(depName string, traces bool) string {
+	config := `apiVersion: 1
 
 datasources:
   - name: Prometheus
     type: prometheus
+    uid: prometheus
     access: proxy
     url: http://` + depName + `-prometheus:9090
     isDefault: true
   - name: Loki
     type: loki
+    uid: loki
     access: proxy
     url: http://` + depName + `-loki:3100
+`
+	if !traces {
+		return config
+	}
+
+	// Trace IDs in log lines link to Tempo, and spans link back to the logs
+	// of their service around the span. $$ escapes provisioning's env
+	// variable expansion.
+	return config + `    jsonData:
+      derivedFields:
+        - name: TraceID
+          datasourceUid: tempo
+          matcherRegex: '(?:trace_id|traceId|traceID|trace\.id)\W+(\w+)'
+          url: '$${__value.raw}'
+          urlDisplayLabel: View trace
+  - name: Tempo
+    type: tempo
+    uid: tempo
+    access: proxy
+    url: http://` + depName + `-tempo:3200
+    jsonData:
+      tracesToLogsV2:
+        datasourceUid: loki
+        spanStartTimeShift: -5m
+        spanEndTimeShift: 5m
+        filterByTraceID: true
+        tags:
+          - key: service.name
+            value: compose_service
+      lokiSearch:
+        datasourceUid: loki
+      nodeGraph:
+        enabled: true
 `
 }
 
@@ -430,6 +590,160 @@ func grafanaLogsDashboard() string {
 `
 }
 
+// grafanaTracesDashboard searches Tempo for the traces of the services and
+// shows the log lines that carry trace IDs, which link to their trace.
+func grafanaTracesDashboard() string {
+	return `{
+  "__inputs": [],
+  "__requires": [],
+  "annotations": { "list": [] },
+  "editable": true,
+  "graphTooltip": 1,
+  "id": null,
+  "links": [],
+  "panels": [
+    {
+      "collapsed": false,
+      "gridPos": { "h": 1, "w": 24, "x": 0, "y": 0 },
+      "id": 10,
+      "title": "Traces",
+      "type": "row"
+    },
+    {
+      "datasource": { "type": "tempo", "uid": "${DS_TEMPO}" },
+      "gridPos": { "h": 10, "w": 24, "x": 0, "y": 1 },
+      "id": 1,
+      "options": { "showHeader": true },
+      "targets": [
+        {
+          "datasource": { "type": "tempo", "uid": "${DS_TEMPO}" },
+          "limit": 50,
+          "query": "{resource.service.name=~\"$service\"}",
+          "queryType": "traceql",
+          "refId": "A",
+          "tableType": "traces"
+        }
+      ],
+      "title": "Recent Traces",
+      "type": "table"
+    },
+    {
+      "datasource": { "type": "tempo", "uid": "${DS_TEMPO}" },
+      "gridPos": { "h": 10, "w": 24, "x": 0, "y": 11 },
+      "id": 2,
+      "options": { "showHeader": true },
+      "targets": [
+        {
+          "datasource": { "type": "tempo", "uid": "${DS_TEMPO}" },
+          "limit": 50,
+          "query": "{resource.service.name=~\"$service\" && duration > $slow}",
+          "queryType": "traceql",
+          "refId": "A",
+          "tableType": "traces"
+        }
+      ],
+      "title": "Slow Traces",
+      "type": "table"
+    },
+    {
+      "collapsed": false,
+      "gridPos": { "h": 1, "w": 24, "x": 0, "y": 21 },
+      "id": 11,
+      "title": "Correlated Logs",
+      "type": "row"
+    },
+    {
+      "datasource": { "type": "loki", "uid": "${DS_LOKI}" },
+      "description": "Log lines that carry a trace ID. Open a line's details to jump to its trace.",
+      "gridPos": { "h": 14, "w": 24, "x": 0, "y": 22 },
+      "id": 3,
+      "options": {
+        "dedupStrategy": "none",
+        "enableLogDetails": true,
+        "prettifyLogMessage": false,
+        "showCommonLabels": false,
+        "showLabels": true,
+        "showTime": true,
+        "sortOrder": "Descending",
+        "wrapLogMessage": false
+      },
+      "targets": [
+        {
+          "datasource": { "type": "loki", "uid": "${DS_LOKI}" },
+          "expr": "{compose_service=~\"$service\"} |~ \"(?i)trace[._]?id\"",
+          "queryType": "range",
+          "refId": "A"
+        }
+      ],
+      "title": "Logs with Trace IDs",
+      "type": "logs"
+    }
+  ],
+  "refresh": "10s",
+  "schemaVersion": 38,
+  "tags": ["devx", "traces"],
+  "templating": {
+    "list": [
+      {
+        "current": {},
+        "hide": 2,
+        "name": "DS_TEMPO",
+        "options": [],
+        "query": "tempo",
+        "refresh": 1,
+        "type": "datasource"
+      },
+      {
+        "current": {},
+        "hide": 2,
+        "name": "DS_LOKI",
+        "options": [],
+        "query": "loki",
+        "refresh": 1,
+        "type": "datasource"
+      },
+      {
+        "allValue": ".*",
+        "current": { "selected": true, "text": ["All"], "value": ["$__all"] },
+        "datasource": { "type": "loki", "uid": "${DS_LOKI}" },
+        "definition": "label_values(compose_service)",
+        "hide": 0,
+        "includeAll": true,
+        "multi": true,
+        "name": "service",
+        "options": [],
+        "query": "label_values(compose_service)",
+        "refresh": 2,
+        "sort": 1,
+        "type": "query",
+        "label": "Service"
+      },
+      {
+        "current": { "selected": true, "text": "500ms", "value": "500ms" },
+        "hide": 0,
+        "name": "slow",
+        "options": [
+          { "selected": false, "text": "100ms", "value": "100ms" },
+          { "selected": true, "text": "500ms", "value": "500ms" },
+          { "selected": false, "text": "1s", "value": "1s" },
+          { "selected": false, "text": "5s", "value": "5s" }
+        ],
+        "query": "100ms,500ms,1s,5s",
+        "type": "custom",
+        "label": "Slow above"
+      }
+    ]
+  },
+  "time": { "from": "now-1h", "to": "now" },
+  "timepicker": {},
+  "timezone": "browser",
+  "title": "Traces",
+  "uid": "devx-traces",
+  "version": 1
+}
+`
+}
+
 func grafanaContainerResourcesDashboard() string {
 	return `{
   "__inputs": [],
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/dever-labs/devx/internal/deps"
+	"gopkg.in/yaml.v3"
+)
+
+// Conditions a dependency must reach before the dependent service starts.
+const (
+	ConditionStarted   = "started"
+	ConditionHealthy   = "healthy"
+	ConditionCompleted = "completed"
+)
+
+// Dependency is a `dependsOn` entry. It is written either as a plain name or
+// as {name, condition}; an empty Condition means ConditionStarted.
+type Dependency struct {
+	Name      string `yaml:"name"`
+	Condition string `yaml:"condition,omitempty"`
+}
+
+func (d *Dependency) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		d.Name = node.Value
+		d.Condition = ""
+		return nil
+	}
+	type plain Dependency
+	return node.Decode((*plain)(d))
+}
+
+func (d Dependency) MarshalYAML() (any, error) {
+	if d.Condition == "" {
+		return d.Name, nil
+	}
+	type plain Dependency
+	return plain(d), nil
+}
+
+// DependencyNames returns the names of a service's dependencies.
+func (s Service) DependencyNames() []string {
+	names := make([]string, 0, len(s.DependsOn))
+	for _, dep := range s.DependsOn {
+		names = append(names, dep.Name)
+	}
+	return names
+}
+
+func dependsOnIssues(prof Profile, service string, svc Service) []string {
+	var issues []string
+	for _, dep := range svc.DependsOn {
+		if !existsServiceOrDep(prof, dep.Name) {
+			issues = append(issues, fmt.Sprintf("service '%s' dependsOn '%s' which does not exist", service, dep.Name))
+			continue
+		}
+		switch dep.Condition {
+		case "", ConditionStarted, ConditionCompleted:
+		case ConditionHealthy:
+			if !hasHealthcheck(prof, dep.Name) {
+				issues = append(issues, fmt.Sprintf("service '%s' waits for '%s' to be healthy but it has no health check", service, dep.Name))
+			}
+		default:
+			issues = append(issues, fmt.Sprintf("service '%s' dependsOn '%s' condition must be started, healthy or completed", service, dep.Name))
+		}
+	}
+	return issues
+}
+
+// hasHealthcheck reports whether the runtime can report a health state for the
+// named service or dep. logMatch checks run on the host, so they do not count.
+func hasHealthcheck(prof Profile, name string) bool {
+	if svc, ok := prof.Services[name]; ok {
+		t := svc.Health.Type()
+		return t != "" && t != HealthLogMatch
+	}
+	if dep, ok := prof.Deps[name]; ok {
+		kind, ok := deps.Lookup(dep.Kind)
+		return ok && kind.Readiness != ""
+	}
+	return false
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expose publishes a service outside a k8s cluster through an Ingress, or
+// through a Gateway API HTTPRoute when Gateway is set.
+type Expose struct {
+	Host string `yaml:"host"`
+	// Path is the URL path prefix routed to the service (default "/").
+	Path string `yaml:"path,omitempty"`
+	// Port is the container port to route to; it defaults to the service's
+	// first port.
+	Port         int    `yaml:"port,omitempty"`
+	TLSSecret    string `yaml:"tlsSecret,omitempty"`
+	IngressClass string `yaml:"ingressClass,omitempty"`
+	// Gateway is the parent Gateway of the HTTPRoute, as "name" or
+	// "namespace/name".
+	Gateway string `yaml:"gateway,omitempty"`
+}
+
+// URL returns the external URL of the exposed service.
+func (e *Expose) URL() string {
+	scheme := "http"
+	if e.TLSSecret != "" {
+		scheme = "https"
+	}
+	path := e.Path
+	if path == "/" {
+		path = ""
+	}
+	return scheme + "://" + e.Host + path
+}
+
+func exposeIssues(service string, svc Service) []string {
+	e := svc.Expose
+	if e == nil {
+		return nil
+	}
+
+	var issues []string
+	if e.Host == "" || strings.ContainsAny(e.Host, ":/ ") {
+		issues = append(issues, fmt.Sprintf("service '%s' expose.host must be a host name", service))
+	}
+	if e.Path != "" && !strings.HasPrefix(e.Path, "/") {
+		issues = append(issues, fmt.Sprintf("service '%s' expose.path must start with /", service))
+	}
+	if e.Port < 0 || e.Port > 65535 {
+		issues = append(issues, fmt.Sprintf("service '%s' expose.port must be a port number", service))
+	}
+	if e.Port == 0 && len(svc.Ports) == 0 {
+		issues = append(issues, fmt.Sprintf("service '%s' expose requires ports or expose.port", service))
+	}
+	if e.Gateway != "" {
+		if parts := strings.Split(e.Gateway, "/"); len(parts) > 2 || parts[len(parts)-1] == "" {
+			issues = append(issues, fmt.Sprintf("service '%s' expose.gateway must be name or namespace/name", service))
+		}
+		if e.TLSSecret != "" || e.IngressClass != "" {
+			issues = append(issues, fmt.Sprintf("service '%s' expose.tlsSecret and expose.ingressClass do not apply to a gateway; configure TLS on the Gateway listener", service))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,48 @@
+package config
+
+import "testing"
+
+func TestExposeURL(t *testing.T) {
+	cases := map[string]Expose{
+		"http://api.local":            {Host: "api.local"},
+		"https://dev.example.com/app": {Host: "dev.example.com", Path: "/app", TLSSecret: "tls"},
+	}
+	for want, expose := range cases {
+		if got := expose.URL(); got != want {
+			t.Errorf("URL() = %q, want %q", got, want)
+		}
+	}
+}
+
+func TestValidateProfileExpose(t *testing.T) {
+	m := &Manifest{
+		Version: 1,
+		Project: Project{Name: "demo", DefaultProfile: "k8s"},
+		Profiles: map[string]Profile{
+			"k8s": {
+				Runtime: "k8s",
+				Services: map[string]Service{
+					"api": {Image: "api", Ports: []string{"8080"}, Expose: &Expose{Host: "api.local", TLSSecret: "tls"}},
+					"web": {Image: "web", Expose: &Expose{Host: "web.local", Port: 3000, Gateway: "infra/public"}},
+				},
+			},
+		},
+	}
+	if err := ValidateProfile(m, "k8s"); err != nil {
+		t.Fatalf("expected valid expose, got %v", err)
+	}
+
+	invalid := []Service{
+		{Image: "web", Expose: &Expose{Host: "web.local"}},
+		{Image: "web", Ports: []string{"80"}, Expose: &Expose{Host: "http://web.local"}},
+		{Image: "web", Ports: []string{"80"}, Expose: &Expose{Host: "web.local", Path: "app"}},
+		{Image: "web", Ports: []string{"80"}, Expose: &Expose{Host: "web.local", Gateway: "public", TLSSecret: "tls"}},
+		{Image: "web", Ports: []string{"80"}, Expose: &Expose{Host: "web.local", Gateway: "a/b/c"}},
+	}
+	for _, svc := range invalid {
+		m.Profiles["k8s"].Services["web"] = svc
+		if err := ValidateProfile(m, "k8s"); err == nil {
+			t.Errorf("expected error for expose %+v", svc.Expose)
+		}
+	}
+}
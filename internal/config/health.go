@@ -0,0 +1,116 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Health probe types returned by Health.Type.
+const (
+	HealthHTTP     = "httpGet"
+	HealthTCP      = "tcp"
+	HealthExec     = "exec"
+	HealthLogMatch = "logMatch"
+)
+
+// Type returns the probe type that is configured, or "" when none is.
+func (h *Health) Type() string {
+	switch {
+	case h == nil:
+		return ""
+	case h.HttpGet != "":
+		return HealthHTTP
+	case h.TCP != 0:
+		return HealthTCP
+	case h.Exec != "":
+		return HealthExec
+	case h.LogMatch != "":
+		return HealthLogMatch
+	}
+	return ""
+}
+
+// HTTPTarget returns the container port and path probed for an httpGet check.
+// The URL's port is a host port, so it is mapped back through ports.
+func (h *Health) HTTPTarget(ports []string) (int, string, error) {
+	u, err := url.Parse(h.HttpGet)
+	if err != nil {
+		return 0, "", err
+	}
+	port := 80
+	if u.Scheme == "https" {
+		port = 443
+	}
+	if p := u.Port(); p != "" {
+		if port, err = strconv.Atoi(p); err != nil {
+			return 0, "", err
+		}
+	}
+	path := u.RequestURI()
+	if path == "" {
+		path = "/"
+	}
+	return ContainerPort(ports, port), path, nil
+}
+
+// ContainerPort returns the container port published on hostPort in a list of
+// "[ip:]host:container[/proto]" specs, or hostPort itself if it is not mapped.
+func ContainerPort(ports []string, hostPort int) int {
+	for _, spec := range ports {
+		parts := strings.Split(strings.SplitN(spec, "/", 2)[0], ":")
+		if len(parts) < 2 {
+			continue
+		}
+		host, err := strconv.Atoi(parts[len(parts)-2])
+		if err != nil || host != hostPort {
+			continue
+		}
+		if target, err := strconv.Atoi(parts[len(parts)-1]); err == nil {
+			return target
+		}
+	}
+	return hostPort
+}
+
+func healthIssues(service string, h *Health) []string {
+	if h == nil {
+		return nil
+	}
+
+	var issues []string
+	set := 0
+	for _, configured := range []bool{h.HttpGet != "", h.TCP != 0, h.Exec != "", h.LogMatch != ""} {
+		if configured {
+			set++
+		}
+	}
+	if set != 1 {
+		issues = append(issues, fmt.Sprintf("service '%s' health must set exactly one of httpGet, tcp, exec or logMatch", service))
+	}
+	if h.HttpGet != "" {
+		if u, err := url.Parse(h.HttpGet); err != nil || u.Host == "" {
+			issues = append(issues, fmt.Sprintf("service '%s' health.httpGet must be an absolute URL", service))
+		}
+	}
+	if h.TCP < 0 || h.TCP > 65535 {
+		issues = append(issues, fmt.Sprintf("service '%s' health.tcp must be a port number", service))
+	}
+	if h.LogMatch != "" {
+		if _, err := regexp.Compile(h.LogMatch); err != nil {
+			issues = append(issues, fmt.Sprintf("service '%s' health.logMatch: %v", service, err))
+		}
+	}
+	for field, value := range map[string]string{"interval": h.Interval, "startPeriod": h.StartPeriod, "timeout": h.Timeout} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(value); err != nil {
+			issues = append(issues, fmt.Sprintf("service '%s' health.%s must be a duration like 5s", service, field))
+		}
+	}
+	return issues
+}
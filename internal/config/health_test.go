@@ -0,0 +1,47 @@
+package config
+
+import "testing"
+
+func TestValidateProfileHealth(t *testing.T) {
+	m := &Manifest{
+		Version: 1,
+		Project: Project{Name: "demo", DefaultProfile: "local"},
+		Profiles: map[string]Profile{
+			"local": {
+				Services: map[string]Service{
+					"api":    {Image: "nginx", Health: &Health{HttpGet: "http://localhost:8080/health", Timeout: "2s"}},
+					"worker": {Image: "worker", Health: &Health{TCP: 9000, StartPeriod: "30s"}},
+					"jobs":   {Image: "jobs", Health: &Health{LogMatch: `listening on :\d+`}},
+				},
+			},
+		},
+	}
+	if err := ValidateProfile(m, "local"); err != nil {
+		t.Fatalf("expected valid health checks, got %v", err)
+	}
+
+	invalid := []*Health{
+		{},
+		{HttpGet: "http://localhost/health", TCP: 80},
+		{HttpGet: "/health"},
+		{TCP: 70000},
+		{LogMatch: "("},
+		{Exec: "true", Interval: "soon"},
+	}
+	for _, health := range invalid {
+		m.Profiles["local"].Services["api"] = Service{Image: "nginx", Health: health}
+		if err := ValidateProfile(m, "local"); err == nil {
+			t.Errorf("expected error for health %+v", health)
+		}
+	}
+}
+
+func TestContainerPort(t *testing.T) {
+	ports := []string{"8080:80", "127.0.0.1:5433:5432/tcp"}
+	cases := map[int]int{8080: 80, 5433: 5432, 9000: 9000}
+	for host, want := range cases {
+		if got := ContainerPort(ports, host); got != want {
+			t.Errorf("ContainerPort(%d) = %d, want %d", host, got, want)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProjectEnvFile is the dotenv file read from the manifest's directory.
+const ProjectEnvFile = ".env"
+
+// lookupFunc resolves a variable name to its value.
+type lookupFunc func(name string) (string, bool)
+
+// interpolateTree expands ${VAR}, ${VAR:-default} and ${VAR:?error} in every
+// string of the tree. Variables come from the process environment, then the
+// service's envFile, the profile's envFile and finally the project .env in
+// dir. Problems are returned per profile name ("" for top-level fields) so
+// they can be reported by Validate and ValidateProfile.
+func interpolateTree(tree map[string]any, dir string) map[string][]string {
+	issues := map[string][]string{}
+
+	project, err := readEnvFile(filepath.Join(dir, ProjectEnvFile))
+	if err != nil && !os.IsNotExist(err) {
+		issues[""] = append(issues[""], fmt.Sprintf("%s: %v", ProjectEnvFile, err))
+	}
+	projectScope := chainLookup(os.LookupEnv, mapLookup(project))
+
+	profiles, _ := tree["profiles"].(map[string]any)
+	for key, value := range tree {
+		if key == "profiles" {
+			continue
+		}
+		tree[key] = interpolateNode(value, key, projectScope, func(issue string) {
+			issues[""] = append(issues[""], issue)
+		})
+	}
+
+	// Profiles resolved through extends share sub-trees with their base, so
+	// every profile is rebuilt rather than modified in place.
+	for profName, raw := range profiles {
+		prof, ok := raw.(map[string]any)
+		if !ok {
+			continue
+		}
+		path := "profiles." + profName
+		report := func(issue string) {
+			issues[profName] = append(issues[profName], issue)
+		}
+
+		profVars := loadEnvFiles(prof["envFile"], dir, path, report)
+		profScope := chainLookup(os.LookupEnv, mapLookup(profVars), mapLookup(project))
+
+		out := make(map[string]any, len(prof))
+		for key, value := range prof {
+			services, ok := value.(map[string]any)
+			switch {
+			case key == "envFile":
+				out[key] = value
+			case key == "services" && ok:
+				outServices := make(map[string]any, len(services))
+				for svcName, rawSvc := range services {
+					svc, ok := rawSvc.(map[string]any)
+					if !ok {
+						outServices[svcName] = rawSvc
+						continue
+					}
+					svcPath := path + ".services." + svcName
+					svcVars := loadEnvFiles(svc["envFile"], dir, svcPath, report)
+					svcScope := chainLookup(os.LookupEnv, mapLookup(svcVars), mapLookup(profVars), mapLookup(project))
+					outSvc := make(map[string]any, len(svc))
+					for field, fieldValue := range svc {
+						if field == "envFile" {
+							outSvc[field] = fieldValue
+							continue
+						}
+						outSvc[field] = interpolateNode(fieldValue, svcPath+"."+field, svcScope, report)
+					}
+					outServices[svcName] = outSvc
+				}
+				out[key] = outServices
+			default:
+				out[key] = interpolateNode(value, path+"."+key, profScope, report)
+			}
+		}
+		profiles[profName] = out
+	}
+
+	return issues
+}
+
+func interpolateNode(node any, path string, lookup lookupFunc, report func(string)) any {
+	switch v := node.(type) {
+	case string:
+		out, err := interpolate(v, lookup)
+		if err != nil {
+			report(fmt.Sprintf("%s: %v", path, err))
+		}
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, value := range v {
+			out[key] = interpolateNode(value, path+"."+key, lookup, report)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, value := range v {
+			out[i] = interpolateNode(value, fmt.Sprintf("%s[%d]", path, i), lookup, report)
+		}
+		return out
+	default:
+		return node
+	}
+}
+
+// interpolate expands the variable references in s into a literal value:
+// `$$` yields a single `$` and a lone `$` not followed by `{` is kept. Values
+// are escaped again where a renderer's output is itself interpolated, such as
+// compose files.
+func interpolate(s string, lookup lookupFunc) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var out strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			out.WriteByte(s[i])
+			continue
+		}
+		if s[i+1] == '$' {
+			out.WriteByte('$')
+			i++
+			continue
+		}
+		if s[i+1] != '{' {
+			out.WriteByte(s[i])
+			continue
+		}
+
+		end := matchBrace(s, i+2)
+		if end < 0 {
+			return s, fmt.Errorf("unterminated variable reference in %q", s)
+		}
+		value, err := expand(s[i+2:end], lookup)
+		if err != nil {
+			return s, err
+		}
+		out.WriteString(value)
+		i = end
+	}
+	return out.String(), nil
+}
+
+// matchBrace returns the index of the `}` closing a reference whose body starts
+// at start, allowing nested ${...} in defaults.
+func matchBrace(s string, start int) int {
+	depth := 1
+	for i := start; i < len(s); i++ {
+		switch {
+		case s[i] == '{' && i > 0 && s[i-1] == '$':
+			depth++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// expand evaluates the body of a ${...} reference.
+func expand(body string, lookup lookupFunc) (string, error) {
+	end := 0
+	for end < len(body) && isVarChar(body[end], end) {
+		end++
+	}
+	name, rest := body[:end], body[end:]
+	if name == "" {
+		return "", fmt.Errorf("invalid variable reference ${%s}", body)
+	}
+
+	op, arg := "", ""
+	for _, candidate := range []string{":-", ":?", "-", "?"} {
+		if strings.HasPrefix(rest, candidate) {
+			op, arg = candidate, rest[len(candidate):]
+			break
+		}
+	}
+	if op == "" && rest != "" {
+		return "", fmt.Errorf("invalid variable reference ${%s}", body)
+	}
+
+	value, ok := lookup(name)
+	unset := !ok || (strings.HasPrefix(op, ":") && value == "")
+	switch op {
+	case ":-", "-":
+		if unset {
+			return interpolate(arg, lookup)
+		}
+	case ":?", "?":
+		if unset {
+			msg, err := interpolate(arg, lookup)
+			if err != nil {
+				return "", err
+			}
+			if msg == "" {
+				return "", fmt.Errorf("required variable %s is not set", name)
+			}
+			return "", fmt.Errorf("required variable %s is not set: %s", name, msg)
+		}
+	}
+	return value, nil
+}
+
+func validVarName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isVarChar(name[i], i) {
+			return false
+		}
+	}
+	return true
+}
+
+func isVarChar(c byte, pos int) bool {
+	return c == '_' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (pos > 0 && c >= '0' && c <= '9')
+}
+
+func chainLookup(lookups ...lookupFunc) lookupFunc {
+	return func(name string) (string, bool) {
+		for _, lookup := range lookups {
+			if value, ok := lookup(name); ok {
+				return value, true
+			}
+		}
+		return "", false
+	}
+}
+
+func mapLookup(vars map[string]string) lookupFunc {
+	return func(name string) (string, bool) {
+		value, ok := vars[name]
+		return value, ok
+	}
+}
+
+// loadEnvFiles reads the envFile list of a profile or service. Later files
+// override earlier ones; relative paths are resolved from dir.
+func loadEnvFiles(raw any, dir string, path string, report func(string)) map[string]string {
+	var files []string
+	switch v := raw.(type) {
+	case nil:
+		return nil
+	case string:
+		files = []string{v}
+	case []any:
+		for _, entry := range v {
+			if file, ok := entry.(string); ok {
+				files = append(files, file)
+			} else {
+				report(fmt.Sprintf("%s.envFile: entries must be paths", path))
+			}
+		}
+	default:
+		report(fmt.Sprintf("%s.envFile: must be a list of paths", path))
+		return nil
+	}
+
+	vars := map[string]string{}
+	for _, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		fileVars, err := readEnvFile(file)
+		if err != nil {
+			report(fmt.Sprintf("%s.envFile: %v", path, err))
+			continue
+		}
+		for key, value := range fileVars {
+			vars[key] = value
+		}
+	}
+	return vars
+}
+
+// readEnvFile parses a dotenv file: KEY=VALUE lines, optional `export`
+// prefix, `#` comments and single- or double-quoted values.
+func readEnvFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !validVarName(key) {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNo)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		} else if idx := strings.Index(value, " #"); idx >= 0 {
+			value = strings.TrimSpace(value[:idx])
+		}
+		vars[key] = value
+	}
+	return vars, scanner.Err()
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	lookup := mapLookup(map[string]string{"USER": "devx", "EMPTY": ""})
+
+	cases := map[string]string{
+		"plain":                       "plain",
+		"${USER}":                     "devx",
+		"postgres://${USER}@db":       "postgres://devx@db",
+		"${MISSING}":                  "",
+		"${MISSING:-fallback}":        "fallback",
+		"${EMPTY:-fallback}":          "fallback",
+		"${EMPTY-fallback}":           "",
+		"${MISSING:-${USER}}":         "devx",
+		"$${USER} is escaped":         "${USER} is escaped",
+		"pa$$word":                    "pa$word",
+		"cost: $5":                    "cost: $5",
+		"${USER:?must be set}-suffix": "devx-suffix",
+	}
+	for in, want := range cases {
+		got, err := interpolate(in, lookup)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", in, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%q: expected %q, got %q", in, want, got)
+		}
+	}
+
+	for _, in := range []string{"${MISSING:?set it}", "${EMPTY:?}", "${UNTERMINATED", "${BAD NAME}"} {
+		if _, err := interpolate(in, lookup); err == nil {
+			t.Errorf("%q: expected error", in)
+		}
+	}
+}
+
+func TestLoadInterpolation(t *testing.T) {
+	dir := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("DEVX_TEST_TAG", "from-process")
+
+	write(".env", "# project defaults\nDB_PASSWORD=from-dotenv\nAPI_PORT=8080\nDEVX_TEST_TAG=from-dotenv\n")
+	write("ci.env", "export DB_PASSWORD=\"from-profile\"\n")
+	write("api.env", "API_PORT=9090\n")
+	write("devx.yaml", `version: 1
+project:
+  name: my-app
+  defaultProfile: local
+profiles:
+  local:
+    services:
+      api:
+        image: "nginx:${DEVX_TEST_TAG}"
+        ports: ["${API_PORT}:80"]
+        env:
+          DB_PASSWORD: ${DB_PASSWORD}
+  ci:
+    envFile: [ci.env]
+    services:
+      api:
+        envFile: [api.env]
+        image: nginx:alpine
+        ports: ["${API_PORT}:80"]
+        env:
+          DB_PASSWORD: ${DB_PASSWORD}
+  staging:
+    services:
+      api:
+        image: nginx:alpine
+        env:
+          SECRET: ${DEVX_TEST_SECRET:?export it first}
+`)
+
+	m, err := Load(filepath.Join(dir, "devx.yaml"))
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	local := m.Profiles["local"].Services["api"]
+	if local.Image != "nginx:from-process" {
+		t.Errorf("expected process env to win over .env, got %q", local.Image)
+	}
+	if local.Ports[0] != "8080:80" || local.Env["DB_PASSWORD"] != "from-dotenv" {
+		t.Errorf("expected .env values, got %+v", local)
+	}
+
+	ci := m.Profiles["ci"].Services["api"]
+	if ci.Ports[0] != "9090:80" || ci.Env["DB_PASSWORD"] != "from-profile" {
+		t.Errorf("expected envFile values, got %+v", ci)
+	}
+
+	if err := ValidateProfile(m, "local"); err != nil {
+		t.Errorf("expected local to validate, got %v", err)
+	}
+
+	err = ValidateProfile(m, "staging")
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	if len(verr.Issues) != 1 || !strings.HasPrefix(verr.Issues[0], "profiles.staging.services.api.env.SECRET: required variable DEVX_TEST_SECRET") {
+		t.Fatalf("unexpected issues: %v", verr.Issues)
+	}
+}
@@ -2,16 +2,24 @@ package config
 
 import (
 	"fmt"
-	"os"
-
-	"gopkg.in/yaml.v3"
+	"path/filepath"
 )
 
 type Manifest struct {
-	Version  int                `yaml:"version"`
-	Project  Project            `yaml:"project"`
-	Registry Registry           `yaml:"registry"`
-	Profiles map[string]Profile `yaml:"profiles"`
+	Version int `yaml:"version"`
+	// Include lists other manifest files merged underneath this one. It is
+	// consumed by Load and is always empty afterwards.
+	Include  []string `yaml:"include,omitempty"`
+	Project  Project  `yaml:"project"`
+	Registry Registry `yaml:"registry"`
+	// Telemetry configures the telemetry stack of compose profiles.
+	Telemetry Telemetry          `yaml:"telemetry,omitempty"`
+	Secrets   map[string]Secret  `yaml:"secrets,omitempty"`
+	Profiles  map[string]Profile `yaml:"profiles"`
+
+	// interpolationIssues holds variable interpolation problems keyed by
+	// profile name ("" for fields outside profiles).
+	interpolationIssues map[string][]string
 }
 
 type Project struct {
@@ -23,11 +31,25 @@ type Registry struct {
 	Prefix string `yaml:"prefix"`
 }
 
+type Telemetry struct {
+	// Traces adds Tempo and an OTLP receiver to the telemetry stack, and
+	// points the OpenTelemetry SDKs of services at it.
+	Traces bool `yaml:"traces,omitempty"`
+}
+
 type Profile struct {
+	// Extends names a profile whose settings this profile inherits and overrides.
+	Extends  string             `yaml:"extends,omitempty"`
 	Services map[string]Service `yaml:"services"`
 	Deps     map[string]Dep     `yaml:"deps"`
 	Runtime  string             `yaml:"runtime"`
 	Hooks    Hooks              `yaml:"hooks"`
+	// EnvFile lists dotenv files whose variables are available for
+	// interpolation within this profile.
+	EnvFile []string `yaml:"envFile,omitempty"`
+	// SecurityContext adds pod and container security settings to the
+	// services of a k8s profile.
+	SecurityContext *SecurityContext `yaml:"securityContext,omitempty"`
 }
 
 // Hooks defines commands to run at lifecycle points around devx up/down.
@@ -57,8 +79,28 @@ type Service struct {
 	Command   []string          `yaml:"command"`
 	Workdir   string            `yaml:"workdir"`
 	Mount     []string          `yaml:"mount"`
-	DependsOn []string          `yaml:"dependsOn"`
+	DependsOn []Dependency      `yaml:"dependsOn"`
 	Health    *Health           `yaml:"health"`
+	// Volumes are named volumes in "name:/path[:ro]" format. They become
+	// compose volumes and PersistentVolumeClaims in k8s.
+	Volumes []string `yaml:"volumes,omitempty"`
+	// Storage sizes the claims of Volumes in k8s.
+	Storage Storage `yaml:"storage,omitempty"`
+	// Expose makes the service reachable from outside a k8s cluster.
+	Expose *Expose `yaml:"expose,omitempty"`
+	// Watch lists the paths `devx watch` reacts to and how.
+	Watch []WatchRule `yaml:"watch,omitempty"`
+	// Resources sets CPU and memory requests and limits.
+	Resources *Resources `yaml:"resources,omitempty"`
+	// Replicas is the number of pods in k8s (default 1).
+	Replicas int `yaml:"replicas,omitempty"`
+	// EnvFile lists dotenv files whose variables are available for
+	// interpolation within this service; they take precedence over the
+	// profile's envFile.
+	EnvFile []string `yaml:"envFile,omitempty"`
+	// SecretEnv maps env var names to the secret they reference, collected
+	// from `env` entries written as {secretRef: name}.
+	SecretEnv map[string]string `yaml:"-"`
 }
 
 type Build struct {
@@ -66,10 +108,22 @@ type Build struct {
 	Dockerfile string `yaml:"dockerfile"`
 }
 
+// Health configures a service's readiness check. Exactly one of HttpGet, TCP,
+// Exec or LogMatch must be set.
 type Health struct {
-	HttpGet  string `yaml:"httpGet"`
-	Interval string `yaml:"interval"`
-	Retries  int    `yaml:"retries"`
+	// HttpGet is a URL on the host (using the published port) that must
+	// return 2xx. Inside the container it is probed on the mapped port.
+	HttpGet string `yaml:"httpGet"`
+	// TCP is a container port that must accept connections.
+	TCP int `yaml:"tcp,omitempty"`
+	// Exec is a shell command run inside the container that must exit 0.
+	Exec string `yaml:"exec,omitempty"`
+	// LogMatch is a regular expression that must match a container log line.
+	LogMatch    string `yaml:"logMatch,omitempty"`
+	Interval    string `yaml:"interval"`
+	Retries     int    `yaml:"retries"`
+	StartPeriod string `yaml:"startPeriod,omitempty"`
+	Timeout     string `yaml:"timeout,omitempty"`
 }
 
 type Dep struct {
@@ -78,22 +132,33 @@ type Dep struct {
 	Env     map[string]string `yaml:"env"`
 	Ports   []string          `yaml:"ports"`
 	Volume  string            `yaml:"volume"`
+	// Storage sizes the k8s volume claim of a dep with a volume.
+	Storage Storage `yaml:"storage,omitempty"`
+	// Resources sets CPU and memory requests and limits.
+	Resources *Resources `yaml:"resources,omitempty"`
+	// SecretEnv maps env var names to the secret they reference, collected
+	// from `env` entries written as {secretRef: name}.
+	SecretEnv map[string]string `yaml:"-"`
 }
 
+// Load reads the manifest at path, merges its includes (resolved relative to
+// the including file), resolves profile inheritance and interpolates variables.
 func Load(path string) (*Manifest, error) {
-	data, err := os.ReadFile(path)
+	tree, err := loadTree(path, nil)
 	if err != nil {
 		return nil, err
 	}
-	return Parse(data)
+	return decodeTree(tree, filepath.Dir(path))
 }
 
+// Parse decodes a manifest document. Includes, .env and envFile paths are
+// resolved relative to the working directory.
 func Parse(data []byte) (*Manifest, error) {
-	var m Manifest
-	if err := yaml.Unmarshal(data, &m); err != nil {
-		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	tree, err := parseTree(data, ".", nil)
+	if err != nil {
+		return nil, err
 	}
-	return &m, nil
+	return decodeTree(tree, ".")
 }
 
 func ProfileByName(m *Manifest, name string) (*Profile, error) {
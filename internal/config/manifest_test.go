@@ -151,3 +151,93 @@ profiles:
 		t.Fatalf("expected error for missing profile")
 	}
 }
+
+func TestValidateProfileBuiltinDepKinds(t *testing.T) {
+	data := []byte(`version: 1
+project:
+  name: my-app
+  defaultProfile: local
+profiles:
+  local:
+    runtime: compose
+    services:
+      api:
+        image: nginx:alpine
+    deps:
+      db:
+        kind: mysql
+        volume: db-data
+      queue:
+        kind: rabbitmq
+      search:
+        kind: elasticsearch
+`)
+
+	m, err := Parse(data)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	if err := ValidateProfile(m, "local"); err != nil {
+		t.Fatalf("expected built-in dep kinds to validate, got: %v", err)
+	}
+}
+
+func TestDependsOnConditions(t *testing.T) {
+	data := []byte(`version: 1
+project:
+  name: demo
+  defaultProfile: local
+profiles:
+  local:
+    services:
+      api:
+        image: nginx
+        dependsOn:
+          - cache
+          - name: db
+            condition: healthy
+          - name: migrate
+            condition: completed
+      migrate:
+        image: migrate
+    deps:
+      db:
+        kind: postgres
+      cache:
+        kind: redis
+`)
+
+	m, err := Parse(data)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if err := ValidateProfile(m, "local"); err != nil {
+		t.Fatalf("expected valid profile, got %v", err)
+	}
+
+	want := []Dependency{{Name: "cache"}, {Name: "db", Condition: ConditionHealthy}, {Name: "migrate", Condition: ConditionCompleted}}
+	got := m.Profiles["local"].Services["api"].DependsOn
+	if len(got) != len(want) {
+		t.Fatalf("unexpected dependsOn %v", got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("dependsOn[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	prof := m.Profiles["local"]
+	api := prof.Services["api"]
+	api.DependsOn = []Dependency{{Name: "migrate", Condition: ConditionHealthy}}
+	prof.Services["api"] = api
+	if err := ValidateProfile(m, "local"); err == nil {
+		t.Errorf("expected error for healthy condition on a service without health check")
+	}
+
+	api.DependsOn = []Dependency{{Name: "db", Condition: "ready"}}
+	prof.Services["api"] = api
+	if err := ValidateProfile(m, "local"); err == nil {
+		t.Errorf("expected error for unknown condition")
+	}
+}
@@ -0,0 +1,213 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The manifest is resolved as a generic YAML tree before it is decoded into
+// Manifest, so that `include:` and profile `extends:` can be merged with
+// deep-merge semantics:
+//
+//   - mappings merge key by key, recursively;
+//   - scalars and lists in the overriding document replace the base value;
+//   - an explicit null deletes the inherited key.
+
+// loadTree reads a manifest file and resolves its includes. stack holds the
+// absolute paths of the files currently being loaded, for cycle detection.
+func loadTree(path string, stack []string) (map[string]any, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, seen := range stack {
+		if seen == abs {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack, abs), " -> "))
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := parseTree(data, filepath.Dir(path), append(stack, abs))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return tree, nil
+}
+
+// parseTree parses a manifest document and merges it over the files listed in
+// its `include:` key. Relative include paths are resolved from dir.
+func parseTree(data []byte, dir string, stack []string) (map[string]any, error) {
+	var tree map[string]any
+	if err := yaml.Unmarshal(data, &tree); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	if tree == nil {
+		tree = map[string]any{}
+	}
+
+	raw, ok := tree["include"]
+	if !ok {
+		return tree, nil
+	}
+	delete(tree, "include")
+
+	list, ok := raw.([]any)
+	if !ok && raw != nil {
+		return nil, fmt.Errorf("include must be a list of paths")
+	}
+
+	base := map[string]any{}
+	for _, entry := range list {
+		rel, ok := entry.(string)
+		if !ok || rel == "" {
+			return nil, fmt.Errorf("include entries must be non-empty paths")
+		}
+		path := rel
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, rel)
+		}
+		included, err := loadTree(path, stack)
+		if err != nil {
+			return nil, err
+		}
+		base = mergeTree(base, included).(map[string]any)
+	}
+
+	return mergeTree(base, tree).(map[string]any), nil
+}
+
+// resolveExtends replaces every profile that sets `extends` with the deep merge
+// of its (recursively resolved) base profile and its own settings.
+func resolveExtends(tree map[string]any) error {
+	profiles, ok := tree["profiles"].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	resolved := map[string]map[string]any{}
+	var resolve func(name string, stack []string) (map[string]any, error)
+	resolve = func(name string, stack []string) (map[string]any, error) {
+		if prof, ok := resolved[name]; ok {
+			return prof, nil
+		}
+		for _, seen := range stack {
+			if seen == name {
+				return nil, fmt.Errorf("profile extends cycle: %s", strings.Join(append(stack, name), " -> "))
+			}
+		}
+
+		prof, ok := profiles[name].(map[string]any)
+		if !ok {
+			if profiles[name] != nil {
+				return nil, fmt.Errorf("profile '%s' must be a mapping", name)
+			}
+			prof = map[string]any{}
+		}
+
+		if raw, ok := prof["extends"]; ok && raw != nil {
+			baseName, ok := raw.(string)
+			if !ok || baseName == "" {
+				return nil, fmt.Errorf("profile '%s' extends must be a profile name", name)
+			}
+			if _, exists := profiles[baseName]; !exists {
+				return nil, fmt.Errorf("profile '%s' extends unknown profile '%s'", name, baseName)
+			}
+			base, err := resolve(baseName, append(stack, name))
+			if err != nil {
+				return nil, err
+			}
+			prof = mergeTree(base, prof).(map[string]any)
+		}
+
+		resolved[name] = prof
+		return prof, nil
+	}
+
+	for name := range profiles {
+		prof, err := resolve(name, nil)
+		if err != nil {
+			return err
+		}
+		profiles[name] = prof
+	}
+	return nil
+}
+
+// mergeTree deep-merges override onto base without modifying either. Null
+// values are kept so that later merges still see the deletion; they are
+// removed by pruneNulls once resolution is complete.
+func mergeTree(base any, override any) any {
+	baseMap, ok := base.(map[string]any)
+	if !ok {
+		return override
+	}
+	overrideMap, ok := override.(map[string]any)
+	if !ok {
+		return override
+	}
+
+	merged := make(map[string]any, len(baseMap)+len(overrideMap))
+	for key, value := range baseMap {
+		merged[key] = value
+	}
+	for key, value := range overrideMap {
+		if value == nil {
+			merged[key] = nil
+			continue
+		}
+		merged[key] = mergeTree(merged[key], value)
+	}
+	return merged
+}
+
+// pruneNulls removes null-valued keys from every mapping in the tree.
+func pruneNulls(node any) any {
+	switch v := node.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, value := range v {
+			if value == nil {
+				continue
+			}
+			out[key] = pruneNulls(value)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i, value := range v {
+			out[i] = pruneNulls(value)
+		}
+		return out
+	default:
+		return node
+	}
+}
+
+// decodeTree resolves profile inheritance, interpolates variables (with .env
+// and relative envFile paths resolved from dir) and decodes the tree into a
+// Manifest.
+func decodeTree(tree map[string]any, dir string) (*Manifest, error) {
+	if err := resolveExtends(tree); err != nil {
+		return nil, err
+	}
+	issues := interpolateTree(tree, dir)
+
+	data, err := yaml.Marshal(pruneNulls(tree))
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %w", err)
+	}
+	m.interpolationIssues = issues
+	return &m, nil
+}
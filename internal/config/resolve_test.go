@@ -0,0 +1,161 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProfileExtends(t *testing.T) {
+	data := []byte(`version: 1
+project:
+  name: my-app
+  defaultProfile: local
+profiles:
+  local:
+    services:
+      api:
+        image: nginx:alpine
+        ports: ["8080:80"]
+        env:
+          APP_ENV: development
+          DEBUG: "1"
+      worker:
+        image: alpine:3.19
+    deps:
+      db:
+        kind: postgres
+        version: "16"
+    hooks:
+      afterUp:
+        - run: "./scripts/seed.sh"
+  ci:
+    extends: local
+    services:
+      api:
+        image: registry.local/api:latest
+        env:
+          APP_ENV: test
+          DEBUG: null
+      worker: null
+    hooks:
+      afterUp: null
+`)
+
+	m, err := Parse(data)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if err := ValidateProfile(m, "ci"); err != nil {
+		t.Fatalf("profile validation failed: %v", err)
+	}
+
+	ci := m.Profiles["ci"]
+	api := ci.Services["api"]
+	if api.Image != "registry.local/api:latest" {
+		t.Errorf("expected overridden image, got %q", api.Image)
+	}
+	if len(api.Ports) != 1 || api.Ports[0] != "8080:80" {
+		t.Errorf("expected inherited ports, got %v", api.Ports)
+	}
+	if api.Env["APP_ENV"] != "test" {
+		t.Errorf("expected overridden env, got %v", api.Env)
+	}
+	if _, ok := api.Env["DEBUG"]; ok {
+		t.Errorf("expected DEBUG to be deleted, got %v", api.Env)
+	}
+	if _, ok := ci.Services["worker"]; ok {
+		t.Errorf("expected worker to be deleted")
+	}
+	if ci.Deps["db"].Kind != "postgres" {
+		t.Errorf("expected inherited dep, got %+v", ci.Deps)
+	}
+	if len(ci.Hooks.AfterUp) != 0 {
+		t.Errorf("expected afterUp hooks to be deleted, got %+v", ci.Hooks.AfterUp)
+	}
+
+	local := m.Profiles["local"]
+	if local.Services["api"].Env["DEBUG"] != "1" || len(local.Services) != 2 {
+		t.Errorf("base profile must not be modified: %+v", local)
+	}
+}
+
+func TestProfileExtendsErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"unknown": `version: 1
+profiles:
+  ci:
+    extends: missing
+`,
+		"cycle": `version: 1
+profiles:
+  a:
+    extends: b
+  b:
+    extends: a
+`,
+	} {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadInclude(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "devx"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, path), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("devx.yaml", `version: 1
+include:
+  - devx/deps.yaml
+project:
+  name: my-app
+  defaultProfile: local
+profiles:
+  local:
+    services:
+      api:
+        image: nginx:alpine
+`)
+	write("devx/deps.yaml", `include:
+  - shared.yaml
+profiles:
+  local:
+    deps:
+      db:
+        kind: postgres
+`)
+	write("devx/shared.yaml", `registry:
+  prefix: registry.local
+`)
+
+	m, err := Load(filepath.Join(dir, "devx.yaml"))
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if err := ValidateProfile(m, "local"); err != nil {
+		t.Fatalf("profile validation failed: %v", err)
+	}
+	if m.Registry.Prefix != "registry.local" {
+		t.Errorf("expected nested include to be resolved relative to its parent, got %q", m.Registry.Prefix)
+	}
+	local := m.Profiles["local"]
+	if local.Services["api"].Image != "nginx:alpine" || local.Deps["db"].Kind != "postgres" {
+		t.Errorf("expected merged profile, got %+v", local)
+	}
+
+	write("devx/shared.yaml", `include:
+  - ../devx.yaml
+`)
+	if _, err := Load(filepath.Join(dir, "devx.yaml")); err == nil {
+		t.Fatalf("expected include cycle error")
+	}
+}
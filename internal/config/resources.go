@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Resources are the CPU and memory a container requests, plus optional
+// limits. Values use Kubernetes quantities such as 500m or 256Mi.
+type Resources struct {
+	CPU    string          `yaml:"cpu,omitempty"`
+	Memory string          `yaml:"memory,omitempty"`
+	Limits *ResourceLimits `yaml:"limits,omitempty"`
+}
+
+type ResourceLimits struct {
+	CPU    string `yaml:"cpu,omitempty"`
+	Memory string `yaml:"memory,omitempty"`
+}
+
+// SecurityContext opts the services of a k8s profile into hardened pod
+// defaults. Deps are left alone since most official images run as root.
+type SecurityContext struct {
+	RunAsNonRoot           bool `yaml:"runAsNonRoot,omitempty"`
+	ReadOnlyRootFilesystem bool `yaml:"readOnlyRootFilesystem,omitempty"`
+}
+
+// cpuPattern matches CPU quantities in cores (0.5, 2) or millicores (500m).
+var cpuPattern = regexp.MustCompile(`^([0-9]+m|[0-9]+(\.[0-9]+)?)$`)
+
+// CPUCores converts a CPU quantity to the decimal cores compose expects for
+// `cpus`, e.g. 500m to 0.5.
+func CPUCores(cpu string) string {
+	milli, ok := strings.CutSuffix(cpu, "m")
+	if !ok {
+		return cpu
+	}
+	n, err := strconv.Atoi(milli)
+	if err != nil {
+		return cpu
+	}
+	return strconv.FormatFloat(float64(n)/1000, 'f', -1, 64)
+}
+
+// memoryUnits are the multipliers of the quantity suffixes quantityPattern
+// accepts.
+var memoryUnits = map[string]float64{
+	"":   1,
+	"k":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"P":  1e15,
+	"E":  1e18,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+	"Pi": 1 << 50,
+	"Ei": 1 << 60,
+}
+
+// MemoryBytes converts a memory quantity to the byte count compose expects,
+// e.g. 256Mi to 268435456. Compose reads suffixes as binary and knows no E,
+// so quantities are never passed through as written.
+func MemoryBytes(memory string) string {
+	m := quantityPattern.FindStringSubmatch(memory)
+	if m == nil {
+		return memory
+	}
+	number := strings.TrimSuffix(memory, m[2])
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return memory
+	}
+	return strconv.FormatFloat(math.Ceil(n*memoryUnits[m[2]]), 'f', 0, 64)
+}
+
+func resourcesIssues(owner string, res *Resources) []string {
+	if res == nil {
+		return nil
+	}
+	var issues []string
+	check := func(field string, cpu string, memory string) {
+		if cpu != "" && !cpuPattern.MatchString(cpu) {
+			issues = append(issues, fmt.Sprintf("%s %scpu must be cores like 0.5 or millicores like 500m", owner, field))
+		}
+		if memory != "" && !quantityPattern.MatchString(memory) {
+			issues = append(issues, fmt.Sprintf("%s %smemory must be a quantity like 256Mi", owner, field))
+		}
+	}
+	check("resources.", res.CPU, res.Memory)
+	if res.Limits != nil {
+		check("resources.limits.", res.Limits.CPU, res.Limits.Memory)
+	}
+	return issues
+}
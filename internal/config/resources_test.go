@@ -0,0 +1,62 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCPUCores(t *testing.T) {
+	cases := map[string]string{"500m": "0.5", "1500m": "1.5", "2": "2", "0.25": "0.25"}
+	for in, want := range cases {
+		if got := CPUCores(in); got != want {
+			t.Errorf("CPUCores(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMemoryBytes(t *testing.T) {
+	cases := map[string]string{
+		"256Mi": "268435456",
+		"1.5Gi": "1610612736",
+		"500M":  "500000000",
+		"1E":    "1000000000000000000",
+		"2Ei":   "2305843009213693952",
+		"1024":  "1024",
+	}
+	for in, want := range cases {
+		if got := MemoryBytes(in); got != want {
+			t.Errorf("MemoryBytes(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestValidateProfileResources(t *testing.T) {
+	m := &Manifest{
+		Version: 1,
+		Project: Project{Name: "demo", DefaultProfile: "local"},
+		Profiles: map[string]Profile{
+			"local": {
+				Services: map[string]Service{
+					"api": {Image: "api", Replicas: 2, Resources: &Resources{CPU: "500m", Memory: "256Mi", Limits: &ResourceLimits{CPU: "1", Memory: "1Gi"}}},
+				},
+				Deps: map[string]Dep{
+					"db": {Kind: "postgres", Resources: &Resources{Memory: "512Mi"}},
+				},
+			},
+		},
+	}
+	if err := ValidateProfile(m, "local"); err != nil {
+		t.Fatalf("expected valid resources, got %v", err)
+	}
+
+	m.Profiles["local"].Services["api"] = Service{Image: "api", Replicas: -1, Resources: &Resources{CPU: "half", Limits: &ResourceLimits{Memory: "lots"}}}
+	err := ValidateProfile(m, "local")
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	for _, want := range []string{"resources.cpu", "resources.limits.memory", "replicas must not be negative"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+}
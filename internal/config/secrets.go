@@ -0,0 +1,107 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Secret describes where a secret value comes from. Exactly one source must
+// be set.
+type Secret struct {
+	// File is a path, relative to devx.yaml, whose content is the secret.
+	File string `yaml:"file,omitempty"`
+	// Env is the name of a host environment variable holding the secret.
+	Env string `yaml:"env,omitempty"`
+	// Command is a host shell command whose stdout is the secret.
+	Command string `yaml:"command,omitempty"`
+}
+
+func (s *Service) UnmarshalYAML(node *yaml.Node) error {
+	refs, err := extractSecretRefs(node)
+	if err != nil {
+		return err
+	}
+	type plain Service
+	if err := node.Decode((*plain)(s)); err != nil {
+		return err
+	}
+	s.SecretEnv = refs
+	return nil
+}
+
+func (d *Dep) UnmarshalYAML(node *yaml.Node) error {
+	refs, err := extractSecretRefs(node)
+	if err != nil {
+		return err
+	}
+	type plain Dep
+	if err := node.Decode((*plain)(d)); err != nil {
+		return err
+	}
+	d.SecretEnv = refs
+	return nil
+}
+
+// extractSecretRefs removes `{secretRef: name}` values from the env mapping of
+// a service or dep node and returns them keyed by env var name.
+func extractSecretRefs(node *yaml.Node) (map[string]string, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var env *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "env" {
+			env = node.Content[i+1]
+		}
+	}
+	if env == nil || env.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+
+	var refs map[string]string
+	kept := env.Content[:0:0]
+	for i := 0; i+1 < len(env.Content); i += 2 {
+		key, value := env.Content[i], env.Content[i+1]
+		if value.Kind != yaml.MappingNode {
+			kept = append(kept, key, value)
+			continue
+		}
+		var ref struct {
+			SecretRef string `yaml:"secretRef"`
+		}
+		if err := value.Decode(&ref); err != nil || ref.SecretRef == "" {
+			return nil, fmt.Errorf("line %d: env %s must be a string or {secretRef: name}", value.Line, key.Value)
+		}
+		if refs == nil {
+			refs = map[string]string{}
+		}
+		refs[key.Value] = ref.SecretRef
+	}
+	env.Content = kept
+	return refs, nil
+}
+
+// ReferencedSecrets returns the sorted names of the secrets used by a profile.
+func ReferencedSecrets(prof *Profile) []string {
+	seen := map[string]bool{}
+	for _, svc := range prof.Services {
+		for _, name := range svc.SecretEnv {
+			seen[name] = true
+		}
+	}
+	for _, dep := range prof.Deps {
+		for _, name := range dep.SecretEnv {
+			seen[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,105 @@
+package config
+
+import "testing"
+
+func TestSecretRefs(t *testing.T) {
+	data := []byte(`version: 1
+project:
+  name: my-app
+  defaultProfile: local
+secrets:
+  db-password:
+    file: ./secrets/db-password.txt
+  api-token:
+    env: API_TOKEN
+profiles:
+  local:
+    services:
+      api:
+        image: nginx:alpine
+        env:
+          APP_ENV: development
+          DB_PASSWORD:
+            secretRef: db-password
+          TOKEN: {secretRef: api-token}
+    deps:
+      db:
+        kind: postgres
+        env:
+          POSTGRES_PASSWORD:
+            secretRef: db-password
+`)
+
+	m, err := Parse(data)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if err := Validate(m); err != nil {
+		t.Fatalf("validate failed: %v", err)
+	}
+	if err := ValidateProfile(m, "local"); err != nil {
+		t.Fatalf("profile validation failed: %v", err)
+	}
+
+	api := m.Profiles["local"].Services["api"]
+	if api.Env["APP_ENV"] != "development" {
+		t.Errorf("expected literal env to be kept, got %v", api.Env)
+	}
+	if _, ok := api.Env["DB_PASSWORD"]; ok {
+		t.Errorf("secret env must not appear in literal env: %v", api.Env)
+	}
+	if api.SecretEnv["DB_PASSWORD"] != "db-password" || api.SecretEnv["TOKEN"] != "api-token" {
+		t.Errorf("unexpected secret env %v", api.SecretEnv)
+	}
+	if m.Profiles["local"].Deps["db"].SecretEnv["POSTGRES_PASSWORD"] != "db-password" {
+		t.Errorf("expected dep secret env")
+	}
+
+	prof := m.Profiles["local"]
+	names := ReferencedSecrets(&prof)
+	if len(names) != 2 || names[0] != "api-token" || names[1] != "db-password" {
+		t.Errorf("unexpected referenced secrets %v", names)
+	}
+}
+
+func TestSecretRefErrors(t *testing.T) {
+	data := []byte(`version: 1
+project:
+  name: my-app
+  defaultProfile: local
+secrets:
+  both:
+    file: ./a
+    env: A
+profiles:
+  local:
+    services:
+      api:
+        image: nginx:alpine
+        env:
+          DB_PASSWORD:
+            secretRef: missing
+`)
+
+	m, err := Parse(data)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if err := Validate(m); err == nil {
+		t.Errorf("expected error for secret with two sources")
+	}
+	if err := ValidateProfile(m, "local"); err == nil {
+		t.Errorf("expected error for unknown secretRef")
+	}
+
+	if _, err := Parse([]byte(`version: 1
+profiles:
+  local:
+    services:
+      api:
+        env:
+          BAD: {value: x}
+`)); err == nil {
+		t.Errorf("expected error for env mapping without secretRef")
+	}
+}
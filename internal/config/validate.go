@@ -4,12 +4,9 @@ import (
 	"fmt"
 	"sort"
 	"strings"
-)
 
-var supportedDeps = map[string]bool{
-	"postgres": true,
-	"redis":    true,
-}
+	"github.com/dever-labs/devx/internal/deps"
+)
 
 type ValidationError struct {
 	Issues []string
@@ -41,6 +38,18 @@ func Validate(m *Manifest) error {
 	if len(m.Profiles) == 0 {
 		issues = append(issues, "profiles are required")
 	}
+	issues = append(issues, m.interpolationIssues[""]...)
+	for name, secret := range m.Secrets {
+		sources := 0
+		for _, source := range []string{secret.File, secret.Env, secret.Command} {
+			if source != "" {
+				sources++
+			}
+		}
+		if sources != 1 {
+			issues = append(issues, fmt.Sprintf("secret '%s' must set exactly one of file, env or command", name))
+		}
+	}
 	if len(issues) > 0 {
 		return &ValidationError{Issues: issues}
 	}
@@ -56,7 +65,7 @@ func ValidateProfile(m *Manifest, profile string) error {
 		return &ValidationError{Issues: []string{"profile does not exist"}}
 	}
 
-	var issues []string
+	issues := append([]string{}, m.interpolationIssues[profile]...)
 	if prof.Runtime != "" && prof.Runtime != "compose" && prof.Runtime != "k8s" {
 		issues = append(issues, fmt.Sprintf("profile '%s' runtime must be compose or k8s", profile))
 	}
@@ -64,18 +73,33 @@ func ValidateProfile(m *Manifest, profile string) error {
 		if svc.Image == "" && svc.Build == nil {
 			issues = append(issues, fmt.Sprintf("service '%s' must define image or build", name))
 		}
-		for _, dep := range svc.DependsOn {
-			if !existsServiceOrDep(prof, dep) {
-				issues = append(issues, fmt.Sprintf("service '%s' dependsOn '%s' which does not exist", name, dep))
+		issues = append(issues, dependsOnIssues(prof, name, svc)...)
+		issues = append(issues, secretRefIssues(m, "service", name, svc.SecretEnv)...)
+		issues = append(issues, healthIssues(name, svc.Health)...)
+		issues = append(issues, watchIssues(name, svc)...)
+		issues = append(issues, exposeIssues(name, svc)...)
+		for _, spec := range svc.Volumes {
+			if _, err := ParseVolume(spec); err != nil {
+				issues = append(issues, fmt.Sprintf("service '%s' volume '%s' %v", name, spec, err))
 			}
 		}
+		issues = append(issues, storageIssues(fmt.Sprintf("service '%s'", name), svc.Storage)...)
+		issues = append(issues, resourcesIssues(fmt.Sprintf("service '%s'", name), svc.Resources)...)
+		if svc.Replicas < 0 {
+			issues = append(issues, fmt.Sprintf("service '%s' replicas must not be negative", name))
+		}
 	}
 
 	for name, dep := range prof.Deps {
+		issues = append(issues, secretRefIssues(m, "dep", name, dep.SecretEnv)...)
+		issues = append(issues, storageIssues(fmt.Sprintf("dep '%s'", name), dep.Storage)...)
+		issues = append(issues, resourcesIssues(fmt.Sprintf("dep '%s'", name), dep.Resources)...)
 		if dep.Kind == "" {
 			issues = append(issues, fmt.Sprintf("dep '%s' must define kind", name))
-		} else if !supportedDeps[dep.Kind] {
-			issues = append(issues, fmt.Sprintf("dep '%s' kind '%s' is not supported", name, dep.Kind))
+		} else if kind, ok := deps.Lookup(dep.Kind); !ok {
+			issues = append(issues, fmt.Sprintf("dep '%s' kind '%s' is not supported (supported: %s)", name, dep.Kind, strings.Join(deps.Names(), ", ")))
+		} else if _, err := kind.VolumeSpec(dep.Volume); err != nil {
+			issues = append(issues, fmt.Sprintf("dep '%s' volume: %v", name, err))
 		}
 	}
 
@@ -103,6 +127,16 @@ func ValidateProfile(m *Manifest, profile string) error {
 	return nil
 }
 
+func secretRefIssues(m *Manifest, kind string, name string, refs map[string]string) []string {
+	var issues []string
+	for envName, secret := range refs {
+		if _, ok := m.Secrets[secret]; !ok {
+			issues = append(issues, fmt.Sprintf("%s '%s' env %s references secret '%s' which does not exist", kind, name, envName, secret))
+		}
+	}
+	return issues
+}
+
 func existsServiceOrDep(prof Profile, name string) bool {
 	if _, ok := prof.Services[name]; ok {
 		return true
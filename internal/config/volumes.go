@@ -0,0 +1,83 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Storage configures the PersistentVolumeClaims rendered for k8s. Empty
+// fields fall back to the cluster's default storage class and 1Gi.
+type Storage struct {
+	Class string `yaml:"class,omitempty"`
+	Size  string `yaml:"size,omitempty"`
+}
+
+// VolumeMount is a parsed named-volume entry of a service.
+type VolumeMount struct {
+	Name     string
+	Path     string
+	ReadOnly bool
+}
+
+// quantityPattern matches Kubernetes storage quantities such as 500Mi or 10Gi.
+var quantityPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(Ki|Mi|Gi|Ti|Pi|Ei|k|M|G|T|P|E)?$`)
+
+// ParseVolume parses a "name:/path[:ro|rw]" named-volume spec. Host paths are
+// rejected; bind mounts belong in `mount`.
+func ParseVolume(spec string) (VolumeMount, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || !strings.HasPrefix(parts[1], "/") {
+		return VolumeMount{}, fmt.Errorf("must be in name:/path[:ro] format")
+	}
+	if strings.ContainsAny(parts[0], `/\`) || strings.HasPrefix(parts[0], ".") || strings.HasPrefix(parts[0], "~") {
+		return VolumeMount{}, fmt.Errorf("'%s' is a path; use mount for bind mounts", parts[0])
+	}
+	vol := VolumeMount{Name: parts[0], Path: parts[1]}
+	if len(parts) == 3 {
+		switch parts[2] {
+		case "ro":
+			vol.ReadOnly = true
+		case "rw":
+		default:
+			return VolumeMount{}, fmt.Errorf("mode must be ro or rw")
+		}
+	}
+	return vol, nil
+}
+
+func storageIssues(owner string, storage Storage) []string {
+	if storage.Size == "" || quantityPattern.MatchString(storage.Size) {
+		return nil
+	}
+	return []string{fmt.Sprintf("%s storage.size must be a quantity like 10Gi", owner)}
+}
+
+// Mount is a parsed bind-mount entry of a service.
+type Mount struct {
+	Source   string
+	Target   string
+	ReadOnly bool
+}
+
+// ParseMount parses a "hostPath:containerPath[:options]" bind-mount spec,
+// where options is a comma-separated list such as "ro" or "ro,z". A Windows
+// drive letter in hostPath is kept.
+func ParseMount(spec string) (Mount, error) {
+	parts := strings.Split(spec, ":")
+	if len(parts) > 2 && len(parts[0]) == 1 && strings.HasPrefix(parts[1], `\`) {
+		parts = append([]string{parts[0] + ":" + parts[1]}, parts[2:]...)
+	}
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || !strings.HasPrefix(parts[1], "/") {
+		return Mount{}, fmt.Errorf("must be in hostPath:containerPath[:options] format")
+	}
+	mount := Mount{Source: parts[0], Target: parts[1]}
+	if len(parts) == 3 {
+		for _, opt := range strings.Split(parts[2], ",") {
+			if opt == "ro" {
+				mount.ReadOnly = true
+			}
+		}
+	}
+	return mount, nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import "testing"
+
+func TestParseVolume(t *testing.T) {
+	vol, err := ParseVolume("uploads:/data/uploads:ro")
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if vol.Name != "uploads" || vol.Path != "/data/uploads" || !vol.ReadOnly {
+		t.Fatalf("unexpected volume %+v", vol)
+	}
+
+	for _, spec := range []string{"uploads", "./data:/data", "uploads:data", "uploads:/data:rx", ":/data"} {
+		if _, err := ParseVolume(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
+
+func TestValidateProfileStorage(t *testing.T) {
+	m := &Manifest{
+		Version: 1,
+		Project: Project{Name: "demo", DefaultProfile: "local"},
+		Profiles: map[string]Profile{
+			"local": {
+				Services: map[string]Service{
+					"api": {Image: "api", Volumes: []string{"uploads:/uploads"}, Storage: Storage{Size: "500Mi"}},
+				},
+				Deps: map[string]Dep{
+					"db": {Kind: "postgres", Volume: "db-data", Storage: Storage{Class: "standard", Size: "10Gi"}},
+				},
+			},
+		},
+	}
+	if err := ValidateProfile(m, "local"); err != nil {
+		t.Fatalf("expected valid storage, got %v", err)
+	}
+
+	m.Profiles["local"].Deps["db"] = Dep{Kind: "postgres", Volume: "db-data", Storage: Storage{Size: "ten gigs"}}
+	if err := ValidateProfile(m, "local"); err == nil {
+		t.Errorf("expected error for invalid storage size")
+	}
+}
+
+func TestParseMount(t *testing.T) {
+	cases := map[string]Mount{
+		"./config:/etc/app":          {Source: "./config", Target: "/etc/app"},
+		"./config:/etc/app:ro":       {Source: "./config", Target: "/etc/app", ReadOnly: true},
+		"nginx.conf:/etc/nginx:ro,z": {Source: "nginx.conf", Target: "/etc/nginx", ReadOnly: true},
+		`C:\config:/etc/app:ro`:      {Source: `C:\config`, Target: "/etc/app", ReadOnly: true},
+	}
+	for spec, want := range cases {
+		got, err := ParseMount(spec)
+		if err != nil {
+			t.Errorf("ParseMount(%q) failed: %v", spec, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("ParseMount(%q) = %+v, want %+v", spec, got, want)
+		}
+	}
+
+	for _, spec := range []string{"./config", "./config:etc/app", ":/etc/app"} {
+		if _, err := ParseMount(spec); err == nil {
+			t.Errorf("expected error for %q", spec)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Actions taken by `devx watch` when a watched file changes.
+const (
+	WatchRebuild = "rebuild"
+	WatchSync    = "sync"
+	WatchRestart = "restart"
+)
+
+// WatchRule tells `devx watch` how to react to changes below Path. A service
+// with a build context is also rebuilt on changes in that context that no
+// rule covers.
+type WatchRule struct {
+	// Path is a file or directory relative to devx.yaml.
+	Path   string `yaml:"path"`
+	Action string `yaml:"action"`
+	// Target is the directory in the container that Path is synced to.
+	Target string `yaml:"target,omitempty"`
+	// Include and Exclude are globs matched against paths relative to Path.
+	// `**` matches any number of directories; a pattern without `/` matches
+	// the file name at any depth.
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+}
+
+func watchIssues(service string, svc Service) []string {
+	var issues []string
+	for i, rule := range svc.Watch {
+		prefix := fmt.Sprintf("service '%s' watch[%d]", service, i)
+		if rule.Path == "" {
+			issues = append(issues, prefix+" must define path")
+		}
+		switch rule.Action {
+		case WatchRebuild:
+			if svc.Build == nil {
+				issues = append(issues, prefix+" action rebuild requires build")
+			}
+		case WatchSync:
+			if !path.IsAbs(rule.Target) {
+				issues = append(issues, prefix+" action sync requires an absolute target")
+			}
+		case WatchRestart:
+		default:
+			issues = append(issues, prefix+" action must be rebuild, sync or restart")
+		}
+		for _, pattern := range append(append([]string{}, rule.Include...), rule.Exclude...) {
+			if !validGlob(pattern) {
+				issues = append(issues, fmt.Sprintf("%s has invalid glob %q", prefix, pattern))
+			}
+		}
+	}
+	return issues
+}
+
+func validGlob(pattern string) bool {
+	if pattern == "" {
+		return false
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,55 @@
+package config
+
+import "testing"
+
+func TestValidateProfileWatch(t *testing.T) {
+	data := []byte(`version: 1
+project:
+  name: demo
+  defaultProfile: local
+profiles:
+  local:
+    services:
+      api:
+        build:
+          context: ./api
+        watch:
+          - path: ./api/static
+            action: sync
+            target: /app/static
+            exclude: ["*.tmp", "**/node_modules/**"]
+          - path: ./api/config.yaml
+            action: restart
+`)
+
+	m, err := Parse(data)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if err := ValidateProfile(m, "local"); err != nil {
+		t.Fatalf("expected valid watch rules, got %v", err)
+	}
+	if rules := m.Profiles["local"].Services["api"].Watch; len(rules) != 2 || rules[0].Target != "/app/static" {
+		t.Fatalf("unexpected watch rules %+v", rules)
+	}
+
+	invalid := []WatchRule{
+		{Action: WatchRestart},
+		{Path: "./api", Action: "reload"},
+		{Path: "./api", Action: WatchSync},
+		{Path: "./api", Action: WatchRestart, Include: []string{"[a-"}},
+	}
+	for _, rule := range invalid {
+		api := m.Profiles["local"].Services["api"]
+		api.Watch = []WatchRule{rule}
+		m.Profiles["local"].Services["api"] = api
+		if err := ValidateProfile(m, "local"); err == nil {
+			t.Errorf("expected error for watch rule %+v", rule)
+		}
+	}
+
+	m.Profiles["local"].Services["api"] = Service{Image: "nginx", Watch: []WatchRule{{Path: "./api", Action: WatchRebuild}}}
+	if err := ValidateProfile(m, "local"); err == nil {
+		t.Errorf("expected error for rebuild without build")
+	}
+}
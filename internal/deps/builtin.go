@@ -0,0 +1,133 @@
+package deps
+
+func init() {
+	for _, kind := range builtin {
+		Register(kind)
+	}
+}
+
+var builtin = []Kind{
+	{
+		Name:             "postgres",
+		Image:            "postgres",
+		DefaultPort:      5432,
+		Env:              map[string]string{"POSTGRES_PASSWORD": "postgres"},
+		DataPath:         "/var/lib/postgresql/data",
+		Readiness:        `pg_isready -U "${POSTGRES_USER:-postgres}"`,
+		ConnectionString: `postgres://{{or .Env.POSTGRES_USER "postgres"}}:{{.Env.POSTGRES_PASSWORD}}@{{.Host}}:{{.Port}}/{{or .Env.POSTGRES_DB .Env.POSTGRES_USER "postgres"}}`,
+	},
+	{
+		Name:             "redis",
+		Image:            "redis",
+		DefaultPort:      6379,
+		DataPath:         "/data",
+		Readiness:        "redis-cli ping",
+		ConnectionString: "redis://{{.Host}}:{{.Port}}",
+	},
+	{
+		Name:             "mysql",
+		Image:            "mysql",
+		DefaultPort:      3306,
+		Env:              map[string]string{"MYSQL_ROOT_PASSWORD": "mysql"},
+		DataPath:         "/var/lib/mysql",
+		Readiness:        "mysqladmin ping -h 127.0.0.1 --silent",
+		ConnectionString: `mysql://root:{{.Env.MYSQL_ROOT_PASSWORD}}@{{.Host}}:{{.Port}}/{{.Env.MYSQL_DATABASE}}`,
+	},
+	{
+		Name:             "mariadb",
+		Image:            "mariadb",
+		DefaultPort:      3306,
+		Env:              map[string]string{"MARIADB_ROOT_PASSWORD": "mariadb"},
+		DataPath:         "/var/lib/mysql",
+		Readiness:        "healthcheck.sh --connect --innodb_initialized",
+		ConnectionString: `mysql://root:{{.Env.MARIADB_ROOT_PASSWORD}}@{{.Host}}:{{.Port}}/{{.Env.MARIADB_DATABASE}}`,
+	},
+	{
+		Name:             "mongodb",
+		Image:            "mongo",
+		DefaultPort:      27017,
+		DataPath:         "/data/db",
+		Readiness:        `mongosh --quiet --eval "db.adminCommand('ping')"`,
+		ConnectionString: `mongodb://{{with .Env.MONGO_INITDB_ROOT_USERNAME}}{{.}}:{{$.Env.MONGO_INITDB_ROOT_PASSWORD}}@{{end}}{{.Host}}:{{.Port}}`,
+	},
+	{
+		Name:             "rabbitmq",
+		Image:            "rabbitmq",
+		DefaultPort:      5672,
+		DataPath:         "/var/lib/rabbitmq",
+		Readiness:        "rabbitmq-diagnostics -q ping",
+		ConnectionString: `amqp://{{or .Env.RABBITMQ_DEFAULT_USER "guest"}}:{{or .Env.RABBITMQ_DEFAULT_PASS "guest"}}@{{.Host}}:{{.Port}}`,
+	},
+	{
+		// A single-node KRaft broker. Containers connect to <dep>:9092; the
+		// broker advertises localhost:9094 to host-side clients, so publish
+		// 9094 to reach it from the host. apache/kafka ignores its default
+		// config once any KAFKA_ variable is set, hence the full set.
+		Name:        "kafka",
+		Image:       "apache/kafka",
+		DefaultPort: 9092,
+		HostPort:    9094,
+		Env: map[string]string{
+			"KAFKA_NODE_ID":                                  "1",
+			"KAFKA_PROCESS_ROLES":                            "broker,controller",
+			"KAFKA_LISTENERS":                                "PLAINTEXT://:9092,CONTROLLER://:9093,HOST://:9094",
+			"KAFKA_ADVERTISED_LISTENERS":                     "PLAINTEXT://{{.Host}}:9092,HOST://localhost:9094",
+			"KAFKA_LISTENER_SECURITY_PROTOCOL_MAP":           "PLAINTEXT:PLAINTEXT,CONTROLLER:PLAINTEXT,HOST:PLAINTEXT",
+			"KAFKA_CONTROLLER_LISTENER_NAMES":                "CONTROLLER",
+			"KAFKA_CONTROLLER_QUORUM_VOTERS":                 "1@localhost:9093",
+			"KAFKA_INTER_BROKER_LISTENER_NAME":               "PLAINTEXT",
+			"KAFKA_OFFSETS_TOPIC_REPLICATION_FACTOR":         "1",
+			"KAFKA_TRANSACTION_STATE_LOG_REPLICATION_FACTOR": "1",
+			"KAFKA_TRANSACTION_STATE_LOG_MIN_ISR":            "1",
+			"KAFKA_GROUP_INITIAL_REBALANCE_DELAY_MS":         "0",
+			"KAFKA_LOG_DIRS":                                 "/var/lib/kafka/data",
+		},
+		DataPath:         "/var/lib/kafka/data",
+		Readiness:        "/opt/kafka/bin/kafka-broker-api-versions.sh --bootstrap-server localhost:9094",
+		ConnectionString: "{{.Host}}:{{.Port}}",
+	},
+	{
+		Name:        "minio",
+		Image:       "minio/minio",
+		DefaultPort: 9000,
+		Env: map[string]string{
+			"MINIO_ROOT_USER":     "minioadmin",
+			"MINIO_ROOT_PASSWORD": "minioadmin",
+		},
+		Command:          []string{"server", "/data", "--console-address", ":9001"},
+		DataPath:         "/data",
+		Readiness:        "mc ready local",
+		ConnectionString: "http://{{.Host}}:{{.Port}}",
+	},
+	{
+		// The official image publishes no latest tag.
+		Name:           "elasticsearch",
+		Image:          "elasticsearch",
+		DefaultVersion: "8.13.4",
+		DefaultPort:    9200,
+		Env: map[string]string{
+			"discovery.type":         "single-node",
+			"xpack.security.enabled": "false",
+			"ES_JAVA_OPTS":           "-Xms512m -Xmx512m",
+		},
+		DataPath:         "/usr/share/elasticsearch/data",
+		Readiness:        "curl -fs http://localhost:9200/_cluster/health",
+		ConnectionString: "http://{{.Host}}:{{.Port}}",
+	},
+	{
+		// MailHog accepts SMTP on 1025 and serves its web UI on 8025.
+		Name:             "mailhog",
+		Image:            "mailhog/mailhog",
+		DefaultPort:      1025,
+		Readiness:        "wget -q -O /dev/null http://localhost:8025",
+		ConnectionString: "smtp://{{.Host}}:{{.Port}}",
+	},
+	{
+		Name:             "localstack",
+		Image:            "localstack/localstack",
+		DefaultPort:      4566,
+		DataPath:         "/var/lib/localstack",
+		Readiness:        "curl -fs http://localhost:4566/_localstack/health",
+		ConnectionString: "http://{{.Host}}:{{.Port}}",
+	},
+}
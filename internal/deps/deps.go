@@ -0,0 +1,148 @@
+// Package deps is the registry of dependency kinds (databases, caches, brokers)
+// that can be declared under a profile's `deps` section.
+package deps
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Kind describes how to run one kind of dependency.
+type Kind struct {
+	Name string
+	// Image is the image repository; the dep's version is used as its tag.
+	Image string
+	// DefaultVersion is the tag used when a dep sets no version, for images
+	// that publish no latest tag.
+	DefaultVersion string
+	// DefaultPort is the port the dependency listens on inside its container.
+	DefaultPort int
+	// HostPort is the container port host-side clients connect to, when it
+	// differs from DefaultPort.
+	HostPort int
+	// Env holds default environment variables; a dep's env overrides them.
+	// Values are text/templates rendered with .Host, the name other
+	// containers reach the dep by.
+	Env map[string]string
+	// Command overrides the image's default command when set.
+	Command []string
+	// DataPath is where the dependency keeps its data. A bare volume name on
+	// a dep is mounted here.
+	DataPath string
+	// Readiness is a shell command run inside the container that exits 0
+	// once the dependency accepts connections.
+	Readiness string
+	// ConnectionString is a text/template rendered with .Host, .Port and .Env.
+	ConnectionString string
+}
+
+var registry = map[string]Kind{}
+
+// Register adds or replaces a dependency kind.
+func Register(kind Kind) {
+	registry[kind.Name] = kind
+}
+
+// Lookup returns the registered kind with the given name.
+func Lookup(name string) (Kind, bool) {
+	kind, ok := registry[name]
+	return kind, ok
+}
+
+// Names returns the names of all registered kinds in sorted order.
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ImageRef returns the image reference for the given version.
+func (k Kind) ImageRef(version string) string {
+	if version == "" {
+		version = k.DefaultVersion
+	}
+	if version == "" {
+		return k.Image
+	}
+	return k.Image + ":" + version
+}
+
+// MergeEnv returns the kind's default env, rendered for a dep reachable at
+// host, overlaid with overrides.
+func (k Kind) MergeEnv(host string, overrides map[string]string) map[string]string {
+	if len(k.Env) == 0 && len(overrides) == 0 {
+		return nil
+	}
+	env := make(map[string]string, len(k.Env)+len(overrides))
+	for key, value := range k.Env {
+		env[key] = expandEnv(value, host)
+	}
+	for key, value := range overrides {
+		env[key] = value
+	}
+	return env
+}
+
+// expandEnv renders a default env value. Defaults are built in, so a value
+// that is not a valid template is used as is.
+func expandEnv(value string, host string) string {
+	if !strings.Contains(value, "{{") {
+		return value
+	}
+	tmpl, err := template.New("env").Parse(value)
+	if err != nil {
+		return value
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, struct{ Host string }{host}); err != nil {
+		return value
+	}
+	return buf.String()
+}
+
+// VolumeSpec expands a dep volume into "name:/path" form. A bare volume name
+// is mounted at the kind's DataPath.
+func (k Kind) VolumeSpec(volume string) (string, error) {
+	if volume == "" || strings.Contains(volume, ":") {
+		return volume, nil
+	}
+	if k.DataPath == "" {
+		return "", fmt.Errorf("kind '%s' has no default data path; use name:/path", k.Name)
+	}
+	return volume + ":" + k.DataPath, nil
+}
+
+// ConnectionInfo is the data passed to a kind's ConnectionString template.
+type ConnectionInfo struct {
+	Host string
+	Port int
+	Env  map[string]string
+}
+
+// Connection renders the kind's connection string for the given endpoint.
+// The env is merged with the kind's defaults before rendering; env variables
+// that are not set render empty.
+func (k Kind) Connection(host string, port int, env map[string]string) (string, error) {
+	if k.ConnectionString == "" {
+		return "", nil
+	}
+	tmpl, err := template.New(k.Name).Option("missingkey=zero").Parse(k.ConnectionString)
+	if err != nil {
+		return "", err
+	}
+	merged := k.MergeEnv(host, env)
+	if merged == nil {
+		merged = map[string]string{}
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, ConnectionInfo{Host: host, Port: port, Env: merged}); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
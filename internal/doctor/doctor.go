@@ -3,9 +3,11 @@ package doctor
 import (
 	"context"
 	"fmt"
+	"io"
 	"net"
 	"os"
 	"os/exec"
+	"path/filepath"
 	goruntime "runtime"
 	"sort"
 	"strings"
@@ -20,16 +22,49 @@ import (
 type Options struct {
 	Manifest *config.Manifest
 	Fix      bool
+
+	// Confirm approves each fix when Fix is set; nil applies all of them.
+	Confirm func(fix string) bool
+	// Out receives the progress of fixes.
+	Out io.Writer
+	// Dir is the project directory; empty means the working directory.
+	Dir string
+	// ManifestPath is the manifest that host port fixes edit.
+	ManifestPath string
+	// Profile is the active profile, whose published ports are checked for
+	// conflicts with other processes unless its stack is Running.
+	Profile     *config.Profile
+	ProfileName string
+	Running     bool
+	// VerifyLock and UpdateLock check devx.lock against the manifest and
+	// regenerate it. The lockfile check needs both and an existing devx.lock.
+	VerifyLock func(ctx context.Context) error
+	UpdateLock func(ctx context.Context) error
 }
 
+// Check is the result of one check. Status is PASS, WARN or FAIL; only FAIL
+// makes the report fail.
 type Check struct {
-	Name   string
-	Status string
-	Detail string
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Detail string `json:"detail"`
+	// Fix, when set, repairs what the check reports.
+	Fix *Fix `json:"fix,omitempty"`
 }
 
 type Report struct {
-	Checks []Check
+	Checks []Check `json:"checks"`
+}
+
+// Fixable returns the checks that did not pass and can be fixed.
+func (r Report) Fixable() []Check {
+	var out []Check
+	for _, c := range r.Checks {
+		if c.Fix != nil && c.Status != "PASS" {
+			out = append(out, c)
+		}
+	}
+	return out
 }
 
 func (r Report) HasFailures() bool {
@@ -41,7 +76,17 @@ func (r Report) HasFailures() bool {
 	return false
 }
 
+// Run checks the machine and project. With opts.Fix it applies the fixes of
+// the checks that did not pass and checks again.
 func Run(ctx context.Context, opts Options) Report {
+	checks := runChecks(ctx, opts)
+	if opts.Fix && applyFixes(ctx, checks, opts) {
+		checks = runChecks(ctx, opts)
+	}
+	return Report{Checks: checks}
+}
+
+func runChecks(ctx context.Context, opts Options) []Check {
 	checks := []Check{}
 
 	checks = append(checks, Check{
@@ -50,7 +95,7 @@ func Run(ctx context.Context, opts Options) Report {
 		Detail: fmt.Sprintf("devx (dev) on %s/%s", goruntime.GOOS, goruntime.GOARCH),
 	})
 
-	runtimeChecks := detectAllRuntimes(ctx)
+	runtimeChecks := detectRuntimes(ctx)
 	for _, info := range runtimeChecks {
 		status := "FAIL"
 		if info.Available {
@@ -60,11 +105,15 @@ func Run(ctx context.Context, opts Options) Report {
 		if detail == "" {
 			detail = info.Name
 		}
-		checks = append(checks, Check{
+		check := Check{
 			Name:   fmt.Sprintf("Runtime: %s", info.Name),
 			Status: status,
 			Detail: detail,
-		})
+		}
+		if !info.Available && info.Name == "podman" {
+			check.Fix = podmanFix(opts.Out)
+		}
+		checks = append(checks, check)
 
 		if info.Available {
 			checks = append(checks, detectCompose(ctx, info.Name))
@@ -79,21 +128,36 @@ func Run(ctx context.Context, opts Options) Report {
 		if requiresK8s(opts.Manifest) {
 			checks = append(checks, checkKubectl())
 		}
+		checks = append(checks, checkGitignore(opts.Dir))
+		checks = append(checks, checkBuildContexts(opts.Manifest, opts.Dir)...)
+		if opts.VerifyLock != nil && opts.UpdateLock != nil && fileExists(filepath.Join(opts.Dir, "devx.lock")) {
+			checks = append(checks, checkLock(ctx, opts))
+		}
+		if opts.Profile != nil && opts.Profile.Runtime != "k8s" && !opts.Running {
+			checks = append(checks, checkHostPorts(opts.Profile, opts.ProfileName, opts.ManifestPath))
+		}
 	}
 
 	sort.SliceStable(checks, func(i, j int) bool {
 		return checks[i].Name < checks[j].Name
 	})
 
-	return Report{Checks: checks}
+	return checks
 }
 
-func PrintReport(out *os.File, report Report) {
+func PrintReport(out io.Writer, report Report) {
 	for _, check := range report.Checks {
 		fmt.Fprintf(out, "%s\t%s\t%s\n", check.Status, check.Name, check.Detail)
+		if check.Fix != nil && check.Status != "PASS" {
+			fmt.Fprintf(out, "\t\tfix: %s\n", check.Fix.Description)
+		}
 	}
 }
 
+// detectRuntimes is replaced in tests, which must not depend on or start the
+// container runtimes of the machine.
+var detectRuntimes = detectAllRuntimes
+
 func detectAllRuntimes(ctx context.Context) []runtime.RuntimeInfo {
 	var infos []runtime.RuntimeInfo
 	for _, rt := range []runtime.Runtime{docker.New(), podman.New()} {
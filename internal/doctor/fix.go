@@ -0,0 +1,488 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+
+	"github.com/dever-labs/devx/internal/config"
+	"github.com/dever-labs/devx/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Fix repairs the problem a check reports.
+type Fix struct {
+	// Description says what Apply does, e.g. for a confirmation prompt.
+	Description string                          `json:"description"`
+	Apply       func(ctx context.Context) error `json:"-"`
+}
+
+const gitignoreEntry = ".devx/"
+
+// dockerfileStub is written for build contexts that have no Dockerfile yet.
+const dockerfileStub = `# Generated by devx doctor --fix. Replace it with the build of this service.
+FROM alpine:3.19
+CMD ["sh", "-c", "echo 'replace this Dockerfile' && sleep infinity"]
+`
+
+// applyFixes offers the fix of every check that did not pass and applies the
+// ones confirm approves, or all of them when confirm is nil. It reports
+// whether any fix was applied.
+func applyFixes(ctx context.Context, checks []Check, opts Options) bool {
+	out := opts.Out
+	if out == nil {
+		out = io.Discard
+	}
+	applied := false
+	for _, check := range checks {
+		if check.Fix == nil || check.Status == "PASS" {
+			continue
+		}
+		if opts.Confirm != nil && !opts.Confirm(fmt.Sprintf("%s: %s", check.Name, check.Fix.Description)) {
+			fmt.Fprintf(out, "Skipped %s\n", check.Name)
+			continue
+		}
+		fmt.Fprintf(out, "Fixing %s: %s\n", check.Name, check.Fix.Description)
+		if err := check.Fix.Apply(ctx); err != nil {
+			fmt.Fprintf(out, "  failed: %v\n", err)
+			continue
+		}
+		applied = true
+	}
+	return applied
+}
+
+// EnsureGitignore adds .devx/ to the .gitignore of dir, creating the file if
+// needed.
+func EnsureGitignore(dir string) error {
+	path := filepath.Join(dir, ".gitignore")
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return os.WriteFile(path, []byte(gitignoreEntry+"\n"), 0644)
+	}
+	if err != nil {
+		return err
+	}
+	if strings.Contains(string(data), gitignoreEntry) {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = f.WriteString("\n" + gitignoreEntry + "\n")
+	return err
+}
+
+func checkGitignore(dir string) Check {
+	data, err := os.ReadFile(filepath.Join(dir, ".gitignore"))
+	if err == nil && strings.Contains(string(data), gitignoreEntry) {
+		return Check{Name: "Gitignore", Status: "PASS", Detail: ".devx/ is ignored"}
+	}
+	return Check{
+		Name:   "Gitignore",
+		Status: "WARN",
+		Detail: ".devx/ is not in .gitignore",
+		Fix: &Fix{
+			Description: "add .devx/ to .gitignore",
+			Apply:       func(ctx context.Context) error { return EnsureGitignore(dir) },
+		},
+	}
+}
+
+func checkLock(ctx context.Context, opts Options) Check {
+	if err := opts.VerifyLock(ctx); err != nil {
+		detail, _, _ := strings.Cut(err.Error(), "\n")
+		return Check{
+			Name:   "Lockfile",
+			Status: "WARN",
+			Detail: strings.TrimSuffix(detail, ":"),
+			Fix:    &Fix{Description: "run devx lock update", Apply: opts.UpdateLock},
+		}
+	}
+	return Check{Name: "Lockfile", Status: "PASS", Detail: "devx.lock is up to date"}
+}
+
+// checkBuildContexts reports build-based services whose Dockerfile is
+// missing.
+func checkBuildContexts(manifest *config.Manifest, dir string) []Check {
+	seen := map[string]bool{}
+	var checks []Check
+	for _, profName := range util.SortedKeys(manifest.Profiles) {
+		prof := manifest.Profiles[profName]
+		for _, name := range util.SortedKeys(prof.Services) {
+			build := prof.Services[name].Build
+			if build == nil {
+				continue
+			}
+			path := dockerfilePath(dir, build)
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+
+			check := Check{Name: fmt.Sprintf("Build: %s", name), Status: "PASS", Detail: "Dockerfile found"}
+			if !fileExists(path) {
+				check.Status = "FAIL"
+				check.Detail = fmt.Sprintf("%s does not exist", path)
+				check.Fix = &Fix{
+					Description: fmt.Sprintf("create a stub %s", path),
+					Apply: func(ctx context.Context) error {
+						if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+							return err
+						}
+						return os.WriteFile(path, []byte(dockerfileStub), 0644)
+					},
+				}
+			}
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
+
+func dockerfilePath(dir string, build *config.Build) string {
+	dockerfile := build.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	if filepath.IsAbs(dockerfile) {
+		return dockerfile
+	}
+	buildDir := build.Context
+	if !filepath.IsAbs(buildDir) {
+		buildDir = filepath.Join(dir, buildDir)
+	}
+	return filepath.Join(buildDir, dockerfile)
+}
+
+// checkHostPorts reports host ports of the profile that another process on
+// this machine already listens on, and offers to move them to free ports in
+// the manifest.
+func checkHostPorts(prof *config.Profile, profileName string, manifestPath string) Check {
+	type binding struct{ owner, ip, port string }
+	var bindings []binding
+	taken := map[string]bool{}
+	add := func(owner string, specs []string) {
+		for _, spec := range specs {
+			ip, port, ok := hostPort(spec)
+			if ok {
+				bindings = append(bindings, binding{owner, ip, port})
+				taken[port] = true
+			}
+		}
+	}
+	for _, name := range util.SortedKeys(prof.Services) {
+		add(name, prof.Services[name].Ports)
+	}
+	for _, name := range util.SortedKeys(prof.Deps) {
+		add(name, prof.Deps[name].Ports)
+	}
+
+	var busy []string
+	moves := map[string]string{}
+	for _, b := range bindings {
+		if portFree(b.ip, b.port) || moves[b.port] != "" {
+			continue
+		}
+		busy = append(busy, fmt.Sprintf("port %s (%s) is in use", b.port, b.owner))
+		if free := freePort(b.ip, b.port, taken); free != "" {
+			moves[b.port] = free
+			taken[free] = true
+		}
+	}
+	if len(busy) == 0 {
+		return Check{Name: "Host ports", Status: "PASS", Detail: "published ports are free"}
+	}
+
+	check := Check{Name: "Host ports", Status: "WARN", Detail: strings.Join(busy, "; ")}
+	if len(moves) > 0 {
+		var descriptions []string
+		for _, old := range util.SortedKeys(moves) {
+			descriptions = append(descriptions, fmt.Sprintf("%s to %s", old, moves[old]))
+		}
+		check.Fix = &Fix{
+			Description: fmt.Sprintf("move host port %s in %s", strings.Join(descriptions, ", "), filepath.Base(manifestPath)),
+			Apply: func(ctx context.Context) error {
+				for _, old := range util.SortedKeys(moves) {
+					if err := RewriteHostPort(manifestPath, profileName, old, moves[old]); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		}
+	}
+	return check
+}
+
+// hostPort returns the host IP and port of a published port spec such as
+// "8080:80", "127.0.0.1:8080:80" or "8080:80/tcp". Specs without a host
+// port are not published on a fixed port.
+func hostPort(spec string) (string, string, bool) {
+	spec, _, _ = strings.Cut(spec, "/")
+	parts := strings.Split(spec, ":")
+	switch len(parts) {
+	case 2:
+		return "", parts[0], parts[0] != ""
+	case 3:
+		return parts[0], parts[1], parts[1] != ""
+	default:
+		return "", "", false
+	}
+}
+
+func portFree(ip string, port string) bool {
+	ln, err := net.Listen("tcp", net.JoinHostPort(ip, port))
+	if err != nil {
+		return false
+	}
+	ln.Close()
+	return true
+}
+
+// freePort returns the first free port after port that the manifest does not
+// already publish, or "" if none of the next hundred is.
+func freePort(ip string, port string, taken map[string]bool) string {
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return ""
+	}
+	for candidate := n + 1; candidate <= n+100 && candidate < 65536; candidate++ {
+		p := strconv.Itoa(candidate)
+		if !taken[p] && portFree(ip, p) {
+			return p
+		}
+	}
+	return ""
+}
+
+// RewriteHostPort replaces host port oldPort with newPort in the `ports`
+// entries of the services and deps of profile, editing the files that define
+// them in place so that their formatting and comments are kept. Those are the
+// manifest at path or one it includes, under the profile or a profile it
+// extends.
+func RewriteHostPort(path string, profile string, oldPort string, newPort string) error {
+	files, err := manifestFiles(path, map[string]bool{})
+	if err != nil {
+		return err
+	}
+
+	var found []portEntry
+	for _, list := range portLists(files, profile) {
+		for _, item := range list.node.Content {
+			if _, port, ok := hostPort(item.Value); ok && port == oldPort && item.Kind == yaml.ScalarNode {
+				found = append(found, portEntry{file: list.file, item: item})
+			}
+		}
+	}
+	if len(found) == 0 {
+		return fmt.Errorf("host port %s is not set in profile '%s' of %s", oldPort, profile, path)
+	}
+
+	for _, f := range files {
+		lines := strings.SplitAfter(string(f.data), "\n")
+		edited := false
+		for _, entry := range found {
+			if entry.file != f {
+				continue
+			}
+			line := lines[entry.item.Line-1]
+			start := entry.item.Column - 1 + hostPortOffset(entry.item.Value)
+			if entry.item.Style == yaml.DoubleQuotedStyle || entry.item.Style == yaml.SingleQuotedStyle {
+				start++
+			}
+			if start+len(oldPort) > len(line) || line[start:start+len(oldPort)] != oldPort {
+				return fmt.Errorf("%s:%d: cannot locate port %s", f.path, entry.item.Line, oldPort)
+			}
+			lines[entry.item.Line-1] = line[:start] + newPort + line[start+len(oldPort):]
+			edited = true
+		}
+		if edited {
+			if err := os.WriteFile(f.path, []byte(strings.Join(lines, "")), 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// manifestFile is a manifest or an included file, parsed as YAML nodes.
+type manifestFile struct {
+	path string
+	data []byte
+	root *yaml.Node
+}
+
+type portEntry struct {
+	file *manifestFile
+	item *yaml.Node
+}
+
+type portList struct {
+	file *manifestFile
+	node *yaml.Node
+}
+
+// manifestFiles returns the manifest at path and the files it includes, in
+// the order their settings take precedence: a file before its includes, and
+// later includes before earlier ones.
+func manifestFiles(path string, seen map[string]bool) ([]*manifestFile, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	if seen[abs] {
+		return nil, nil
+	}
+	seen[abs] = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &manifestFile{path: path, data: data, root: &yaml.Node{}}
+	if err := yaml.Unmarshal(data, f.root); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	files := []*manifestFile{f}
+
+	includes := lookup(f.root, "include")
+	if includes == nil || includes.Kind != yaml.SequenceNode {
+		return files, nil
+	}
+	for i := len(includes.Content) - 1; i >= 0; i-- {
+		include := includes.Content[i].Value
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		included, err := manifestFiles(include, seen)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, included...)
+	}
+	return files, nil
+}
+
+// portLists returns the `ports` list that takes effect for every service and
+// dep of profile: the first one files define under the profile, then under
+// the profiles it extends.
+func portLists(files []*manifestFile, profile string) map[string]portList {
+	lists := map[string]portList{}
+	visited := map[string]bool{}
+	for name := profile; name != "" && !visited[name]; {
+		visited[name] = true
+		extends := ""
+		for _, f := range files {
+			prof := lookup(f.root, "profiles", name)
+			if prof == nil {
+				continue
+			}
+			if e := lookup(prof, "extends"); e != nil && extends == "" {
+				extends = e.Value
+			}
+			for _, section := range []string{"services", "deps"} {
+				entries := lookup(prof, section)
+				if entries == nil || entries.Kind != yaml.MappingNode {
+					continue
+				}
+				for i := 0; i+1 < len(entries.Content); i += 2 {
+					key := section + "/" + entries.Content[i].Value
+					ports := lookup(entries.Content[i+1], "ports")
+					if _, done := lists[key]; done || ports == nil {
+						continue
+					}
+					lists[key] = portList{file: f, node: ports}
+				}
+			}
+		}
+		name = extends
+	}
+	return lists
+}
+
+// lookup follows keys through nested mappings from a document or mapping
+// node, returning nil when one is missing.
+func lookup(node *yaml.Node, keys ...string) *yaml.Node {
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		node = node.Content[0]
+	}
+	for _, key := range keys {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				next = node.Content[i+1]
+			}
+		}
+		if next == nil {
+			return nil
+		}
+		node = next
+	}
+	return node
+}
+
+// hostPortOffset returns where the host port starts in a port spec.
+func hostPortOffset(spec string) int {
+	if parts := strings.Split(spec, ":"); len(parts) == 3 {
+		return len(parts[0]) + 1
+	}
+	return 0
+}
+
+// podmanFix starts the podman machine on macOS and Windows, or the podman
+// API socket on Linux, when podman is installed but not answering. The
+// command's output goes to out with the rest of the fix progress.
+func podmanFix(out io.Writer) *Fix {
+	if _, err := exec.LookPath("podman"); err != nil {
+		return nil
+	}
+	if goruntime.GOOS == "linux" {
+		return &Fix{
+			Description: "start the podman socket (systemctl --user start podman.socket)",
+			Apply: func(ctx context.Context) error {
+				return runCommand(ctx, out, "systemctl", "--user", "start", "podman.socket")
+			},
+		}
+	}
+	return &Fix{
+		Description: "start the podman machine (podman machine start)",
+		Apply: func(ctx context.Context) error {
+			return runCommand(ctx, out, "podman", "machine", "start")
+		},
+	}
+}
+
+func runCommand(ctx context.Context, out io.Writer, name string, args ...string) error {
+	if out == nil {
+		out = io.Discard
+	}
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Stdout = out
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,234 @@
+package doctor
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+	"testing"
+
+	"github.com/dever-labs/devx/internal/config"
+	"github.com/dever-labs/devx/internal/runtime"
+)
+
+func TestRewriteHostPort(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devx.yaml")
+	manifest := `profiles:
+  local:
+    services:
+      api:
+        ports:
+          - "8080:80" # public API
+          - 127.0.0.1:9090:9090
+      web:
+        ports: ["8080:3000", "18080:80"]
+`
+	if err := os.WriteFile(path, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RewriteHostPort(path, "local", "8080", "8081"); err != nil {
+		t.Fatalf("rewrite failed: %v", err)
+	}
+	if err := RewriteHostPort(path, "local", "9090", "9091"); err != nil {
+		t.Fatalf("rewrite failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `profiles:
+  local:
+    services:
+      api:
+        ports:
+          - "8081:80" # public API
+          - 127.0.0.1:9091:9090
+      web:
+        ports: ["8081:3000", "18080:80"]
+`
+	if string(data) != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, data)
+	}
+
+	if err := RewriteHostPort(path, "local", "7000", "7001"); err == nil {
+		t.Error("expected an error for a port that is not in the manifest")
+	}
+}
+
+func TestRewriteHostPortScope(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "devx.yaml")
+	manifest := `include: [base.yaml]
+profiles:
+  local:
+    extends: shared
+    services:
+      api:
+        ports: ["10.0.0.80:80:80"]
+  staging:
+    services:
+      api:
+        ports: ["80:80", "5432:5432"]
+`
+	base := `profiles:
+  local:
+    deps:
+      db:
+        ports: ["5432:5432"]
+  shared:
+    services:
+      worker:
+        ports: ["80:8080"]
+      api:
+        ports: ["80:9999"]
+`
+	if err := os.WriteFile(path, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "base.yaml"), []byte(base), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RewriteHostPort(path, "local", "80", "8081"); err != nil {
+		t.Fatalf("rewrite failed: %v", err)
+	}
+	if err := RewriteHostPort(path, "local", "5432", "5433"); err != nil {
+		t.Fatalf("rewrite of an included port failed: %v", err)
+	}
+
+	got, _ := os.ReadFile(path)
+	// Only the host port field of the active profile changes; staging and
+	// the IP address keep their 80s.
+	wantManifest := strings.Replace(manifest, `"10.0.0.80:80:80"`, `"10.0.0.80:8081:80"`, 1)
+	if string(got) != wantManifest {
+		t.Errorf("expected\n%s\ngot\n%s", wantManifest, got)
+	}
+	got, _ = os.ReadFile(filepath.Join(dir, "base.yaml"))
+	// The worker port comes from the extended profile; the api entry there
+	// is overridden by local and stays.
+	wantBase := strings.Replace(strings.Replace(base, `"5432:5432"`, `"5433:5432"`, 1), `"80:8080"`, `"8081:8080"`, 1)
+	if string(got) != wantBase {
+		t.Errorf("expected\n%s\ngot\n%s", wantBase, got)
+	}
+}
+
+func TestCheckHostPorts(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	_, busy, _ := net.SplitHostPort(ln.Addr().String())
+
+	path := filepath.Join(t.TempDir(), "devx.yaml")
+	if err := os.WriteFile(path, []byte("profiles:\n  local:\n    services:\n      api:\n        ports:\n          - 127.0.0.1:"+busy+":80\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	prof := &config.Profile{Services: map[string]config.Service{
+		"api": {Ports: []string{"127.0.0.1:" + busy + ":80", "3000"}},
+	}}
+
+	check := checkHostPorts(prof, "local", path)
+	if check.Status != "WARN" || !strings.Contains(check.Detail, "port "+busy+" (api) is in use") || check.Fix == nil {
+		t.Fatalf("expected a fixable conflict, got %+v", check)
+	}
+	if err := check.Fix.Apply(context.Background()); err != nil {
+		t.Fatalf("fix failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), ":"+busy+":") {
+		t.Errorf("expected port %s to be moved, got %s", busy, data)
+	}
+}
+
+func TestRunFixes(t *testing.T) {
+	detectRuntimes = func(context.Context) []runtime.RuntimeInfo { return nil }
+	t.Cleanup(func() { detectRuntimes = detectAllRuntimes })
+
+	dir := t.TempDir()
+	manifest := &config.Manifest{Profiles: map[string]config.Profile{
+		"local": {Services: map[string]config.Service{
+			"api": {Build: &config.Build{Context: "src/api"}},
+		}},
+	}}
+	lockUpdated := false
+	opts := Options{
+		Manifest:   manifest,
+		Dir:        dir,
+		VerifyLock: func(ctx context.Context) error { return os.ErrNotExist },
+		UpdateLock: func(ctx context.Context) error { lockUpdated = true; return nil },
+	}
+	if err := os.WriteFile(filepath.Join(dir, "devx.lock"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	report := Run(context.Background(), opts)
+	if got := names(report.Fixable()); got != "Build: api,Gitignore,Lockfile" {
+		t.Fatalf("unexpected fixable checks %s", got)
+	}
+
+	// Declining every fix changes nothing.
+	opts.Fix = true
+	opts.Confirm = func(string) bool { return false }
+	Run(context.Background(), opts)
+	if fileExists(filepath.Join(dir, ".gitignore")) || lockUpdated {
+		t.Fatal("expected declined fixes not to be applied")
+	}
+
+	opts.Confirm = func(fix string) bool { return !strings.HasPrefix(fix, "Lockfile") }
+	report = Run(context.Background(), opts)
+	if got := names(report.Fixable()); got != "Lockfile" {
+		t.Errorf("expected only the declined lockfile fix to remain, got %s", got)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "src", "api", "Dockerfile"))
+	if err != nil || !strings.HasPrefix(string(data), "# Generated by devx doctor --fix") {
+		t.Errorf("expected a stub Dockerfile, got %q (%v)", data, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, ".gitignore")); string(data) != ".devx/\n" {
+		t.Errorf("unexpected .gitignore %q", data)
+	}
+
+	opts.Confirm = nil
+	Run(context.Background(), opts)
+	if !lockUpdated {
+		t.Error("expected the lockfile fix to run without confirmation")
+	}
+}
+
+func TestPodmanFixOutput(t *testing.T) {
+	if goruntime.GOOS == "windows" {
+		t.Skip("uses shell scripts as podman and systemctl")
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"podman", "systemctl"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\necho started $@\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// The command's output joins the fix progress, keeping stdout free for
+	// structured reports.
+	var out bytes.Buffer
+	if err := podmanFix(&out).Apply(context.Background()); err != nil {
+		t.Fatalf("fix failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "started ") {
+		t.Errorf("expected the command output in the progress writer, got %q", out.String())
+	}
+}
+
+func names(checks []Check) string {
+	var out []string
+	for _, c := range checks {
+		out = append(out, c.Name)
+	}
+	return strings.Join(out, ",")
+}
@@ -3,6 +3,7 @@ package graph
 import (
 	"container/heap"
 	"fmt"
+	"sort"
 
 	"github.com/dever-labs/devx/internal/config"
 )
@@ -11,6 +12,9 @@ type Node struct {
 	Name      string
 	Kind      string
 	DependsOn []string
+	// Conditions maps each dependency to the config.Condition* state it must
+	// reach before this node starts.
+	Conditions map[string]string
 }
 
 type Graph struct {
@@ -25,10 +29,19 @@ func Build(profile *config.Profile) (*Graph, error) {
 	}
 
 	for name, svc := range profile.Services {
+		conditions := make(map[string]string, len(svc.DependsOn))
+		for _, dep := range svc.DependsOn {
+			condition := dep.Condition
+			if condition == "" {
+				condition = config.ConditionStarted
+			}
+			conditions[dep.Name] = condition
+		}
 		nodes[name] = Node{
-			Name:      name,
-			Kind:      "service",
-			DependsOn: append([]string{}, svc.DependsOn...),
+			Name:       name,
+			Kind:       "service",
+			DependsOn:  svc.DependencyNames(),
+			Conditions: conditions,
 		}
 	}
 
@@ -46,6 +59,79 @@ func Build(profile *config.Profile) (*Graph, error) {
 	return &Graph{Nodes: nodes}, nil
 }
 
+// Closure returns the named nodes together with everything they transitively
+// depend on, sorted by name.
+func Closure(g *Graph, names []string) ([]string, error) {
+	seen := map[string]bool{}
+	var visit func(name string) error
+	visit = func(name string) error {
+		if seen[name] {
+			return nil
+		}
+		node, ok := g.Nodes[name]
+		if !ok {
+			return fmt.Errorf("unknown service '%s'", name)
+		}
+		seen[name] = true
+		for _, dep := range node.DependsOn {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+
+	out := make([]string, 0, len(seen))
+	for name := range seen {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// Dependents returns the nodes that transitively depend on any of the named
+// nodes, excluding the named nodes themselves, sorted by name.
+func Dependents(g *Graph, names []string) []string {
+	dependents := map[string][]string{}
+	for name, node := range g.Nodes {
+		for _, dep := range node.DependsOn {
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	named := map[string]bool{}
+	for _, name := range names {
+		named[name] = true
+	}
+	seen := map[string]bool{}
+	var visit func(name string)
+	visit = func(name string) {
+		for _, dependent := range dependents[name] {
+			if seen[dependent] || named[dependent] {
+				continue
+			}
+			seen[dependent] = true
+			visit(dependent)
+		}
+	}
+	for _, name := range names {
+		visit(name)
+	}
+
+	out := make([]string, 0, len(seen))
+	for name := range seen {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out
+}
+
 // stringHeap is a min-heap of strings for deterministic topological ordering.
 type stringHeap []string
 
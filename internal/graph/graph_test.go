@@ -1,6 +1,7 @@
 package graph
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/dever-labs/devx/internal/config"
@@ -9,7 +10,7 @@ import (
 func TestTopoSort(t *testing.T) {
 	prof := &config.Profile{
 		Services: map[string]config.Service{
-			"api": {DependsOn: []string{"db"}},
+			"api": {DependsOn: []config.Dependency{{Name: "db", Condition: config.ConditionHealthy}}},
 		},
 		Deps: map[string]config.Dep{
 			"db": {Kind: "postgres"},
@@ -29,13 +30,16 @@ func TestTopoSort(t *testing.T) {
 	if len(order) != 2 || order[0] != "db" {
 		t.Fatalf("unexpected order: %v", order)
 	}
+	if got := g.Nodes["api"].Conditions["db"]; got != config.ConditionHealthy {
+		t.Fatalf("expected healthy condition, got %q", got)
+	}
 }
 
 func TestTopoSortCycle(t *testing.T) {
 	prof := &config.Profile{
 		Services: map[string]config.Service{
-			"api": {DependsOn: []string{"web"}},
-			"web": {DependsOn: []string{"api"}},
+			"api": {DependsOn: []config.Dependency{{Name: "web"}}},
+			"web": {DependsOn: []config.Dependency{{Name: "api"}}},
 		},
 	}
 
@@ -48,3 +52,66 @@ func TestTopoSortCycle(t *testing.T) {
 		t.Fatalf("expected cycle error")
 	}
 }
+
+func TestClosure(t *testing.T) {
+	prof := &config.Profile{
+		Services: map[string]config.Service{
+			"api":    {DependsOn: []config.Dependency{{Name: "auth"}, {Name: "db"}}},
+			"auth":   {DependsOn: []config.Dependency{{Name: "cache"}}},
+			"worker": {DependsOn: []config.Dependency{{Name: "queue"}}},
+			"web":    {},
+		},
+		Deps: map[string]config.Dep{
+			"db":    {Kind: "postgres"},
+			"cache": {Kind: "redis"},
+			"queue": {Kind: "rabbitmq"},
+		},
+	}
+
+	g, err := Build(prof)
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	got, err := Closure(g, []string{"api"})
+	if err != nil {
+		t.Fatalf("closure failed: %v", err)
+	}
+	want := []string{"api", "auth", "cache", "db"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("closure = %v, want %v", got, want)
+	}
+
+	if _, err := Closure(g, []string{"missing"}); err == nil {
+		t.Fatalf("expected error for unknown service")
+	}
+}
+
+func TestDependents(t *testing.T) {
+	prof := &config.Profile{
+		Services: map[string]config.Service{
+			"api":    {DependsOn: []config.Dependency{{Name: "auth"}, {Name: "db"}}},
+			"auth":   {DependsOn: []config.Dependency{{Name: "cache"}}},
+			"web":    {DependsOn: []config.Dependency{{Name: "api"}}},
+			"worker": {},
+		},
+		Deps: map[string]config.Dep{
+			"db":    {Kind: "postgres"},
+			"cache": {Kind: "redis"},
+		},
+	}
+	g, err := Build(prof)
+	if err != nil {
+		t.Fatalf("build failed: %v", err)
+	}
+
+	if got := Dependents(g, []string{"cache"}); strings.Join(got, ",") != "api,auth,web" {
+		t.Errorf("dependents of cache = %v", got)
+	}
+	if got := Dependents(g, []string{"auth", "api"}); strings.Join(got, ",") != "web" {
+		t.Errorf("dependents of auth and api = %v", got)
+	}
+	if got := Dependents(g, []string{"worker"}); len(got) != 0 {
+		t.Errorf("expected no dependents of worker, got %v", got)
+	}
+}
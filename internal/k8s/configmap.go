@@ -0,0 +1,157 @@
+package k8s
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dever-labs/devx/internal/config"
+)
+
+// MaxConfigMapSize is the largest total file size a mount may have; the API
+// server rejects ConfigMaps above 1MiB.
+const MaxConfigMapSize = 1 << 20
+
+type ConfigMap struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   ObjectMeta        `yaml:"metadata"`
+	Data       map[string]string `yaml:"data,omitempty"`
+	// BinaryData holds base64-encoded values.
+	BinaryData map[string]string `yaml:"binaryData,omitempty"`
+}
+
+type ConfigMapVolumeSource struct {
+	Name  string      `yaml:"name"`
+	Items []KeyToPath `yaml:"items,omitempty"`
+}
+
+type KeyToPath struct {
+	Key  string `yaml:"key"`
+	Path string `yaml:"path"`
+}
+
+// mountConfigMap packs the files of a read-only bind mount into a ConfigMap
+// whose name carries a hash of the content, so changed files roll the pods.
+// Keys are sanitized and the volume's items map them back to the original
+// names, so a directory keeps its layout and a single file is mounted with
+// subPath. Dot-directories such as .git and editor swap files are skipped.
+func mountConfigMap(app string, namespace string, labels map[string]string, baseDir string, volName string, mount config.Mount) (ConfigMap, Volume, VolumeMount, error) {
+	source := mount.Source
+	if !filepath.IsAbs(source) {
+		source = filepath.Join(baseDir, source)
+	}
+	info, err := os.Stat(source)
+	if err != nil {
+		return ConfigMap{}, Volume{}, VolumeMount{}, err
+	}
+
+	// files maps slash-separated paths relative to the mount to their content.
+	files := map[string][]byte{}
+	if info.IsDir() {
+		err = filepath.WalkDir(source, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if file != source && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if isSwapFile(d.Name()) {
+				return nil
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(source, file)
+			if err != nil {
+				return err
+			}
+			files[filepath.ToSlash(rel)] = data
+			return nil
+		})
+	} else {
+		var data []byte
+		data, err = os.ReadFile(source)
+		files[filepath.Base(source)] = data
+	}
+	if err != nil {
+		return ConfigMap{}, Volume{}, VolumeMount{}, err
+	}
+
+	paths := make([]string, 0, len(files))
+	size := 0
+	for p, data := range files {
+		paths = append(paths, p)
+		size += len(data)
+	}
+	sort.Strings(paths)
+	if size > MaxConfigMapSize {
+		return ConfigMap{}, Volume{}, VolumeMount{}, fmt.Errorf("is %d bytes, over the %d byte ConfigMap limit", size, MaxConfigMapSize)
+	}
+
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%s\n", mount.Target)
+	cm := ConfigMap{APIVersion: "v1", Kind: "ConfigMap"}
+	var items []KeyToPath
+	for i, p := range paths {
+		// Keys cannot contain slashes, so directory entries are numbered.
+		key := sanitizeKey(path.Base(p))
+		if info.IsDir() {
+			key = fmt.Sprintf("f%d-%s", i, key)
+		}
+		items = append(items, KeyToPath{Key: key, Path: p})
+		data := files[p]
+		_, _ = fmt.Fprintf(hash, "%s\n%d\n", p, len(data))
+		_, _ = hash.Write(data)
+		if utf8.Valid(data) {
+			if cm.Data == nil {
+				cm.Data = map[string]string{}
+			}
+			cm.Data[key] = string(data)
+		} else {
+			if cm.BinaryData == nil {
+				cm.BinaryData = map[string]string{}
+			}
+			cm.BinaryData[key] = base64.StdEncoding.EncodeToString(data)
+		}
+	}
+
+	name := fmt.Sprintf("%s-%s", app, hex.EncodeToString(hash.Sum(nil))[:10])
+	cm.Metadata = ObjectMeta{Name: name, Namespace: namespace, Labels: labels}
+
+	volume := Volume{Name: volName, ConfigMap: &ConfigMapVolumeSource{Name: name, Items: items}}
+	volumeMount := VolumeMount{Name: volName, MountPath: mount.Target, ReadOnly: true}
+	if !info.IsDir() {
+		volumeMount.SubPath = paths[0]
+	}
+	return cm, volume, volumeMount, nil
+}
+
+// isSwapFile reports whether name is an editor swap or backup file.
+func isSwapFile(name string) bool {
+	return strings.HasSuffix(name, "~") || strings.HasSuffix(name, ".swp") || strings.HasSuffix(name, ".swo") ||
+		(strings.HasPrefix(name, "#") && strings.HasSuffix(name, "#"))
+}
+
+// sanitizeKey keeps the characters allowed in ConfigMap keys.
+func sanitizeKey(value string) string {
+	out := []rune(value)
+	for i, r := range out {
+		if !((r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '-' || r == '_' || r == '.') {
+			out[i] = '_'
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,261 @@
+package k8s
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type HelmChartMeta struct {
+	APIVersion  string `yaml:"apiVersion"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Type        string `yaml:"type"`
+	Version     string `yaml:"version"`
+	AppVersion  string `yaml:"appVersion"`
+}
+
+// HelmValues is the values.yaml of a generated chart.
+type HelmValues struct {
+	Services map[string]HelmServiceValues `yaml:"services"`
+}
+
+type HelmServiceValues struct {
+	Image    string            `yaml:"image"`
+	Replicas int               `yaml:"replicas"`
+	Env      map[string]string `yaml:"env"`
+	Ports    []int             `yaml:"ports"`
+}
+
+const (
+	helmImage        = "__DEVX_IMAGE__"
+	helmReplicas     = "__DEVX_REPLICAS__"
+	helmEnv          = "__DEVX_ENV__"
+	helmPorts        = "__DEVX_PORTS__"
+	helmServicePorts = "__DEVX_SERVICE_PORTS__"
+)
+
+// HelmChart lays out a Helm chart for the rendered objects of a project and
+// returns its files keyed by slash-separated path. The image, replicas, plain
+// env vars and ports of each Deployment and StatefulSet are read from
+// values.yaml under `services.<name>`; everything else is templated verbatim.
+func HelmChart(project string, resources []Resource) (map[string][]byte, error) {
+	files := map[string][]byte{}
+
+	chart, err := encodeYAML(HelmChartMeta{
+		APIVersion:  "v2",
+		Name:        sanitizeName(project),
+		Description: fmt.Sprintf("Kubernetes deployment of %s, generated by devx", project),
+		Type:        "application",
+		Version:     "0.1.0",
+		AppVersion:  "0.1.0",
+	})
+	if err != nil {
+		return nil, err
+	}
+	files["Chart.yaml"] = chart
+
+	values := HelmValues{Services: map[string]HelmServiceValues{}}
+	prefix := AppPrefix(project)
+
+	// Workloads first, so each Service object knows whether its ports come
+	// from values.
+	templated := map[string]string{}
+	for _, res := range resources {
+		if res.Kind != "Deployment" && res.Kind != "StatefulSet" {
+			continue
+		}
+		key := strings.TrimPrefix(res.Name, prefix)
+		text, svcValues, err := workloadTemplate(res, key)
+		if err != nil {
+			return nil, err
+		}
+		values.Services[key] = svcValues
+		templated[res.Name] = key
+		files[path.Join("templates", resourceFile(res))] = []byte(text)
+	}
+
+	for _, res := range resources {
+		if res.Kind == "Deployment" || res.Kind == "StatefulSet" {
+			continue
+		}
+		key, ok := templated[res.Name]
+		if res.Kind != "Service" || !ok {
+			data, err := encodeYAML(res.Object)
+			if err != nil {
+				return nil, err
+			}
+			files[path.Join("templates", resourceFile(res))] = []byte(escapeTemplate(string(data)))
+			continue
+		}
+		text, err := serviceTemplate(res, key)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Join("templates", resourceFile(res))] = []byte(text)
+	}
+
+	data, err := encodeYAML(values)
+	if err != nil {
+		return nil, err
+	}
+	files["values.yaml"] = data
+	return files, nil
+}
+
+// workloadTemplate turns a Deployment or StatefulSet into a template reading
+// its first container's settings from values, and returns those values.
+func workloadTemplate(res Resource, key string) (string, HelmServiceValues, error) {
+	obj, err := copyObject(res.Object)
+	if err != nil {
+		return "", HelmServiceValues{}, err
+	}
+
+	spec, _ := obj["spec"].(map[string]any)
+	template, _ := spec["template"].(map[string]any)
+	podSpec, _ := template["spec"].(map[string]any)
+	containers, _ := podSpec["containers"].([]any)
+	if len(containers) == 0 {
+		return "", HelmServiceValues{}, fmt.Errorf("%s '%s' has no containers", res.Kind, res.Name)
+	}
+	container, _ := containers[0].(map[string]any)
+
+	values := HelmServiceValues{Env: map[string]string{}, Ports: []int{}}
+	values.Image, _ = container["image"].(string)
+	values.Replicas, _ = spec["replicas"].(int)
+
+	// Env vars with a plain value move to values; secret references stay in
+	// the template.
+	var fixedEnv []any
+	env, _ := container["env"].([]any)
+	for _, item := range env {
+		entry, _ := item.(map[string]any)
+		name, _ := entry["name"].(string)
+		value, plain := entry["value"]
+		if _, ref := entry["valueFrom"]; ref || !plain {
+			fixedEnv = append(fixedEnv, item)
+			continue
+		}
+		values.Env[name] = fmt.Sprint(value)
+	}
+	ports, _ := container["ports"].([]any)
+	for _, item := range ports {
+		port, _ := item.(map[string]any)
+		if number, ok := port["containerPort"].(int); ok {
+			values.Ports = append(values.Ports, number)
+		}
+	}
+
+	spec["replicas"] = helmReplicas
+	container["image"] = helmImage
+	container["env"] = helmEnv
+	container["ports"] = helmPorts
+
+	text, err := encodeYAML(obj)
+	if err != nil {
+		return "", HelmServiceValues{}, err
+	}
+	fixed := ""
+	if len(fixedEnv) > 0 {
+		data, err := encodeYAML(fixedEnv)
+		if err != nil {
+			return "", HelmServiceValues{}, err
+		}
+		fixed = escapeTemplate(string(data))
+	}
+
+	out := escapeTemplate(string(text))
+	out = strings.Replace(out, helmReplicas, "{{ $svc.replicas }}", 1)
+	out = strings.Replace(out, helmImage, "{{ $svc.image | quote }}", 1)
+	out = expandBlock(out, helmEnv, func(indent string) string {
+		return indentLines(fixed, indent+"  ") +
+			indent + "{{- range $name, $value := $svc.env }}\n" +
+			indent + "  - name: {{ $name }}\n" +
+			indent + "    value: {{ $value | quote }}\n" +
+			indent + "{{- end }}\n"
+	})
+	out = expandBlock(out, helmPorts, func(indent string) string {
+		return indent + "{{- range $svc.ports }}\n" +
+			indent + "  - containerPort: {{ . }}\n" +
+			indent + "{{- end }}\n"
+	})
+	return fmt.Sprintf("{{- $svc := index .Values.services %q -}}\n%s", key, out), values, nil
+}
+
+// serviceTemplate turns a Service into a template whose ports follow the
+// workload's values; it renders nothing when the workload has no ports.
+func serviceTemplate(res Resource, key string) (string, error) {
+	obj, err := copyObject(res.Object)
+	if err != nil {
+		return "", err
+	}
+	spec, _ := obj["spec"].(map[string]any)
+	spec["ports"] = helmServicePorts
+
+	text, err := encodeYAML(obj)
+	if err != nil {
+		return "", err
+	}
+	out := expandBlock(escapeTemplate(string(text)), helmServicePorts, func(indent string) string {
+		return indent + "{{- range $svc.ports }}\n" +
+			indent + "  - name: p-{{ . }}\n" +
+			indent + "    port: {{ . }}\n" +
+			indent + "    targetPort: {{ . }}\n" +
+			indent + "{{- end }}\n"
+	})
+	return fmt.Sprintf("{{- $svc := index .Values.services %q }}\n{{- if $svc.ports }}\n%s{{- end }}\n", key, out), nil
+}
+
+// expandBlock replaces the line `<indent><key>: <placeholder>` with
+// `<indent><key>:` followed by the lines returned by block.
+func expandBlock(text string, placeholder string, block func(indent string) string) string {
+	lines := strings.SplitAfter(text, "\n")
+	var out strings.Builder
+	for _, line := range lines {
+		trimmed := strings.TrimRight(line, "\n")
+		if !strings.HasSuffix(trimmed, ": "+placeholder) {
+			out.WriteString(line)
+			continue
+		}
+		// A key that opens a list item ("- env: ...") is indented past the dash.
+		indent := strings.Repeat(" ", len(trimmed)-len(strings.TrimLeft(trimmed, " -")))
+		out.WriteString(strings.TrimSuffix(trimmed, " "+placeholder) + "\n")
+		out.WriteString(block(indent))
+	}
+	return out.String()
+}
+
+func indentLines(text string, indent string) string {
+	if text == "" {
+		return ""
+	}
+	lines := strings.SplitAfter(text, "\n")
+	var out strings.Builder
+	for _, line := range lines {
+		if line == "" {
+			continue
+		}
+		out.WriteString(indent + line)
+	}
+	return out.String()
+}
+
+// escapeTemplate keeps literal `{{` in rendered values out of Helm's template
+// engine.
+func escapeTemplate(text string) string {
+	return strings.ReplaceAll(text, "{{", `{{ "{{" }}`)
+}
+
+func copyObject(obj map[string]any) (map[string]any, error) {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	var out map[string]any
+	if err := yaml.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
@@ -0,0 +1,117 @@
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"text/template"
+
+	"github.com/dever-labs/devx/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestHelmChart(t *testing.T) {
+	manifest := &config.Manifest{Project: config.Project{Name: "shop"}}
+	profile := &config.Profile{
+		Services: map[string]config.Service{
+			"api": {
+				Image:     "shop/api:dev",
+				Ports:     []string{"8080:80"},
+				Env:       map[string]string{"LOG": "debug", "GREETING": "{{hello}}"},
+				SecretEnv: map[string]string{"DB_PASSWORD": "db-password"},
+			},
+		},
+	}
+
+	resources, err := Resources(manifest, "dev", profile, RenderOptions{OmitSecrets: true})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	for _, res := range resources {
+		if res.Kind == "Secret" {
+			t.Fatalf("expected secrets to be omitted")
+		}
+	}
+
+	files, err := HelmChart("shop", resources)
+	if err != nil {
+		t.Fatalf("chart failed: %v", err)
+	}
+	for _, name := range []string{"Chart.yaml", "values.yaml", "templates/shop-api-deployment.yaml", "templates/shop-api-service.yaml"} {
+		if _, ok := files[name]; !ok {
+			t.Fatalf("expected %s in chart", name)
+		}
+	}
+
+	var values map[string]any
+	if err := yaml.Unmarshal(files["values.yaml"], &values); err != nil {
+		t.Fatalf("values.yaml: %v", err)
+	}
+	api := values["services"].(map[string]any)["api"].(map[string]any)
+	if api["image"] != "shop/api:dev" || api["replicas"] != 1 {
+		t.Fatalf("unexpected api values: %v", api)
+	}
+
+	// Override values the way `helm install --set` would.
+	api["image"] = "shop/api:1.0"
+	api["replicas"] = 3
+	api["ports"] = []any{9090}
+	api["env"].(map[string]any)["EXTRA"] = "yes"
+
+	deployment := executeTemplate(t, files["templates/shop-api-deployment.yaml"], values)
+	spec := deployment["spec"].(map[string]any)
+	if spec["replicas"] != 3 {
+		t.Errorf("expected replicas from values, got %v", spec["replicas"])
+	}
+	container := spec["template"].(map[string]any)["spec"].(map[string]any)["containers"].([]any)[0].(map[string]any)
+	if container["image"] != "shop/api:1.0" {
+		t.Errorf("expected image from values, got %v", container["image"])
+	}
+	env := map[string]any{}
+	for _, item := range container["env"].([]any) {
+		entry := item.(map[string]any)
+		if ref, ok := entry["valueFrom"]; ok {
+			env[entry["name"].(string)] = ref
+			continue
+		}
+		env[entry["name"].(string)] = entry["value"]
+	}
+	if env["LOG"] != "debug" || env["EXTRA"] != "yes" || env["GREETING"] != "{{hello}}" {
+		t.Errorf("unexpected env: %v", env)
+	}
+	if _, ok := env["DB_PASSWORD"].(map[string]any); !ok {
+		t.Errorf("expected secret env to stay a secretKeyRef, got %v", env["DB_PASSWORD"])
+	}
+	if got := fmt.Sprint(container["ports"]); got != "[map[containerPort:9090]]" {
+		t.Errorf("expected ports from values, got %s", got)
+	}
+
+	service := executeTemplate(t, files["templates/shop-api-service.yaml"], values)
+	if got := fmt.Sprint(service["spec"].(map[string]any)["ports"]); got != "[map[name:p-9090 port:9090 targetPort:9090]]" {
+		t.Errorf("expected service ports from values, got %s", got)
+	}
+}
+
+// executeTemplate renders a chart template with the subset of Helm's
+// functions the generated charts use.
+func executeTemplate(t *testing.T, text []byte, values map[string]any) map[string]any {
+	t.Helper()
+	funcs := template.FuncMap{"quote": func(v any) string { return fmt.Sprintf("%q", fmt.Sprint(v)) }}
+	tmpl, err := template.New("chart").Funcs(funcs).Parse(string(text))
+	if err != nil {
+		t.Fatalf("parse template: %v\n%s", err, text)
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, map[string]any{"Values": values}); err != nil {
+		t.Fatalf("execute template: %v", err)
+	}
+	var out map[string]any
+	if err := yaml.Unmarshal(buf.Bytes(), &out); err != nil {
+		t.Fatalf("rendered template is not YAML: %v\n%s", err, buf.String())
+	}
+	if !strings.Contains(buf.String(), "kind:") {
+		t.Fatalf("expected an object, got:\n%s", buf.String())
+	}
+	return out
+}
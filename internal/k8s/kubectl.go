@@ -1,33 +1,265 @@
 package k8s
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
+// FieldManager is the server-side apply field manager devx applies as.
+const FieldManager = "devx"
+
+// deleteKinds are the resource types Delete removes by label, and Apply
+// prunes. PersistentVolumeClaims are added only on request so that removing a
+// service never deletes its data.
+var deleteKinds = []string{
+	"deployments.apps",
+	"statefulsets.apps",
+	"services",
+	"configmaps",
+	"secrets",
+	"ingresses.networking.k8s.io",
+}
+
+const gatewayGroup = "gateway.networking.k8s.io"
+
 func DetectKubectl() error {
 	_, err := exec.LookPath("kubectl")
 	return err
 }
 
-func Apply(ctx context.Context, manifestPath string) error {
+// Kubectl runs kubectl against the cluster selected by Context and
+// Kubeconfig. Empty fields leave the choice to kubectl (KUBECONFIG and the
+// current context).
+type Kubectl struct {
+	Context    string
+	Kubeconfig string
+}
+
+// Args prepends the cluster selection to a kubectl argument list.
+func (k Kubectl) Args(args ...string) []string {
+	var out []string
+	if k.Kubeconfig != "" {
+		out = append(out, "--kubeconfig", k.Kubeconfig)
+	}
+	if k.Context != "" {
+		out = append(out, "--context", k.Context)
+	}
+	return append(out, args...)
+}
+
+type ApplyOptions struct {
+	// Prune deletes objects labelled as owned by Project that are no longer
+	// in the manifest.
+	Prune   bool
+	Project string
+}
+
+// Apply server-side applies the manifest content. devx owns the objects it
+// renders, so conflicting fields are taken over.
+func (k Kubectl) Apply(ctx context.Context, manifest string, opts ApplyOptions) error {
 	if err := DetectKubectl(); err != nil {
 		return fmt.Errorf("kubectl not found in PATH")
 	}
-	cmd := exec.CommandContext(ctx, "kubectl", "apply", "-f", manifestPath)
+	cmd := exec.CommandContext(ctx, "kubectl", k.Args("apply", "--server-side", "--field-manager", FieldManager, "--force-conflicts", "-f", "-")...)
+	cmd.Stdin = strings.NewReader(manifest)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	if !opts.Prune {
+		return nil
+	}
+	return k.prune(ctx, manifest, opts.Project)
+}
+
+// prune deletes the objects owned by project that the manifest no longer
+// contains. kubectl's own --prune only considers objects carrying the
+// client-side last-applied annotation, which server-side apply never writes,
+// so the stale objects are listed by label and compared by name instead.
+func (k Kubectl) prune(ctx context.Context, manifest string, project string) error {
+	objects, namespace, err := manifestObjects(manifest)
+	if err != nil {
+		return err
+	}
+	kinds := append([]string{}, deleteKinds...)
+	if k.hasGroup(ctx, gatewayGroup) {
+		kinds = append(kinds, "httproutes."+gatewayGroup)
+	}
+	var scope []string
+	if namespace != "" {
+		scope = []string{"-n", namespace}
+	}
+
+	var stderr bytes.Buffer
+	list := exec.CommandContext(ctx, "kubectl", k.Args(append([]string{"get", strings.Join(kinds, ","), "-l", OwnerSelector(project), "-o", "name"}, scope...)...)...)
+	list.Stderr = &stderr
+	out, err := list.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("listing objects to prune: %s", msg)
+		}
+		return fmt.Errorf("listing objects to prune: %w", err)
+	}
+	var stale []string
+	for _, name := range strings.Fields(string(out)) {
+		if !objects[name] {
+			stale = append(stale, name)
+		}
+	}
+	if len(stale) == 0 {
+		return nil
+	}
+
+	args := append([]string{"delete"}, stale...)
+	args = append(append(args, scope...), "--ignore-not-found")
+	cmd := exec.CommandContext(ctx, "kubectl", k.Args(args...)...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
 
-func Delete(ctx context.Context, manifestPath string) error {
+// manifestObjects returns the objects of a rendered manifest in the form
+// `kubectl get -o name` prints them ("deployment.apps/name", "service/name"),
+// and the namespace they are rendered into.
+func manifestObjects(manifest string) (map[string]bool, string, error) {
+	dec := yaml.NewDecoder(strings.NewReader(manifest))
+	out := map[string]bool{}
+	namespace := ""
+	for {
+		var obj struct {
+			APIVersion string `yaml:"apiVersion"`
+			Kind       string `yaml:"kind"`
+			Metadata   struct {
+				Name      string `yaml:"name"`
+				Namespace string `yaml:"namespace"`
+			} `yaml:"metadata"`
+		}
+		if err := dec.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				return out, namespace, nil
+			}
+			return nil, "", err
+		}
+		if obj.Kind == "" {
+			continue
+		}
+		resource := strings.ToLower(obj.Kind)
+		if group, _, ok := strings.Cut(obj.APIVersion, "/"); ok {
+			resource += "." + group
+		}
+		out[resource+"/"+obj.Metadata.Name] = true
+		if obj.Metadata.Namespace != "" {
+			namespace = obj.Metadata.Namespace
+		}
+	}
+}
+
+// Delete removes the objects labelled as owned by project. Claims, and with
+// them the data of services and deps, are only removed with volumes.
+func (k Kubectl) Delete(ctx context.Context, project string, volumes bool) error {
 	if err := DetectKubectl(); err != nil {
 		return fmt.Errorf("kubectl not found in PATH")
 	}
-	cmd := exec.CommandContext(ctx, "kubectl", "delete", "-f", manifestPath)
+	kinds := append([]string{}, deleteKinds...)
+	if k.hasGroup(ctx, gatewayGroup) {
+		kinds = append(kinds, "httproutes."+gatewayGroup)
+	}
+	if volumes {
+		kinds = append(kinds, "persistentvolumeclaims")
+	}
+	cmd := exec.CommandContext(ctx, "kubectl", k.Args("delete", strings.Join(kinds, ","), "-l", OwnerSelector(project), "--ignore-not-found")...)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
 	return cmd.Run()
 }
+
+// WaitForRollout runs `kubectl rollout status` on each workload in turn
+// ("deployment/name" or "statefulset/name") until all are rolled out or
+// timeout has passed in total, reporting progress to out.
+func (k Kubectl) WaitForRollout(ctx context.Context, workloads []string, timeout time.Duration, out io.Writer) error {
+	deadline := time.Now().Add(timeout)
+	for i, workload := range workloads {
+		// kubectl treats a zero timeout as no timeout.
+		remaining := time.Until(deadline).Round(time.Second)
+		if remaining < time.Second {
+			return fmt.Errorf("timed out after %s waiting for %s", timeout, workload)
+		}
+		fmt.Fprintf(out, "[%d/%d] Waiting for %s\n", i+1, len(workloads), workload)
+
+		start := time.Now()
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "kubectl", k.Args("rollout", "status", workload, "--timeout", remaining.String())...)
+		cmd.Stdout = &indentWriter{w: out}
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("%s did not roll out: %s", workload, msg)
+			}
+			return fmt.Errorf("%s did not roll out: %w", workload, err)
+		}
+		fmt.Fprintf(out, "[%d/%d] %s ready (%s)\n", i+1, len(workloads), workload, time.Since(start).Round(time.Second))
+	}
+	return nil
+}
+
+// Workloads lists the Deployments and StatefulSets in a rendered manifest as
+// "deployment/name" and "statefulset/name", in manifest order.
+func Workloads(manifest string) ([]string, error) {
+	dec := yaml.NewDecoder(strings.NewReader(manifest))
+	var out []string
+	for {
+		var obj struct {
+			Kind     string `yaml:"kind"`
+			Metadata struct {
+				Name string `yaml:"name"`
+			} `yaml:"metadata"`
+		}
+		if err := dec.Decode(&obj); err != nil {
+			if errors.Is(err, io.EOF) {
+				return out, nil
+			}
+			return nil, err
+		}
+		if obj.Kind == "Deployment" || obj.Kind == "StatefulSet" {
+			out = append(out, strings.ToLower(obj.Kind)+"/"+obj.Metadata.Name)
+		}
+	}
+}
+
+// hasGroup reports whether the cluster serves an API group, e.g. the Gateway
+// API whose kinds cannot be named when its CRDs are missing.
+func (k Kubectl) hasGroup(ctx context.Context, group string) bool {
+	out, err := exec.CommandContext(ctx, "kubectl", k.Args("api-resources", "--api-group", group, "-o", "name")...).Output()
+	return err == nil && len(bytes.TrimSpace(out)) > 0
+}
+
+// indentWriter indents kubectl's progress lines under devx's own.
+type indentWriter struct {
+	w       io.Writer
+	midLine bool
+}
+
+func (iw *indentWriter) Write(p []byte) (int, error) {
+	for _, b := range p {
+		if !iw.midLine {
+			if _, err := io.WriteString(iw.w, "      "); err != nil {
+				return 0, err
+			}
+		}
+		if _, err := iw.w.Write([]byte{b}); err != nil {
+			return 0, err
+		}
+		iw.midLine = b != '\n'
+	}
+	return len(p), nil
+}
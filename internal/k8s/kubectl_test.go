@@ -0,0 +1,118 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dever-labs/devx/internal/config"
+)
+
+func TestWorkloads(t *testing.T) {
+	manifest := &config.Manifest{Project: config.Project{Name: "my-app"}}
+	profile := &config.Profile{
+		Services: map[string]config.Service{"api": {Image: "api", Ports: []string{"80"}}},
+		Deps: map[string]config.Dep{
+			"cache": {Kind: "redis"},
+			"db":    {Kind: "postgres", Volume: "db-data"},
+		},
+	}
+	out, err := Render(manifest, "k8s", profile, RenderOptions{})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	got, err := Workloads(out)
+	if err != nil {
+		t.Fatalf("workloads failed: %v", err)
+	}
+	want := []string{"deployment/my-app-api", "deployment/my-app-cache", "statefulset/my-app-db"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+
+	for _, doc := range strings.Split(out, "---\n") {
+		if strings.Contains(doc, "kind:") && !strings.Contains(doc, ManagedByLabel+": devx\n    "+PartOfLabel+": my-app") {
+			t.Errorf("expected the owner labels on every object:\n%s", doc)
+		}
+	}
+	if got := OwnerSelector("My App"); got != "app.kubernetes.io/managed-by=devx,app.kubernetes.io/part-of=my-app" {
+		t.Errorf("unexpected selector %q", got)
+	}
+}
+
+func TestKubectlCommands(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as kubectl")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	script := "#!/bin/sh\necho \"$@\" >> " + log + "\ncase \"$*\" in *api-resources*) echo httproutes.gateway.networking.k8s.io;; *rollout*) echo rolled out;; *\" get \"*) printf 'deployment.apps/my-app-api\\nservice/my-app-api\\nservice/my-app-old\\n';; esac\n"
+	if err := os.WriteFile(filepath.Join(dir, "kubectl"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	ctx := context.Background()
+	cluster := Kubectl{Context: "staging", Kubeconfig: "/tmp/kubeconfig"}
+	manifest := "apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: my-app-api\n  namespace: dev\n---\napiVersion: v1\nkind: Service\nmetadata:\n  name: my-app-api\n  namespace: dev\n"
+	if err := cluster.Apply(ctx, manifest, ApplyOptions{Prune: true, Project: "my-app"}); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if err := cluster.Apply(ctx, manifest, ApplyOptions{Project: "my-app"}); err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	if err := cluster.Delete(ctx, "my-app", false); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	var progress bytes.Buffer
+	if err := cluster.WaitForRollout(ctx, []string{"deployment/my-app-api"}, time.Minute, &progress); err != nil {
+		t.Fatalf("rollout failed: %v", err)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(calls) != 8 {
+		t.Fatalf("expected 8 kubectl calls, got:\n%s", data)
+	}
+	for _, call := range calls {
+		if !strings.HasPrefix(call, "--kubeconfig /tmp/kubeconfig --context staging ") {
+			t.Errorf("expected cluster selection first, got %q", call)
+		}
+	}
+	if !strings.HasSuffix(calls[0], "apply --server-side --field-manager devx --force-conflicts -f -") {
+		t.Errorf("unexpected apply call %q", calls[0])
+	}
+	// Server-side apply leaves nothing for kubectl --prune to go on, so stale
+	// objects are listed by label and deleted by name.
+	list := calls[2]
+	if !strings.Contains(list, "get deployments.apps,") || !strings.Contains(list, "httproutes.gateway.networking.k8s.io -l "+OwnerSelector("my-app")+" -o name -n dev") {
+		t.Errorf("unexpected prune listing %q", list)
+	}
+	if strings.Contains(list, "persistentvolumeclaims") {
+		t.Errorf("claims must never be pruned: %q", list)
+	}
+	if !strings.HasSuffix(calls[3], "delete service/my-app-old -n dev --ignore-not-found") {
+		t.Errorf("expected only the stale service to be deleted, got %q", calls[3])
+	}
+	if !strings.HasSuffix(calls[4], "apply --server-side --field-manager devx --force-conflicts -f -") {
+		t.Errorf("expected no pruning without Prune, got %q", calls[4])
+	}
+	if !strings.Contains(calls[6], "delete deployments.apps,") || !strings.Contains(calls[6], "httproutes.gateway.networking.k8s.io -l") || strings.Contains(calls[6], "persistentvolumeclaims") {
+		t.Errorf("unexpected delete call %q", calls[6])
+	}
+	if !strings.Contains(calls[7], "rollout status deployment/my-app-api --timeout 1m0s") {
+		t.Errorf("unexpected rollout call %q", calls[7])
+	}
+	if got := progress.String(); !strings.Contains(got, "[1/1] Waiting for deployment/my-app-api\n      rolled out\n[1/1] deployment/my-app-api ready") {
+		t.Errorf("unexpected progress output:\n%s", got)
+	}
+}
@@ -0,0 +1,190 @@
+package k8s
+
+import (
+	"bytes"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/dever-labs/devx/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+type Kustomization struct {
+	APIVersion string           `yaml:"apiVersion"`
+	Kind       string           `yaml:"kind"`
+	Resources  []string         `yaml:"resources,omitempty"`
+	Patches    []KustomizePatch `yaml:"patches,omitempty"`
+}
+
+type KustomizePatch struct {
+	Path   string       `yaml:"path"`
+	Target *PatchTarget `yaml:"target,omitempty"`
+}
+
+type PatchTarget struct {
+	Kind string `yaml:"kind"`
+	Name string `yaml:"name"`
+}
+
+// PatchOp is a JSON6902 patch operation.
+type PatchOp struct {
+	Op    string `yaml:"op"`
+	Path  string `yaml:"path"`
+	Value any    `yaml:"value,omitempty"`
+}
+
+// Kustomize lays out a kustomize tree and returns its files keyed by
+// slash-separated path. base/ holds one file per object of base plus a
+// kustomization.yaml, and overlays/<profile>/ describes each overlay by how
+// its objects differ from base: JSON6902 patches for changed objects, extra
+// resources for new ones and `$patch: delete` for missing ones.
+func Kustomize(base []Resource, overlays map[string][]Resource) (map[string][]byte, error) {
+	files := map[string][]byte{}
+
+	kustomization := Kustomization{APIVersion: "kustomize.config.k8s.io/v1beta1", Kind: "Kustomization"}
+	baseObjects := map[string]Resource{}
+	for _, res := range base {
+		name := resourceFile(res)
+		data, err := encodeYAML(res.Object)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Join("base", name)] = data
+		kustomization.Resources = append(kustomization.Resources, name)
+		baseObjects[resourceKey(res)] = res
+	}
+	data, err := encodeYAML(kustomization)
+	if err != nil {
+		return nil, err
+	}
+	files["base/kustomization.yaml"] = data
+
+	for _, profile := range util.SortedKeys(overlays) {
+		dir := path.Join("overlays", profile)
+		overlay := Kustomization{
+			APIVersion: kustomization.APIVersion,
+			Kind:       kustomization.Kind,
+			Resources:  []string{"../../base"},
+		}
+
+		seen := map[string]bool{}
+		for _, res := range overlays[profile] {
+			key := resourceKey(res)
+			seen[key] = true
+			orig, ok := baseObjects[key]
+			if !ok {
+				name := resourceFile(res)
+				data, err := encodeYAML(res.Object)
+				if err != nil {
+					return nil, err
+				}
+				files[path.Join(dir, name)] = data
+				overlay.Resources = append(overlay.Resources, name)
+				continue
+			}
+			ops := diffObjects("", orig.Object, res.Object)
+			if len(ops) == 0 {
+				continue
+			}
+			name := strings.TrimSuffix(resourceFile(res), ".yaml") + ".patch.yaml"
+			data, err := encodeYAML(ops)
+			if err != nil {
+				return nil, err
+			}
+			files[path.Join(dir, name)] = data
+			overlay.Patches = append(overlay.Patches, KustomizePatch{
+				Path:   name,
+				Target: &PatchTarget{Kind: res.Kind, Name: res.Name},
+			})
+		}
+
+		for _, res := range base {
+			if seen[resourceKey(res)] {
+				continue
+			}
+			name := strings.TrimSuffix(resourceFile(res), ".yaml") + ".delete.yaml"
+			data, err := encodeYAML(map[string]any{
+				"$patch":     "delete",
+				"apiVersion": res.Object["apiVersion"],
+				"kind":       res.Kind,
+				"metadata":   map[string]any{"name": res.Name},
+			})
+			if err != nil {
+				return nil, err
+			}
+			files[path.Join(dir, name)] = data
+			overlay.Patches = append(overlay.Patches, KustomizePatch{Path: name})
+		}
+
+		data, err := encodeYAML(overlay)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Join(dir, "kustomization.yaml")] = data
+	}
+
+	return files, nil
+}
+
+// diffObjects returns the operations that turn from into to. Maps are compared
+// key by key; lists and scalars are replaced as a whole.
+func diffObjects(prefix string, from, to map[string]any) []PatchOp {
+	keys := map[string]bool{}
+	for key := range from {
+		keys[key] = true
+	}
+	for key := range to {
+		keys[key] = true
+	}
+
+	var ops []PatchOp
+	for _, key := range util.SortedKeys(keys) {
+		p := prefix + "/" + escapePointer(key)
+		oldValue, inFrom := from[key]
+		newValue, inTo := to[key]
+		switch {
+		case !inTo:
+			ops = append(ops, PatchOp{Op: "remove", Path: p})
+		case !inFrom:
+			ops = append(ops, PatchOp{Op: "add", Path: p, Value: newValue})
+		default:
+			oldMap, oldIsMap := oldValue.(map[string]any)
+			newMap, newIsMap := newValue.(map[string]any)
+			if oldIsMap && newIsMap {
+				ops = append(ops, diffObjects(p, oldMap, newMap)...)
+				continue
+			}
+			if !reflect.DeepEqual(oldValue, newValue) {
+				ops = append(ops, PatchOp{Op: "replace", Path: p, Value: newValue})
+			}
+		}
+	}
+	return ops
+}
+
+func escapePointer(key string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1")
+}
+
+func resourceKey(res Resource) string {
+	return res.Kind + "/" + res.Name
+}
+
+// resourceFile names the file of an object, e.g. my-app-api-deployment.yaml.
+func resourceFile(res Resource) string {
+	return res.Name + "-" + strings.ToLower(res.Kind) + ".yaml"
+}
+
+func encodeYAML(v any) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
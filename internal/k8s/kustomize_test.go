@@ -0,0 +1,97 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/dever-labs/devx/internal/config"
+)
+
+func TestKustomize(t *testing.T) {
+	manifest := &config.Manifest{Project: config.Project{Name: "shop"}}
+	dev := &config.Profile{
+		Services: map[string]config.Service{
+			"api": {Image: "shop/api:dev", Ports: []string{"8080"}, Env: map[string]string{"LOG": "debug"}},
+		},
+		Deps: map[string]config.Dep{"db": {Kind: "postgres", Version: "16"}},
+	}
+	prod := &config.Profile{
+		Services: map[string]config.Service{
+			"api":    {Image: "shop/api:1.0", Ports: []string{"8080"}, Env: map[string]string{"LOG": "debug"}},
+			"worker": {Image: "shop/worker:1.0"},
+		},
+	}
+
+	base, err := Resources(manifest, "dev", dev, RenderOptions{})
+	if err != nil {
+		t.Fatalf("render dev failed: %v", err)
+	}
+	prodResources, err := Resources(manifest, "prod", prod, RenderOptions{})
+	if err != nil {
+		t.Fatalf("render prod failed: %v", err)
+	}
+
+	files, err := Kustomize(base, map[string][]Resource{"dev": base, "prod": prodResources})
+	if err != nil {
+		t.Fatalf("kustomize failed: %v", err)
+	}
+
+	for _, name := range []string{
+		"base/kustomization.yaml",
+		"base/shop-api-deployment.yaml",
+		"base/shop-db-service.yaml",
+		"overlays/dev/kustomization.yaml",
+		"overlays/prod/kustomization.yaml",
+		"overlays/prod/shop-worker-deployment.yaml",
+		"overlays/prod/shop-api-deployment.patch.yaml",
+		"overlays/prod/shop-db-deployment.delete.yaml",
+	} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected %s in output", name)
+		}
+	}
+
+	if got := string(files["overlays/dev/kustomization.yaml"]); strings.Contains(got, "patches") {
+		t.Errorf("expected dev overlay to match base, got:\n%s", got)
+	}
+	patch := string(files["overlays/prod/shop-api-deployment.patch.yaml"])
+	if !strings.Contains(patch, "op: replace") || !strings.Contains(patch, "shop/api:1.0") {
+		t.Errorf("expected image replace in patch, got:\n%s", patch)
+	}
+	prodKustomization := string(files["overlays/prod/kustomization.yaml"])
+	for _, want := range []string{"- ../../base", "- shop-worker-deployment.yaml", "kind: Deployment\n      name: shop-api", "- path: shop-db-service.delete.yaml"} {
+		if !strings.Contains(prodKustomization, want) {
+			t.Errorf("expected %q in prod kustomization, got:\n%s", want, prodKustomization)
+		}
+	}
+	if got := string(files["overlays/prod/shop-db-deployment.delete.yaml"]); !strings.Contains(got, "$patch: delete") {
+		t.Errorf("expected delete patch, got:\n%s", got)
+	}
+}
+
+func TestDiffObjects(t *testing.T) {
+	from := map[string]any{
+		"metadata": map[string]any{"labels": map[string]any{"app": "a", "a/b": "x"}},
+		"spec":     map[string]any{"replicas": 1, "old": true},
+	}
+	to := map[string]any{
+		"metadata": map[string]any{"labels": map[string]any{"app": "a", "a/b": "y"}},
+		"spec":     map[string]any{"replicas": 2, "new": "v"},
+	}
+
+	ops := diffObjects("", from, to)
+	want := []PatchOp{
+		{Op: "replace", Path: "/metadata/labels/a~1b", Value: "y"},
+		{Op: "add", Path: "/spec/new", Value: "v"},
+		{Op: "remove", Path: "/spec/old"},
+		{Op: "replace", Path: "/spec/replicas", Value: 2},
+	}
+	if len(ops) != len(want) {
+		t.Fatalf("expected %d ops, got %+v", len(want), ops)
+	}
+	for i := range want {
+		if ops[i] != want[i] {
+			t.Errorf("op %d: expected %+v, got %+v", i, want[i], ops[i])
+		}
+	}
+}
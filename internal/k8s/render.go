@@ -3,10 +3,13 @@ package k8s
 import (
 	"bytes"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dever-labs/devx/internal/config"
+	"github.com/dever-labs/devx/internal/deps"
 	"github.com/dever-labs/devx/internal/util"
 	"gopkg.in/yaml.v3"
 )
@@ -25,11 +28,54 @@ type Deployment struct {
 }
 
 type DeploymentSpec struct {
-	Replicas int             `yaml:"replicas"`
-	Selector LabelSelector   `yaml:"selector"`
-	Template PodTemplateSpec `yaml:"template"`
+	Replicas int                 `yaml:"replicas"`
+	Selector LabelSelector       `yaml:"selector"`
+	Strategy *DeploymentStrategy `yaml:"strategy,omitempty"`
+	Template PodTemplateSpec     `yaml:"template"`
 }
 
+// DeploymentStrategy is set to Recreate for pods that mount a ReadWriteOnce
+// claim, which cannot be attached to the old and new pod at the same time.
+type DeploymentStrategy struct {
+	Type string `yaml:"type"`
+}
+
+type StatefulSet struct {
+	APIVersion string          `yaml:"apiVersion"`
+	Kind       string          `yaml:"kind"`
+	Metadata   ObjectMeta      `yaml:"metadata"`
+	Spec       StatefulSetSpec `yaml:"spec"`
+}
+
+type StatefulSetSpec struct {
+	ServiceName          string                  `yaml:"serviceName"`
+	Replicas             int                     `yaml:"replicas"`
+	Selector             LabelSelector           `yaml:"selector"`
+	Template             PodTemplateSpec         `yaml:"template"`
+	VolumeClaimTemplates []PersistentVolumeClaim `yaml:"volumeClaimTemplates,omitempty"`
+}
+
+type PersistentVolumeClaim struct {
+	APIVersion string                    `yaml:"apiVersion,omitempty"`
+	Kind       string                    `yaml:"kind,omitempty"`
+	Metadata   ObjectMeta                `yaml:"metadata"`
+	Spec       PersistentVolumeClaimSpec `yaml:"spec"`
+}
+
+type PersistentVolumeClaimSpec struct {
+	AccessModes      []string             `yaml:"accessModes"`
+	StorageClassName string               `yaml:"storageClassName,omitempty"`
+	Resources        ResourceRequirements `yaml:"resources"`
+}
+
+type ResourceRequirements struct {
+	Requests map[string]string `yaml:"requests,omitempty"`
+	Limits   map[string]string `yaml:"limits,omitempty"`
+}
+
+// DefaultStorageSize is requested by volume claims without a storage.size.
+const DefaultStorageSize = "1Gi"
+
 type LabelSelector struct {
 	MatchLabels map[string]string `yaml:"matchLabels"`
 }
@@ -40,25 +86,181 @@ type PodTemplateSpec struct {
 }
 
 type PodSpec struct {
-	Containers []Container `yaml:"containers"`
-	Volumes    []Volume    `yaml:"volumes,omitempty"`
+	Containers      []Container         `yaml:"containers"`
+	Volumes         []Volume            `yaml:"volumes,omitempty"`
+	SecurityContext *PodSecurityContext `yaml:"securityContext,omitempty"`
+}
+
+type PodSecurityContext struct {
+	RunAsNonRoot *bool `yaml:"runAsNonRoot,omitempty"`
+}
+
+type SecurityContext struct {
+	ReadOnlyRootFilesystem *bool `yaml:"readOnlyRootFilesystem,omitempty"`
 }
 
 type Container struct {
-	Name         string          `yaml:"name"`
-	Image        string          `yaml:"image"`
-	Command      []string        `yaml:"command,omitempty"`
-	WorkingDir   string          `yaml:"workingDir,omitempty"`
-	Env          []EnvVar        `yaml:"env,omitempty"`
-	Ports        []ContainerPort `yaml:"ports,omitempty"`
-	VolumeMounts []VolumeMount   `yaml:"volumeMounts,omitempty"`
+	Name            string                `yaml:"name"`
+	Image           string                `yaml:"image"`
+	Command         []string              `yaml:"command,omitempty"`
+	WorkingDir      string                `yaml:"workingDir,omitempty"`
+	Env             []EnvVar              `yaml:"env,omitempty"`
+	Ports           []ContainerPort       `yaml:"ports,omitempty"`
+	VolumeMounts    []VolumeMount         `yaml:"volumeMounts,omitempty"`
+	ReadinessProbe  *Probe                `yaml:"readinessProbe,omitempty"`
+	LivenessProbe   *Probe                `yaml:"livenessProbe,omitempty"`
+	Resources       *ResourceRequirements `yaml:"resources,omitempty"`
+	SecurityContext *SecurityContext      `yaml:"securityContext,omitempty"`
+}
+
+type Probe struct {
+	HTTPGet             *HTTPGetAction   `yaml:"httpGet,omitempty"`
+	TCPSocket           *TCPSocketAction `yaml:"tcpSocket,omitempty"`
+	Exec                *ExecAction      `yaml:"exec,omitempty"`
+	InitialDelaySeconds int              `yaml:"initialDelaySeconds,omitempty"`
+	PeriodSeconds       int              `yaml:"periodSeconds,omitempty"`
+	TimeoutSeconds      int              `yaml:"timeoutSeconds,omitempty"`
+	FailureThreshold    int              `yaml:"failureThreshold,omitempty"`
+}
+
+type HTTPGetAction struct {
+	Path   string `yaml:"path"`
+	Port   int    `yaml:"port"`
+	Scheme string `yaml:"scheme,omitempty"`
+}
+
+type TCPSocketAction struct {
+	Port int `yaml:"port"`
+}
+
+type ExecAction struct {
+	Command []string `yaml:"command"`
 }
 
 type EnvVar struct {
-	Name  string `yaml:"name"`
+	Name      string        `yaml:"name"`
+	Value     string        `yaml:"value,omitempty"`
+	ValueFrom *EnvVarSource `yaml:"valueFrom,omitempty"`
+}
+
+type EnvVarSource struct {
+	SecretKeyRef *SecretKeySelector `yaml:"secretKeyRef,omitempty"`
+}
+
+type SecretKeySelector struct {
+	Name string `yaml:"name"`
+	Key  string `yaml:"key"`
+}
+
+type Secret struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   ObjectMeta        `yaml:"metadata"`
+	Type       string            `yaml:"type"`
+	StringData map[string]string `yaml:"stringData"`
+}
+
+type Ingress struct {
+	APIVersion string      `yaml:"apiVersion"`
+	Kind       string      `yaml:"kind"`
+	Metadata   ObjectMeta  `yaml:"metadata"`
+	Spec       IngressSpec `yaml:"spec"`
+}
+
+type IngressSpec struct {
+	IngressClassName string        `yaml:"ingressClassName,omitempty"`
+	TLS              []IngressTLS  `yaml:"tls,omitempty"`
+	Rules            []IngressRule `yaml:"rules"`
+}
+
+type IngressTLS struct {
+	Hosts      []string `yaml:"hosts"`
+	SecretName string   `yaml:"secretName"`
+}
+
+type IngressRule struct {
+	Host string               `yaml:"host"`
+	HTTP HTTPIngressRuleValue `yaml:"http"`
+}
+
+type HTTPIngressRuleValue struct {
+	Paths []HTTPIngressPath `yaml:"paths"`
+}
+
+type HTTPIngressPath struct {
+	Path     string         `yaml:"path"`
+	PathType string         `yaml:"pathType"`
+	Backend  IngressBackend `yaml:"backend"`
+}
+
+type IngressBackend struct {
+	Service IngressServiceBackend `yaml:"service"`
+}
+
+type IngressServiceBackend struct {
+	Name string             `yaml:"name"`
+	Port ServiceBackendPort `yaml:"port"`
+}
+
+type ServiceBackendPort struct {
+	Number int `yaml:"number"`
+}
+
+type HTTPRoute struct {
+	APIVersion string        `yaml:"apiVersion"`
+	Kind       string        `yaml:"kind"`
+	Metadata   ObjectMeta    `yaml:"metadata"`
+	Spec       HTTPRouteSpec `yaml:"spec"`
+}
+
+type HTTPRouteSpec struct {
+	ParentRefs []ParentReference `yaml:"parentRefs"`
+	Hostnames  []string          `yaml:"hostnames"`
+	Rules      []HTTPRouteRule   `yaml:"rules"`
+}
+
+type ParentReference struct {
+	Name      string `yaml:"name"`
+	Namespace string `yaml:"namespace,omitempty"`
+}
+
+type HTTPRouteRule struct {
+	Matches     []HTTPRouteMatch `yaml:"matches"`
+	BackendRefs []HTTPBackendRef `yaml:"backendRefs"`
+}
+
+type HTTPRouteMatch struct {
+	Path HTTPPathMatch `yaml:"path"`
+}
+
+type HTTPPathMatch struct {
+	Type  string `yaml:"type"`
 	Value string `yaml:"value"`
 }
 
+type HTTPBackendRef struct {
+	Name string `yaml:"name"`
+	Port int    `yaml:"port"`
+}
+
+// SecretKey is the key under which a devx secret's value is stored in its
+// Kubernetes Secret.
+const SecretKey = "value"
+
+// RenderOptions controls k8s rendering.
+type RenderOptions struct {
+	Namespace string
+	// SecretValues holds the resolved value of every secret referenced by
+	// the profile, keyed by secret name.
+	SecretValues map[string][]byte
+	// BaseDir is the directory relative host paths in `mount` are read from
+	// (default: the working directory).
+	BaseDir string
+	// OmitSecrets skips the Secret objects, for output that is committed to
+	// a repository; the Secrets must then be provided by the cluster.
+	OmitSecrets bool
+}
+
 type ContainerPort struct {
 	ContainerPort int `yaml:"containerPort"`
 }
@@ -66,15 +268,23 @@ type ContainerPort struct {
 type VolumeMount struct {
 	Name      string `yaml:"name"`
 	MountPath string `yaml:"mountPath"`
+	ReadOnly  bool   `yaml:"readOnly,omitempty"`
+	SubPath   string `yaml:"subPath,omitempty"`
 }
 
 type Volume struct {
-	Name     string    `yaml:"name"`
-	EmptyDir *EmptyDir `yaml:"emptyDir,omitempty"`
+	Name                  string                             `yaml:"name"`
+	EmptyDir              *EmptyDir                          `yaml:"emptyDir,omitempty"`
+	PersistentVolumeClaim *PersistentVolumeClaimVolumeSource `yaml:"persistentVolumeClaim,omitempty"`
+	ConfigMap             *ConfigMapVolumeSource             `yaml:"configMap,omitempty"`
 }
 
 type EmptyDir struct{}
 
+type PersistentVolumeClaimVolumeSource struct {
+	ClaimName string `yaml:"claimName"`
+}
+
 type Service struct {
 	APIVersion string      `yaml:"apiVersion"`
 	Kind       string      `yaml:"kind"`
@@ -83,9 +293,11 @@ type Service struct {
 }
 
 type ServiceSpec struct {
-	Selector map[string]string `yaml:"selector"`
-	Ports    []ServicePort     `yaml:"ports"`
-	Type     string            `yaml:"type,omitempty"`
+	// ClusterIP is "None" for the headless Service that governs a StatefulSet.
+	ClusterIP string            `yaml:"clusterIP,omitempty"`
+	Selector  map[string]string `yaml:"selector"`
+	Ports     []ServicePort     `yaml:"ports,omitempty"`
+	Type      string            `yaml:"type,omitempty"`
 }
 
 type ServicePort struct {
@@ -94,113 +306,304 @@ type ServicePort struct {
 	TargetPort int    `yaml:"targetPort"`
 }
 
-var depImages = map[string]string{
-	"postgres": "postgres",
-	"redis":    "redis",
+// Render returns the profile's Kubernetes objects as a multi-document YAML
+// stream.
+func Render(manifest *config.Manifest, profileName string, profile *config.Profile, opts RenderOptions) (string, error) {
+	docs, err := objects(manifest, profile, opts)
+	if err != nil {
+		return "", err
+	}
+
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	for i, doc := range docs {
+		if i > 0 {
+			_, _ = buf.WriteString("---\n")
+		}
+		if err := enc.Encode(doc); err != nil {
+			return "", err
+		}
+	}
+
+	return buf.String(), nil
+}
+
+// Resource is a rendered object in generic form, for output targets that
+// post-process objects.
+type Resource struct {
+	Kind   string
+	Name   string
+	Object map[string]any
+}
+
+// Resources renders the profile like Render and returns each object as a
+// Resource, in the same order.
+func Resources(manifest *config.Manifest, profileName string, profile *config.Profile, opts RenderOptions) ([]Resource, error) {
+	docs, err := objects(manifest, profile, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]Resource, 0, len(docs))
+	for _, doc := range docs {
+		data, err := yaml.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		var obj map[string]any
+		if err := yaml.Unmarshal(data, &obj); err != nil {
+			return nil, err
+		}
+		kind, _ := obj["kind"].(string)
+		meta, _ := obj["metadata"].(map[string]any)
+		name, _ := meta["name"].(string)
+		out = append(out, Resource{Kind: kind, Name: name, Object: obj})
+	}
+	return out, nil
 }
 
-func Render(manifest *config.Manifest, profileName string, profile *config.Profile, namespace string) (string, error) {
+func objects(manifest *config.Manifest, profile *config.Profile, opts RenderOptions) ([]any, error) {
 	if manifest == nil || profile == nil {
-		return "", fmt.Errorf("manifest and profile are required")
+		return nil, fmt.Errorf("manifest and profile are required")
 	}
 
 	var docs []any
-	project := sanitizeName(manifest.Project.Name)
+	namespace := opts.Namespace
+	project := manifest.Project.Name
+
+	for _, name := range config.ReferencedSecrets(profile) {
+		if opts.OmitSecrets {
+			break
+		}
+		value, ok := opts.SecretValues[name]
+		if !ok {
+			return nil, fmt.Errorf("secret '%s' has no resolved value", name)
+		}
+		docs = append(docs, Secret{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Metadata:   ObjectMeta{Name: AppName(project, name), Namespace: namespace, Labels: ownerLabels(project, nil)},
+			Type:       "Opaque",
+			StringData: map[string]string{SecretKey: string(value)},
+		})
+	}
 
+	// claims records the PersistentVolumeClaims already emitted, since a
+	// named volume may be shared by several services.
+	claims := map[string]bool{}
 	for _, name := range util.SortedKeys(profile.Services) {
 		svc := profile.Services[name]
 		if svc.Build != nil && svc.Image == "" {
-			return "", fmt.Errorf("service '%s' requires image for k8s render", name)
-		}
-		if len(svc.Mount) > 0 {
-			return "", fmt.Errorf("service '%s' uses mount which is not supported in k8s render", name)
+			return nil, fmt.Errorf("service '%s' requires image for k8s render", name)
 		}
 
 		image := svc.Image
 		if image == "" {
-			return "", fmt.Errorf("service '%s' requires image for k8s render", name)
+			return nil, fmt.Errorf("service '%s' requires image for k8s render", name)
 		}
 
-		labels := map[string]string{"app": project + "-" + sanitizeName(name)}
+		labels := map[string]string{"app": AppName(manifest.Project.Name, name)}
+		owned := ownerLabels(project, labels)
 		container := Container{
 			Name:       sanitizeName(name),
 			Image:      image,
 			Command:    svc.Command,
 			WorkingDir: svc.Workdir,
-			Env:        envVars(svc.Env),
+			Env:        envVars(project, svc.Env, svc.SecretEnv),
 			Ports:      containerPorts(svc.Ports),
+			Resources:  resourceRequirements(svc.Resources),
+		}
+		probe, err := renderProbe(svc.Health, svc.Ports)
+		if err != nil {
+			return nil, fmt.Errorf("service '%s' health: %w", name, err)
+		}
+		if svc.Expose != nil && svc.Expose.Port != 0 && !hasContainerPort(container.Ports, svc.Expose.Port) {
+			container.Ports = append(container.Ports, ContainerPort{ContainerPort: svc.Expose.Port})
+		}
+		container.ReadinessProbe = probe
+		container.LivenessProbe = livenessProbe(probe, container.Ports)
+
+		var volumes []Volume
+		for _, spec := range svc.Volumes {
+			vol, err := config.ParseVolume(spec)
+			if err != nil {
+				return nil, fmt.Errorf("service '%s' volume '%s' %w", name, spec, err)
+			}
+			claim := AppName(project, vol.Name)
+			if !claims[claim] {
+				claims[claim] = true
+				docs = append(docs, volumeClaim(claim, namespace, ownerLabels(project, map[string]string{"app": claim}), svc.Storage))
+			}
+			volName := sanitizeName(vol.Name)
+			volumes = append(volumes, Volume{Name: volName, PersistentVolumeClaim: &PersistentVolumeClaimVolumeSource{ClaimName: claim}})
+			container.VolumeMounts = append(container.VolumeMounts, VolumeMount{Name: volName, MountPath: vol.Path, ReadOnly: vol.ReadOnly})
+		}
+		for i, spec := range svc.Mount {
+			mount, err := config.ParseMount(spec)
+			if err != nil {
+				return nil, fmt.Errorf("service '%s' mount '%s' %w", name, spec, err)
+			}
+			if !mount.ReadOnly {
+				return nil, fmt.Errorf("service '%s' mount '%s' must be read-only (:ro) in k8s, where it is rendered as a ConfigMap", name, spec)
+			}
+			cm, volume, volumeMount, err := mountConfigMap(labels["app"], namespace, owned, opts.BaseDir, fmt.Sprintf("mount-%d", i), mount)
+			if err != nil {
+				return nil, fmt.Errorf("service '%s' mount '%s' %w", name, spec, err)
+			}
+			docs = append(docs, cm)
+			volumes = append(volumes, volume)
+			container.VolumeMounts = append(container.VolumeMounts, volumeMount)
 		}
 
-		docs = append(docs, Deployment{
+		podSpec := PodSpec{Volumes: volumes}
+		if sc := profile.SecurityContext; sc != nil {
+			if sc.RunAsNonRoot {
+				podSpec.SecurityContext = &PodSecurityContext{RunAsNonRoot: &sc.RunAsNonRoot}
+			}
+			if sc.ReadOnlyRootFilesystem {
+				// Most images still need somewhere to write temporary files.
+				container.SecurityContext = &SecurityContext{ReadOnlyRootFilesystem: &sc.ReadOnlyRootFilesystem}
+				podSpec.Volumes = append(podSpec.Volumes, Volume{Name: "devx-tmp", EmptyDir: &EmptyDir{}})
+				container.VolumeMounts = append(container.VolumeMounts, VolumeMount{Name: "devx-tmp", MountPath: "/tmp"})
+			}
+		}
+		podSpec.Containers = []Container{container}
+
+		replicas := svc.Replicas
+		if replicas == 0 {
+			replicas = 1
+		}
+		deployment := Deployment{
 			APIVersion: "apps/v1",
 			Kind:       "Deployment",
-			Metadata:   ObjectMeta{Name: labels["app"], Namespace: namespace, Labels: labels},
+			Metadata:   ObjectMeta{Name: labels["app"], Namespace: namespace, Labels: owned},
 			Spec: DeploymentSpec{
-				Replicas: 1,
+				Replicas: replicas,
 				Selector: LabelSelector{MatchLabels: labels},
 				Template: PodTemplateSpec{
 					Metadata: ObjectMeta{Labels: labels},
-					Spec:     PodSpec{Containers: []Container{container}},
+					Spec:     podSpec,
 				},
 			},
-		})
+		}
+		if len(svc.Volumes) > 0 {
+			deployment.Spec.Strategy = &DeploymentStrategy{Type: "Recreate"}
+		}
+		docs = append(docs, deployment)
 
 		if len(container.Ports) > 0 {
 			docs = append(docs, Service{
 				APIVersion: "v1",
 				Kind:       "Service",
-				Metadata:   ObjectMeta{Name: labels["app"], Namespace: namespace, Labels: labels},
+				Metadata:   ObjectMeta{Name: labels["app"], Namespace: namespace, Labels: owned},
 				Spec: ServiceSpec{
 					Selector: labels,
 					Ports:    servicePorts(container.Ports),
 				},
 			})
 		}
+
+		if svc.Expose != nil {
+			port := svc.Expose.Port
+			if port == 0 && len(container.Ports) > 0 {
+				port = container.Ports[0].ContainerPort
+			}
+			if port == 0 {
+				return nil, fmt.Errorf("service '%s' expose requires ports or expose.port", name)
+			}
+			docs = append(docs, exposeObject(labels["app"], namespace, owned, svc.Expose, port))
+		}
 	}
 
 	for _, name := range util.SortedKeys(profile.Deps) {
 		dep := profile.Deps[name]
-		image := depImages[dep.Kind]
-		if image == "" {
-			return "", fmt.Errorf("dep '%s' kind '%s' is not supported for k8s render", name, dep.Kind)
-		}
-		if dep.Version != "" {
-			image = image + ":" + dep.Version
+		kind, ok := deps.Lookup(dep.Kind)
+		if !ok {
+			return nil, fmt.Errorf("dep '%s' kind '%s' is not supported for k8s render", name, dep.Kind)
 		}
 
-		labels := map[string]string{"app": project + "-" + sanitizeName(name)}
+		labels := map[string]string{"app": AppName(manifest.Project.Name, name)}
+		owned := ownerLabels(project, labels)
 		container := Container{
-			Name:  sanitizeName(name),
-			Image: image,
-			Env:   envVars(dep.Env),
-			Ports: containerPorts(dep.Ports),
+			Name:      sanitizeName(name),
+			Image:     kind.ImageRef(dep.Version),
+			Command:   kind.Command,
+			Env:       envVars(project, kind.MergeEnv(labels["app"], dep.Env), dep.SecretEnv),
+			Ports:     containerPorts(dep.Ports),
+			Resources: resourceRequirements(dep.Resources),
+		}
+		if len(container.Ports) == 0 && kind.DefaultPort > 0 {
+			container.Ports = []ContainerPort{{ContainerPort: kind.DefaultPort}}
+		}
+		if kind.Readiness != "" {
+			container.ReadinessProbe = &Probe{
+				Exec:                &ExecAction{Command: []string{"sh", "-c", kind.Readiness}},
+				InitialDelaySeconds: 5,
+				PeriodSeconds:       5,
+				TimeoutSeconds:      5,
+			}
 		}
 
-		volumes, mounts, err := depVolumes(name, dep.Volume)
+		volume, err := kind.VolumeSpec(dep.Volume)
 		if err != nil {
-			return "", err
+			return nil, fmt.Errorf("dep '%s' volume: %w", name, err)
+		}
+		template := PodTemplateSpec{
+			Metadata: ObjectMeta{Labels: labels},
+			Spec:     PodSpec{Containers: []Container{container}},
 		}
-		container.VolumeMounts = mounts
 
-		docs = append(docs, Deployment{
-			APIVersion: "apps/v1",
-			Kind:       "Deployment",
-			Metadata:   ObjectMeta{Name: labels["app"], Namespace: namespace, Labels: labels},
-			Spec: DeploymentSpec{
-				Replicas: 1,
-				Selector: LabelSelector{MatchLabels: labels},
-				Template: PodTemplateSpec{
-					Metadata: ObjectMeta{Labels: labels},
-					Spec:     PodSpec{Containers: []Container{container}, Volumes: volumes},
+		if volume == "" {
+			docs = append(docs, Deployment{
+				APIVersion: "apps/v1",
+				Kind:       "Deployment",
+				Metadata:   ObjectMeta{Name: labels["app"], Namespace: namespace, Labels: owned},
+				Spec: DeploymentSpec{
+					Replicas: 1,
+					Selector: LabelSelector{MatchLabels: labels},
+					Template: template,
 				},
-			},
-		})
+			})
+		} else {
+			// A dep with a volume keeps its data in a claim created from the
+			// StatefulSet's volumeClaimTemplates, which outlives its pods. The
+			// headless Service gives its pod a stable DNS name.
+			claim, mount, err := depVolumeClaim(name, volume, owned, dep.Storage)
+			if err != nil {
+				return nil, err
+			}
+			template.Spec.Containers[0].VolumeMounts = []VolumeMount{mount}
+			headless := labels["app"] + "-headless"
+			docs = append(docs, Service{
+				APIVersion: "v1",
+				Kind:       "Service",
+				Metadata:   ObjectMeta{Name: headless, Namespace: namespace, Labels: owned},
+				Spec: ServiceSpec{
+					ClusterIP: "None",
+					Selector:  labels,
+					Ports:     servicePorts(container.Ports),
+				},
+			})
+			docs = append(docs, StatefulSet{
+				APIVersion: "apps/v1",
+				Kind:       "StatefulSet",
+				Metadata:   ObjectMeta{Name: labels["app"], Namespace: namespace, Labels: owned},
+				Spec: StatefulSetSpec{
+					ServiceName:          headless,
+					Replicas:             1,
+					Selector:             LabelSelector{MatchLabels: labels},
+					Template:             template,
+					VolumeClaimTemplates: []PersistentVolumeClaim{claim},
+				},
+			})
+		}
 
 		if len(container.Ports) > 0 {
 			docs = append(docs, Service{
 				APIVersion: "v1",
 				Kind:       "Service",
-				Metadata:   ObjectMeta{Name: labels["app"], Namespace: namespace, Labels: labels},
+				Metadata:   ObjectMeta{Name: labels["app"], Namespace: namespace, Labels: owned},
 				Spec: ServiceSpec{
 					Selector: labels,
 					Ports:    servicePorts(container.Ports),
@@ -209,33 +612,209 @@ func Render(manifest *config.Manifest, profileName string, profile *config.Profi
 		}
 	}
 
-	buf := &bytes.Buffer{}
-	enc := yaml.NewEncoder(buf)
-	enc.SetIndent(2)
-	for i, doc := range docs {
-		if i > 0 {
-			_, _ = buf.WriteString("---\n")
-		}
-		if err := enc.Encode(doc); err != nil {
-			return "", err
-		}
+	return docs, nil
+}
+
+// resourceRequirements maps a resources block to container requests and
+// limits.
+func resourceRequirements(res *config.Resources) *ResourceRequirements {
+	if res == nil {
+		return nil
+	}
+	out := &ResourceRequirements{
+		Requests: resourceList(res.CPU, res.Memory),
 	}
+	if res.Limits != nil {
+		out.Limits = resourceList(res.Limits.CPU, res.Limits.Memory)
+	}
+	if out.Requests == nil && out.Limits == nil {
+		return nil
+	}
+	return out
+}
 
-	return buf.String(), nil
+func resourceList(cpu string, memory string) map[string]string {
+	out := map[string]string{}
+	if cpu != "" {
+		out["cpu"] = cpu
+	}
+	if memory != "" {
+		out["memory"] = memory
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
 }
 
-func envVars(env map[string]string) []EnvVar {
-	if len(env) == 0 {
+// envVars renders literal env values and secretKeyRef entries for env vars
+// backed by a secret; a secret reference wins over a literal of the same name.
+func envVars(project string, env map[string]string, secretRefs map[string]string) []EnvVar {
+	if len(env) == 0 && len(secretRefs) == 0 {
 		return nil
 	}
 
-	vars := make([]EnvVar, 0, len(env))
+	vars := make([]EnvVar, 0, len(env)+len(secretRefs))
 	for _, key := range util.SortedKeys(env) {
+		if _, ok := secretRefs[key]; ok {
+			continue
+		}
 		vars = append(vars, EnvVar{Name: key, Value: env[key]})
 	}
+	for _, key := range util.SortedKeys(secretRefs) {
+		vars = append(vars, EnvVar{Name: key, ValueFrom: &EnvVarSource{
+			SecretKeyRef: &SecretKeySelector{Name: AppName(project, secretRefs[key]), Key: SecretKey},
+		}})
+	}
+	sort.Slice(vars, func(i, j int) bool { return vars[i].Name < vars[j].Name })
 	return vars
 }
 
+// renderProbe converts a service health config into the readiness probe;
+// livenessProbe derives the liveness probe from it. logMatch has no probe
+// equivalent and yields nil.
+func renderProbe(health *config.Health, ports []string) (*Probe, error) {
+	probe := &Probe{}
+	switch health.Type() {
+	case config.HealthHTTP:
+		port, path, err := health.HTTPTarget(ports)
+		if err != nil {
+			return nil, err
+		}
+		probe.HTTPGet = &HTTPGetAction{Path: path, Port: port}
+		if strings.HasPrefix(health.HttpGet, "https://") {
+			probe.HTTPGet.Scheme = "HTTPS"
+		}
+	case config.HealthTCP:
+		probe.TCPSocket = &TCPSocketAction{Port: health.TCP}
+	case config.HealthExec:
+		probe.Exec = &ExecAction{Command: []string{"sh", "-c", health.Exec}}
+	default:
+		return nil, nil
+	}
+
+	var err error
+	if probe.InitialDelaySeconds, err = durationSeconds(health.StartPeriod); err != nil {
+		return nil, err
+	}
+	if probe.PeriodSeconds, err = durationSeconds(health.Interval); err != nil {
+		return nil, err
+	}
+	if probe.TimeoutSeconds, err = durationSeconds(health.Timeout); err != nil {
+		return nil, err
+	}
+	probe.FailureThreshold = health.Retries
+	return probe, nil
+}
+
+// livenessGraceSeconds is added to the readiness probe's initial delay before
+// liveness is checked, so a slow start does not count as a failure.
+const livenessGraceSeconds = 30
+
+// livenessProbe derives the liveness probe from the readiness probe. A health
+// check that exercises the service's own dependencies would have every pod
+// restarted during an outage of one, so httpGet and exec checks are replaced
+// by a TCP check of the container port, and an exec check is kept only when
+// there is no port. Liveness also waits longer and tolerates twice as many
+// failures as readiness.
+func livenessProbe(readiness *Probe, ports []ContainerPort) *Probe {
+	if readiness == nil {
+		return nil
+	}
+	probe := *readiness
+	switch {
+	case readiness.HTTPGet != nil:
+		probe.HTTPGet = nil
+		probe.TCPSocket = &TCPSocketAction{Port: readiness.HTTPGet.Port}
+	case readiness.Exec != nil && len(ports) > 0:
+		probe.Exec = nil
+		probe.TCPSocket = &TCPSocketAction{Port: ports[0].ContainerPort}
+	}
+
+	probe.InitialDelaySeconds += livenessGraceSeconds
+	threshold := readiness.FailureThreshold
+	if threshold == 0 {
+		threshold = 3 // the Kubernetes default
+	}
+	probe.FailureThreshold = 2 * threshold
+	return &probe
+}
+
+// durationSeconds converts a duration string to whole seconds, rounding up.
+func durationSeconds(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	return int((d + time.Second - 1) / time.Second), nil
+}
+
+// exposeObject returns the Ingress, or the HTTPRoute when a gateway is set,
+// that routes expose.host and expose.path to the service's port.
+func exposeObject(app string, namespace string, labels map[string]string, expose *config.Expose, port int) any {
+	path := expose.Path
+	if path == "" {
+		path = "/"
+	}
+	meta := ObjectMeta{Name: app, Namespace: namespace, Labels: labels}
+
+	if expose.Gateway != "" {
+		parent := ParentReference{Name: expose.Gateway}
+		if ns, name, ok := strings.Cut(expose.Gateway, "/"); ok {
+			parent = ParentReference{Namespace: ns, Name: name}
+		}
+		return HTTPRoute{
+			APIVersion: "gateway.networking.k8s.io/v1",
+			Kind:       "HTTPRoute",
+			Metadata:   meta,
+			Spec: HTTPRouteSpec{
+				ParentRefs: []ParentReference{parent},
+				Hostnames:  []string{expose.Host},
+				Rules: []HTTPRouteRule{{
+					Matches:     []HTTPRouteMatch{{Path: HTTPPathMatch{Type: "PathPrefix", Value: path}}},
+					BackendRefs: []HTTPBackendRef{{Name: app, Port: port}},
+				}},
+			},
+		}
+	}
+
+	ingress := Ingress{
+		APIVersion: "networking.k8s.io/v1",
+		Kind:       "Ingress",
+		Metadata:   meta,
+		Spec: IngressSpec{
+			IngressClassName: expose.IngressClass,
+			Rules: []IngressRule{{
+				Host: expose.Host,
+				HTTP: HTTPIngressRuleValue{Paths: []HTTPIngressPath{{
+					Path:     path,
+					PathType: "Prefix",
+					Backend: IngressBackend{Service: IngressServiceBackend{
+						Name: app,
+						Port: ServiceBackendPort{Number: port},
+					}},
+				}}},
+			}},
+		},
+	}
+	if expose.TLSSecret != "" {
+		ingress.Spec.TLS = []IngressTLS{{Hosts: []string{expose.Host}, SecretName: expose.TLSSecret}}
+	}
+	return ingress
+}
+
+func hasContainerPort(ports []ContainerPort, port int) bool {
+	for _, p := range ports {
+		if p.ContainerPort == port {
+			return true
+		}
+	}
+	return false
+}
+
 func containerPorts(ports []string) []ContainerPort {
 	var out []ContainerPort
 	seen := map[int]bool{}
@@ -272,19 +851,68 @@ func parseContainerPort(port string) (int, error) {
 	return value, nil
 }
 
-func depVolumes(depName string, volume string) ([]Volume, []VolumeMount, error) {
-	if volume == "" {
-		return nil, nil, nil
-	}
-
+// depVolumeClaim returns the claim template and mount for a dep volume in
+// name:/path format.
+func depVolumeClaim(depName string, volume string, labels map[string]string, storage config.Storage) (PersistentVolumeClaim, VolumeMount, error) {
 	parts := strings.SplitN(volume, ":", 2)
 	if len(parts) != 2 {
-		return nil, nil, fmt.Errorf("dep '%s' volume must be in name:/path format", depName)
+		return PersistentVolumeClaim{}, VolumeMount{}, fmt.Errorf("dep '%s' volume must be in name:/path format", depName)
+	}
+
+	volName := sanitizeName(parts[0])
+	claim := volumeClaim(volName, "", labels, storage)
+	claim.APIVersion, claim.Kind = "", ""
+	return claim, VolumeMount{Name: volName, MountPath: parts[1]}, nil
+}
+
+func volumeClaim(name string, namespace string, labels map[string]string, storage config.Storage) PersistentVolumeClaim {
+	size := storage.Size
+	if size == "" {
+		size = DefaultStorageSize
+	}
+	return PersistentVolumeClaim{
+		APIVersion: "v1",
+		Kind:       "PersistentVolumeClaim",
+		Metadata:   ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec: PersistentVolumeClaimSpec{
+			AccessModes:      []string{"ReadWriteOnce"},
+			StorageClassName: storage.Class,
+			Resources:        ResourceRequirements{Requests: map[string]string{"storage": size}},
+		},
+	}
+}
+
+// Labels written on every rendered object, so that devx can prune and delete
+// the objects it owns without the manifest they came from.
+const (
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	PartOfLabel    = "app.kubernetes.io/part-of"
+)
+
+// OwnerSelector returns the label selector matching the objects rendered for
+// a project.
+func OwnerSelector(project string) string {
+	return fmt.Sprintf("%s=devx,%s=%s", ManagedByLabel, PartOfLabel, sanitizeName(project))
+}
+
+// ownerLabels returns labels plus the ownership labels of project.
+func ownerLabels(project string, labels map[string]string) map[string]string {
+	out := map[string]string{ManagedByLabel: "devx", PartOfLabel: sanitizeName(project)}
+	for key, value := range labels {
+		out[key] = value
 	}
+	return out
+}
+
+// AppName returns the value of the `app` label (and object name) used for a
+// service or dep of the given project.
+func AppName(project string, name string) string {
+	return AppPrefix(project) + sanitizeName(name)
+}
 
-	volName := sanitizeName(depName + "-data")
-	mountPath := parts[1]
-	return []Volume{{Name: volName, EmptyDir: &EmptyDir{}}}, []VolumeMount{{Name: volName, MountPath: mountPath}}, nil
+// AppPrefix returns the prefix shared by the app labels of a project.
+func AppPrefix(project string) string {
+	return sanitizeName(project) + "-"
 }
 
 func sanitizeName(value string) string {
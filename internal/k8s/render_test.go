@@ -1,6 +1,8 @@
 package k8s
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -21,7 +23,7 @@ func TestRenderK8s(t *testing.T) {
 		},
 	}
 
-	out, err := Render(manifest, "local", profile, "")
+	out, err := Render(manifest, "local", profile, RenderOptions{})
 	if err != nil {
 		t.Fatalf("render failed: %v", err)
 	}
@@ -39,3 +41,386 @@ func TestRenderK8s(t *testing.T) {
 		t.Fatalf("expected dep image in output")
 	}
 }
+
+func TestRenderK8sDepDefaults(t *testing.T) {
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "local"},
+	}
+	profile := &config.Profile{
+		Deps: map[string]config.Dep{
+			"queue": {Kind: "rabbitmq", Version: "3", Volume: "queue-data"},
+		},
+	}
+
+	out, err := Render(manifest, "local", profile, RenderOptions{})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	if !strings.Contains(out, "image: rabbitmq:3") {
+		t.Fatalf("expected registry image in output")
+	}
+	if !strings.Contains(out, "containerPort: 5672") {
+		t.Fatalf("expected default port in output")
+	}
+	if !strings.Contains(out, "mountPath: /var/lib/rabbitmq") {
+		t.Fatalf("expected default data path in output")
+	}
+	if !strings.Contains(out, "- rabbitmq-diagnostics -q ping") {
+		t.Fatalf("expected readiness probe in output")
+	}
+}
+
+func TestRenderK8sSecrets(t *testing.T) {
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "local"},
+		Secrets: map[string]config.Secret{"db-password": {Env: "DB_PASSWORD"}},
+	}
+	profile := &config.Profile{
+		Deps: map[string]config.Dep{
+			"db": {Kind: "postgres", SecretEnv: map[string]string{"POSTGRES_PASSWORD": "db-password"}},
+		},
+	}
+
+	out, err := Render(manifest, "local", profile, RenderOptions{
+		SecretValues: map[string][]byte{"db-password": []byte("s3cret")},
+	})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	if !strings.Contains(out, "kind: Secret") || !strings.Contains(out, "value: s3cret") {
+		t.Fatalf("expected Secret object in output:\n%s", out)
+	}
+	if !strings.Contains(out, "secretKeyRef:\n                  name: my-app-db-password\n                  key: value") {
+		t.Fatalf("expected secretKeyRef env in output:\n%s", out)
+	}
+	if strings.Contains(out, "value: postgres") {
+		t.Fatalf("default password must not be rendered as a literal:\n%s", out)
+	}
+
+	if _, err := Render(manifest, "local", profile, RenderOptions{}); err == nil {
+		t.Fatalf("expected error for unresolved secret")
+	}
+}
+
+func TestRenderK8sProbes(t *testing.T) {
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "local"},
+	}
+	profile := &config.Profile{
+		Services: map[string]config.Service{
+			"api": {
+				Image:  "nginx:alpine",
+				Ports:  []string{"8080:80"},
+				Health: &config.Health{HttpGet: "http://localhost:8080/health", Interval: "5s", StartPeriod: "1500ms", Retries: 4},
+			},
+			"worker": {Image: "worker", Health: &config.Health{Exec: "test -f /tmp/ready"}},
+			"queue":  {Image: "queue", Ports: []string{"5672"}, Health: &config.Health{Exec: "queue-ping"}},
+		},
+	}
+
+	out, err := Render(manifest, "local", profile, RenderOptions{})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"readinessProbe:\n            httpGet:\n              path: /health\n              port: 80",
+		"initialDelaySeconds: 2\n            periodSeconds: 5\n            failureThreshold: 4",
+		// Liveness checks the port only, starts later and tolerates more
+		// failures, so a failing dependency does not restart the pods.
+		"livenessProbe:\n            tcpSocket:\n              port: 80\n            initialDelaySeconds: 32\n            periodSeconds: 5\n            failureThreshold: 8",
+		"exec:\n              command:\n                - sh\n                - -c\n                - test -f /tmp/ready",
+		"- test -f /tmp/ready\n            initialDelaySeconds: 30\n            failureThreshold: 6",
+		"livenessProbe:\n            tcpSocket:\n              port: 5672\n            initialDelaySeconds: 30",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderK8sPersistentVolumes(t *testing.T) {
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "local"},
+	}
+	profile := &config.Profile{
+		Services: map[string]config.Service{
+			"api":    {Image: "api", Volumes: []string{"uploads:/data/uploads"}, Storage: config.Storage{Size: "5Gi"}},
+			"worker": {Image: "worker", Volumes: []string{"uploads:/uploads:ro"}},
+		},
+		Deps: map[string]config.Dep{
+			"db":    {Kind: "postgres", Version: "16", Volume: "db-data", Storage: config.Storage{Class: "fast", Size: "10Gi"}},
+			"cache": {Kind: "redis"},
+		},
+	}
+
+	out, err := Render(manifest, "local", profile, RenderOptions{})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	if strings.Contains(out, "emptyDir") {
+		t.Fatalf("expected no emptyDir volumes:\n%s", out)
+	}
+	if strings.Count(out, "kind: PersistentVolumeClaim") != 1 || !strings.Contains(out, "name: my-app-uploads") {
+		t.Fatalf("expected one shared claim for the uploads volume:\n%s", out)
+	}
+	for _, want := range []string{
+		"storage: 5Gi",
+		"claimName: my-app-uploads",
+		"readOnly: true",
+		"type: Recreate",
+		"kind: StatefulSet\nmetadata:\n  name: my-app-db",
+		"serviceName: my-app-db-headless\n",
+		"kind: Service\nmetadata:\n  name: my-app-db-headless",
+		"clusterIP: None",
+		"volumeClaimTemplates:\n    - metadata:\n        name: db-data",
+		"storageClassName: fast",
+		"storage: 10Gi",
+		"mountPath: /var/lib/postgresql/data",
+		"kind: Deployment\nmetadata:\n  name: my-app-cache",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderK8sExpose(t *testing.T) {
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "k8s"},
+	}
+	profile := &config.Profile{
+		Services: map[string]config.Service{
+			"api": {
+				Image:  "api",
+				Ports:  []string{"8080:80"},
+				Expose: &config.Expose{Host: "api.dev.example.com", TLSSecret: "api-tls", IngressClass: "nginx"},
+			},
+			"web": {
+				Image:  "web",
+				Expose: &config.Expose{Host: "dev.example.com", Path: "/app", Port: 3000, Gateway: "infra/public"},
+			},
+		},
+	}
+
+	out, err := Render(manifest, "k8s", profile, RenderOptions{})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"apiVersion: networking.k8s.io/v1\nkind: Ingress",
+		"ingressClassName: nginx",
+		"- hosts:\n        - api.dev.example.com\n      secretName: api-tls",
+		"host: api.dev.example.com",
+		"name: my-app-api\n                port:\n                  number: 80",
+		"apiVersion: gateway.networking.k8s.io/v1\nkind: HTTPRoute",
+		"parentRefs:\n    - name: public\n      namespace: infra",
+		"type: PathPrefix\n            value: /app",
+		"- name: my-app-web\n          port: 3000",
+		"containerPort: 3000",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderK8sResourcesAndSecurity(t *testing.T) {
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "k8s"},
+	}
+	profile := &config.Profile{
+		SecurityContext: &config.SecurityContext{RunAsNonRoot: true, ReadOnlyRootFilesystem: true},
+		Services: map[string]config.Service{
+			"api": {
+				Image:     "api",
+				Replicas:  3,
+				Resources: &config.Resources{CPU: "250m", Memory: "256Mi", Limits: &config.ResourceLimits{Memory: "512Mi"}},
+			},
+		},
+		Deps: map[string]config.Dep{
+			"db": {Kind: "postgres", Resources: &config.Resources{CPU: "1"}},
+		},
+	}
+
+	out, err := Render(manifest, "k8s", profile, RenderOptions{})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"part-of: my-app\nspec:\n  replicas: 3",
+		"resources:\n            requests:\n              cpu: 250m\n              memory: 256Mi\n            limits:\n              memory: 512Mi",
+		"securityContext:\n            readOnlyRootFilesystem: true",
+		"securityContext:\n        runAsNonRoot: true",
+		"mountPath: /tmp",
+		"emptyDir: {}",
+		"resources:\n            requests:\n              cpu: \"1\"",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+	if strings.Count(out, "runAsNonRoot") != 1 {
+		t.Errorf("expected the security context on services only:\n%s", out)
+	}
+}
+
+func TestRenderK8sMounts(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "config", "app.yaml"), "level: debug\n")
+	writeFile(t, filepath.Join(dir, "config", "tls", "ca.der"), "\xff\xfe")
+	writeFile(t, filepath.Join(dir, "config", ".git", "HEAD"), "ref: refs/heads/main\n")
+	writeFile(t, filepath.Join(dir, "config", ".app.yaml.swp"), "swap")
+	writeFile(t, filepath.Join(dir, "nginx.conf"), "server {}\n")
+	writeFile(t, filepath.Join(dir, "my config.yml"), "a: 1\n")
+
+	manifest := &config.Manifest{
+		Version: 1,
+		Project: config.Project{Name: "my-app", DefaultProfile: "k8s"},
+	}
+	profile := &config.Profile{
+		Services: map[string]config.Service{
+			"api": {Image: "api", Mount: []string{"./config:/etc/app:ro", "nginx.conf:/etc/nginx/nginx.conf:ro,z"}},
+		},
+	}
+
+	out, err := Render(manifest, "k8s", profile, RenderOptions{BaseDir: dir})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if strings.Count(out, "kind: ConfigMap") != 2 {
+		t.Fatalf("expected a ConfigMap per mount:\n%s", out)
+	}
+	for _, want := range []string{
+		"app.yaml: |\n    level: debug",
+		"binaryData:\n  f1-ca.der: //4=",
+		"- key: f0-app.yaml\n                path: app.yaml",
+		"- key: f1-ca.der\n                path: tls/ca.der",
+		"name: mount-0\n              mountPath: /etc/app\n              readOnly: true",
+		"mountPath: /etc/nginx/nginx.conf\n              readOnly: true\n              subPath: nginx.conf",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+	for _, unwanted := range []string{"HEAD", "refs/heads", "swp"} {
+		if strings.Contains(out, unwanted) {
+			t.Errorf("expected dot-directories and swap files to be skipped, found %q:\n%s", unwanted, out)
+		}
+	}
+	if strings.Contains(out, "Recreate") {
+		t.Errorf("ConfigMap mounts must not force the Recreate strategy:\n%s", out)
+	}
+
+	// The ConfigMap name follows the content.
+	writeFile(t, filepath.Join(dir, "nginx.conf"), "server { listen 80; }\n")
+	changed, err := Render(manifest, "k8s", profile, RenderOptions{BaseDir: dir})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if configMapNames(changed)[1] == configMapNames(out)[1] {
+		t.Errorf("expected a new ConfigMap name after the file changed")
+	}
+
+	// A file name that is not a valid key is sanitized and mapped back.
+	profile.Services["api"] = config.Service{Image: "api", Mount: []string{"my config.yml:/etc/app/config.yml:ro"}}
+	spaced, err := Render(manifest, "k8s", profile, RenderOptions{BaseDir: dir})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	for _, want := range []string{
+		"my_config.yml: |\n    a: 1",
+		"- key: my_config.yml\n                path: my config.yml",
+		"subPath: my config.yml",
+	} {
+		if !strings.Contains(spaced, want) {
+			t.Errorf("expected %q in output:\n%s", want, spaced)
+		}
+	}
+
+	profile.Services["api"] = config.Service{Image: "api", Mount: []string{"./config:/etc/app"}}
+	if _, err := Render(manifest, "k8s", profile, RenderOptions{BaseDir: dir}); err == nil || !strings.Contains(err.Error(), "'./config:/etc/app' must be read-only") {
+		t.Errorf("expected writable mount error, got %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "big.bin"), strings.Repeat("x", MaxConfigMapSize+1))
+	profile.Services["api"] = config.Service{Image: "api", Mount: []string{"big.bin:/data/big.bin:ro"}}
+	if _, err := Render(manifest, "k8s", profile, RenderOptions{BaseDir: dir}); err == nil || !strings.Contains(err.Error(), "'big.bin:/data/big.bin:ro' is") {
+		t.Errorf("expected oversized mount error, got %v", err)
+	}
+}
+
+func TestRenderK8sLiteralDollar(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".env": "DB_PASSWORD=pa$word\n",
+		"devx.yaml": `version: 1
+project:
+  name: my-app
+  defaultProfile: local
+profiles:
+  local:
+    services:
+      api:
+        image: api
+        command: ["sh", "-c", "echo $$HOME"]
+        env:
+          DB_PASSWORD: ${DB_PASSWORD}
+          GREETING: hello $${USER}
+`,
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	manifest, err := config.Load(filepath.Join(dir, "devx.yaml"))
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	profile := manifest.Profiles["local"]
+
+	out, err := Render(manifest, "local", &profile, RenderOptions{})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	for _, want := range []string{
+		"name: DB_PASSWORD\n              value: pa$word\n",
+		"name: GREETING\n              value: hello ${USER}\n",
+		"- echo $HOME\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func configMapNames(out string) []string {
+	var names []string
+	for _, doc := range strings.Split(out, "---\n") {
+		if strings.Contains(doc, "kind: ConfigMap") {
+			names = append(names, strings.Fields(doc[strings.Index(doc, "name: "):])[1])
+		}
+	}
+	return names
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,176 @@
+package lock
+
+import (
+	"regexp"
+	"strings"
+)
+
+// fromInstruction is a FROM line of a Dockerfile, with the image reference
+// expanded from the ARGs declared before the first FROM.
+type fromInstruction struct {
+	start, end int // byte range of the image reference in the Dockerfile
+	image      string
+}
+
+var argRef = regexp.MustCompile(`\$(?:\{([A-Za-z_][A-Za-z0-9_]*)\}|([A-Za-z_][A-Za-z0-9_]*))`)
+
+// BaseImages returns the external images the FROM lines of a Dockerfile build
+// on, in order and without duplicates. References to earlier stages, scratch
+// and images that depend on an ARG without a default are skipped.
+func BaseImages(dockerfile []byte) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, from := range parseFroms(string(dockerfile)) {
+		if !seen[from.image] {
+			seen[from.image] = true
+			out = append(out, from.image)
+		}
+	}
+	return out
+}
+
+// RewriteBaseImages replaces the image of every FROM line that BaseImages
+// reports with rewrite(image), and reports whether anything changed.
+func RewriteBaseImages(dockerfile []byte, rewrite func(string) string) ([]byte, bool) {
+	text := string(dockerfile)
+	var out strings.Builder
+	last := 0
+	changed := false
+	for _, from := range parseFroms(text) {
+		image := rewrite(from.image)
+		if image == from.image {
+			continue
+		}
+		out.WriteString(text[last:from.start])
+		out.WriteString(image)
+		last = from.end
+		changed = true
+	}
+	if !changed {
+		return dockerfile, false
+	}
+	out.WriteString(text[last:])
+	return []byte(out.String()), true
+}
+
+func parseFroms(text string) []fromInstruction {
+	var out []fromInstruction
+	args := map[string]string{}
+	stages := map[string]bool{}
+	inStage := false
+
+	for _, line := range logicalLines(text) {
+		fields := strings.Fields(line.text)
+		if len(fields) == 0 {
+			continue
+		}
+		switch strings.ToUpper(fields[0]) {
+		case "ARG":
+			// Only ARGs declared before the first FROM are in scope for FROM.
+			if inStage {
+				continue
+			}
+			for _, decl := range fields[1:] {
+				name, value, ok := strings.Cut(decl, "=")
+				if !ok {
+					continue
+				}
+				args[name] = strings.Trim(value, `"'`)
+			}
+		case "FROM":
+			inStage = true
+			idx := 1
+			for idx < len(fields) && strings.HasPrefix(fields[idx], "--") {
+				idx++
+			}
+			if idx >= len(fields) {
+				continue
+			}
+			image, ok := expandArgs(fields[idx], args)
+			external := ok && image != "scratch" && !stages[strings.ToLower(image)]
+			if len(fields) >= idx+3 && strings.EqualFold(fields[idx+1], "AS") {
+				stages[strings.ToLower(fields[idx+2])] = true
+			}
+			if !external {
+				continue
+			}
+			pos := indexField(line.text, idx)
+			out = append(out, fromInstruction{
+				start: line.offsets[pos],
+				end:   line.offsets[pos+len(fields[idx])-1] + 1,
+				image: image,
+			})
+		}
+	}
+	return out
+}
+
+// indexField returns the byte offset in s of its n-th whitespace-separated
+// field.
+func indexField(s string, n int) int {
+	pos := 0
+	for i := 0; ; i++ {
+		trimmed := strings.TrimLeft(s[pos:], " \t")
+		pos += len(s[pos:]) - len(trimmed)
+		if i == n {
+			return pos
+		}
+		pos += len(strings.Fields(trimmed)[0])
+	}
+}
+
+func expandArgs(raw string, args map[string]string) (string, bool) {
+	ok := true
+	image := argRef.ReplaceAllStringFunc(raw, func(ref string) string {
+		m := argRef.FindStringSubmatch(ref)
+		name := m[1] + m[2]
+		value, found := args[name]
+		if !found || value == "" {
+			ok = false
+		}
+		return value
+	})
+	// Anything left unexpanded, such as ${NAME:-default}, cannot be pinned.
+	return image, ok && image != "" && !strings.Contains(image, "$")
+}
+
+type logicalLine struct {
+	text    string
+	offsets []int // byte offset in the Dockerfile of each byte of text
+}
+
+// logicalLines splits a Dockerfile into instructions, dropping comments and
+// blank lines. Lines continued with a trailing backslash are joined, and
+// comments between them are skipped, as Docker does.
+func logicalLines(text string) []logicalLine {
+	var out []logicalLine
+	var cur *logicalLine
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		start := offset
+		offset += len(line)
+		content := strings.TrimRight(line, "\r\n")
+		trimmed := strings.TrimSpace(content)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		body := strings.TrimRight(content, " \t")
+		continued := strings.HasSuffix(body, `\`)
+		body = strings.TrimSuffix(body, `\`)
+		if cur == nil {
+			cur = &logicalLine{}
+		}
+		cur.text += body
+		for i := 0; i < len(body); i++ {
+			cur.offsets = append(cur.offsets, start+i)
+		}
+		if !continued {
+			out = append(out, *cur)
+			cur = nil
+		}
+	}
+	if cur != nil {
+		out = append(out, *cur)
+	}
+	return out
+}
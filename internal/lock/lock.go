@@ -4,42 +4,83 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"regexp"
 	"strings"
+
+	"github.com/dever-labs/devx/internal/util"
 )
 
+// Version is the lockfile schema version Save writes. Version 1 mapped each
+// image straight to the digest of whatever image the local runtime held.
+const Version = 2
+
 type Lockfile struct {
-	Version int               `json:"version"`
-	Images  map[string]string `json:"images"`
+	Version int              `json:"version"`
+	Images  map[string]Entry `json:"images"`
+}
+
+// Entry pins an image. Digest is the digest of the manifest its tag points at
+// in the registry; for multi-arch images that is the manifest list, which is
+// the same on every machine. Platforms maps "os/arch[/variant]" to the digest
+// of that platform's image in the list.
+type Entry struct {
+	Digest    string            `json:"digest"`
+	Platforms map[string]string `json:"platforms,omitempty"`
 }
 
 func New() *Lockfile {
-	return &Lockfile{Version: 1, Images: map[string]string{}}
+	return &Lockfile{Version: Version, Images: map[string]Entry{}}
 }
 
+// Load reads a lockfile, migrating version 1 files to the current schema.
+// The returned Version is the one read, so callers can tell a migration
+// happened.
 func Load(path string) (*Lockfile, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
-	var lf Lockfile
-	if err := json.Unmarshal(data, &lf); err != nil {
+	var header struct {
+		Version int             `json:"version"`
+		Images  json.RawMessage `json:"images"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
 		return nil, err
 	}
 
-	if lf.Images == nil {
-		lf.Images = map[string]string{}
+	lf := &Lockfile{Version: header.Version, Images: map[string]Entry{}}
+	if len(header.Images) == 0 || string(header.Images) == "null" {
+		return lf, nil
 	}
 
-	return &lf, nil
+	switch header.Version {
+	case 1:
+		var digests map[string]string
+		if err := json.Unmarshal(header.Images, &digests); err != nil {
+			return nil, err
+		}
+		for image, digest := range digests {
+			lf.Images[image] = Entry{Digest: digest}
+		}
+	case Version:
+		if err := json.Unmarshal(header.Images, &lf.Images); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%s has unsupported version %d", path, header.Version)
+	}
+
+	return lf, nil
 }
 
+// Save writes lf in the current schema version.
 func Save(path string, lf *Lockfile) error {
-	data, err := json.MarshalIndent(lf, "", "  ")
+	data, err := json.MarshalIndent(Lockfile{Version: Version, Images: lf.Images}, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	return os.WriteFile(path, append(data, '\n'), 0644)
 }
 
 func Apply(image string, lf *Lockfile) string {
@@ -50,13 +91,13 @@ func Apply(image string, lf *Lockfile) string {
 		return image
 	}
 
-	digest, ok := lf.Images[image]
-	if !ok || digest == "" {
+	entry, ok := lf.Images[image]
+	if !ok || entry.Digest == "" {
 		return image
 	}
 
 	base := stripTag(image)
-	return fmt.Sprintf("%s@%s", base, digest)
+	return fmt.Sprintf("%s@%s", base, entry.Digest)
 }
 
 func stripTag(image string) string {
@@ -72,3 +113,72 @@ func stripTag(image string) string {
 
 	return image
 }
+
+var digestPattern = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+
+// Issue reasons. Verify reports the first three for single images; the
+// others describe the lockfile as a whole.
+const (
+	IssueMissing         = "missing"
+	IssueMismatch        = "mismatch"
+	IssueInvalid         = "invalid"
+	IssueNoLockfile      = "no-lockfile"
+	IssueOutdatedVersion = "outdated-version"
+)
+
+// Issue is a reason the lockfile does not pin an image. Image is empty for
+// issues with the lockfile as a whole.
+type Issue struct {
+	Image   string `json:"image"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+// Verify checks that every image is pinned by lf and returns an issue for each
+// image that is not. Images that already carry a digest only need to agree
+// with the lockfile if it has an entry for them, either on the manifest list
+// or on one of its platforms.
+func Verify(lf *Lockfile, images []string) []Issue {
+	var issues []Issue
+	add := func(image string, reason string, format string, args ...any) {
+		issues = append(issues, Issue{Image: image, Reason: reason, Message: fmt.Sprintf(format, args...)})
+	}
+	seen := map[string]bool{}
+	for _, image := range images {
+		if image == "" || seen[image] {
+			continue
+		}
+		seen[image] = true
+
+		entry, locked := lf.Images[image]
+		_, pinned, hasDigest := strings.Cut(image, "@")
+		switch {
+		case hasDigest && locked && !entry.matches(pinned):
+			add(image, IssueMismatch, "image '%s' is locked to %s", image, entry.Digest)
+		case hasDigest:
+		case !locked:
+			add(image, IssueMissing, "image '%s' is not in the lockfile", image)
+		case !digestPattern.MatchString(entry.Digest):
+			add(image, IssueInvalid, "image '%s' is locked to '%s', which is not a sha256 digest", image, entry.Digest)
+		default:
+			for _, platform := range util.SortedKeys(entry.Platforms) {
+				if digest := entry.Platforms[platform]; !digestPattern.MatchString(digest) {
+					add(image, IssueInvalid, "image '%s' platform %s is locked to '%s', which is not a sha256 digest", image, platform, digest)
+				}
+			}
+		}
+	}
+	return issues
+}
+
+func (e Entry) matches(digest string) bool {
+	if e.Digest == digest {
+		return true
+	}
+	for _, d := range e.Platforms {
+		if d == digest {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,171 @@
+package lock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBaseImages(t *testing.T) {
+	dockerfile := `# syntax=docker/dockerfile:1
+ARG GO_VERSION=1.22
+ARG REGISTRY
+FROM --platform=$BUILDPLATFORM golang:${GO_VERSION} AS build
+ARG LATE=ignored
+RUN go build \
+    -o /app
+FROM build AS test
+FROM $REGISTRY/tools:1 AS tools
+from scratch
+FROM gcr.io/distroless/static:nonroot
+COPY --from=build /app /app
+FROM golang:1.22
+FROM \
+  # the web stage
+  node:20 AS web
+`
+	got := BaseImages([]byte(dockerfile))
+	want := []string{"golang:1.22", "gcr.io/distroless/static:nonroot", "node:20"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestRewriteBaseImages(t *testing.T) {
+	dockerfile := "ARG BASE=alpine:3.19\nFROM ${BASE} AS base\nFROM base\nFROM   node:20  as web\n"
+	got, changed := RewriteBaseImages([]byte(dockerfile), func(image string) string {
+		if image == "node:20" {
+			return "node@sha256:abc"
+		}
+		return image
+	})
+	want := "ARG BASE=alpine:3.19\nFROM ${BASE} AS base\nFROM base\nFROM   node@sha256:abc  as web\n"
+	if !changed || string(got) != want {
+		t.Errorf("expected %q, got %q (changed %v)", want, got, changed)
+	}
+
+	if _, changed := RewriteBaseImages([]byte(dockerfile), func(image string) string { return image }); changed {
+		t.Error("expected no change for an identity rewrite")
+	}
+
+	// Continued instructions are joined, and the rewrite lands on the
+	// original line.
+	continued := "ARG GO_VERSION=1.22 \\\n    DEBIAN=bookworm\nFROM \\\n  --platform=$BUILDPLATFORM golang:${GO_VERSION}-${DEBIAN} AS build\n"
+	if images := BaseImages([]byte(continued)); !reflect.DeepEqual(images, []string{"golang:1.22-bookworm"}) {
+		t.Errorf("expected the continued FROM to be read, got %v", images)
+	}
+	got, changed = RewriteBaseImages([]byte(continued), func(image string) string { return "golang@sha256:abc" })
+	want = "ARG GO_VERSION=1.22 \\\n    DEBIAN=bookworm\nFROM \\\n  --platform=$BUILDPLATFORM golang@sha256:abc AS build\n"
+	if !changed || string(got) != want {
+		t.Errorf("expected %q, got %q (changed %v)", want, got, changed)
+	}
+}
+
+func TestApply(t *testing.T) {
+	lf := &Lockfile{Version: Version, Images: map[string]Entry{"registry:5000/app:1.0": {Digest: "sha256:abc"}}}
+	if got := Apply("registry:5000/app:1.0", lf); got != "registry:5000/app@sha256:abc" {
+		t.Errorf("unexpected pinned image %q", got)
+	}
+	if got := Apply("app:2.0", lf); got != "app:2.0" {
+		t.Errorf("expected unlocked image unchanged, got %q", got)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+	other := "sha256:" + strings.Repeat("b", 64)
+	lf := &Lockfile{Version: Version, Images: map[string]Entry{
+		"postgres:16":     {Digest: digest, Platforms: map[string]string{"linux/amd64": other}},
+		"redis:7":         {Digest: "latest"},
+		"api@" + other:    {Digest: digest},
+		"nginx@" + digest: {Digest: digest},
+		"node@" + other:   {Digest: digest, Platforms: map[string]string{"linux/arm64": other}},
+		"mysql:8.4":       {Digest: digest, Platforms: map[string]string{"linux/amd64": "sha256:short"}},
+	}}
+
+	got := Verify(lf, []string{"postgres:16", "postgres:16", "redis:7", "mysql:8", "mysql:8.4", "api@" + other, "nginx@" + digest, "node@" + other, "busybox@" + other})
+	want := []Issue{
+		{Image: "redis:7", Reason: IssueInvalid, Message: "image 'redis:7' is locked to 'latest', which is not a sha256 digest"},
+		{Image: "mysql:8", Reason: IssueMissing, Message: "image 'mysql:8' is not in the lockfile"},
+		{Image: "mysql:8.4", Reason: IssueInvalid, Message: "image 'mysql:8.4' platform linux/amd64 is locked to 'sha256:short', which is not a sha256 digest"},
+		{Image: "api@" + other, Reason: IssueMismatch, Message: "image 'api@" + other + "' is locked to " + digest},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestLoadMigratesVersion1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "devx.lock")
+	if err := os.WriteFile(path, []byte(`{"version": 1, "images": {"redis:7": "sha256:abc"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	lf, err := Load(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if lf.Version != 1 || !reflect.DeepEqual(lf.Images, map[string]Entry{"redis:7": {Digest: "sha256:abc"}}) {
+		t.Fatalf("unexpected migrated lockfile %+v", lf)
+	}
+
+	if err := Save(path, lf); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{\n  \"version\": 2,\n  \"images\": {\n    \"redis:7\": {\n      \"digest\": \"sha256:abc\"\n    }\n  }\n}\n"
+	if string(data) != want {
+		t.Errorf("expected %s, got %s", want, data)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 3, "images": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "unsupported version 3") {
+		t.Errorf("expected unsupported version error, got %v", err)
+	}
+}
+
+func TestEntryFromManifest(t *testing.T) {
+	index := []byte(`{
+  "schemaVersion": 2,
+  "mediaType": "application/vnd.oci.image.index.v1+json",
+  "manifests": [
+    {"digest": "sha256:amd", "platform": {"os": "linux", "architecture": "amd64"}},
+    {"digest": "sha256:arm", "platform": {"os": "linux", "architecture": "arm64", "variant": "v8"}},
+    {"digest": "sha256:att", "platform": {"os": "unknown", "architecture": "unknown"}}
+  ]
+}`)
+	entry, err := EntryFromManifest(index)
+	if err != nil {
+		t.Fatalf("entry failed: %v", err)
+	}
+	sum := sha256.Sum256(index)
+	want := Entry{
+		Digest:    "sha256:" + hex.EncodeToString(sum[:]),
+		Platforms: map[string]string{"linux/amd64": "sha256:amd", "linux/arm64/v8": "sha256:arm"},
+	}
+	if !reflect.DeepEqual(entry, want) {
+		t.Errorf("expected %+v, got %+v", want, entry)
+	}
+
+	single := []byte(`{"schemaVersion": 2, "mediaType": "application/vnd.docker.distribution.manifest.v2+json", "layers": []}`)
+	entry, err = EntryFromManifest(single)
+	if err != nil {
+		t.Fatalf("entry failed: %v", err)
+	}
+	if entry.Platforms != nil || !strings.HasPrefix(entry.Digest, "sha256:") {
+		t.Errorf("unexpected single-manifest entry %+v", entry)
+	}
+
+	if _, err := EntryFromManifest([]byte(`{"errors": []}`)); err == nil {
+		t.Error("expected an error for a document that is not a manifest")
+	}
+}
@@ -0,0 +1,54 @@
+package lock
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// imageManifest holds the fields of an OCI image index, Docker manifest list
+// or single image manifest that an Entry is built from.
+type imageManifest struct {
+	MediaType string `json:"mediaType"`
+	Manifests []struct {
+		Digest   string `json:"digest"`
+		Platform *struct {
+			OS           string `json:"os"`
+			Architecture string `json:"architecture"`
+			Variant      string `json:"variant"`
+		} `json:"platform"`
+	} `json:"manifests"`
+	Layers []json.RawMessage `json:"layers"`
+}
+
+// EntryFromManifest builds the Entry of an image from the raw manifest its tag
+// resolves to, exactly as served by the registry. For an index or manifest
+// list the entry records each platform; attestation manifests, whose platform
+// is unknown/unknown, are skipped.
+func EntryFromManifest(raw []byte) (Entry, error) {
+	var m imageManifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return Entry{}, fmt.Errorf("parse manifest: %w", err)
+	}
+	if m.Manifests == nil && m.Layers == nil {
+		return Entry{}, fmt.Errorf("manifest of media type '%s' is neither an image nor an index", m.MediaType)
+	}
+
+	sum := sha256.Sum256(raw)
+	entry := Entry{Digest: "sha256:" + hex.EncodeToString(sum[:])}
+	for _, desc := range m.Manifests {
+		if desc.Platform == nil || desc.Platform.OS == "unknown" {
+			continue
+		}
+		platform := desc.Platform.OS + "/" + desc.Platform.Architecture
+		if desc.Platform.Variant != "" {
+			platform += "/" + desc.Platform.Variant
+		}
+		if entry.Platforms == nil {
+			entry.Platforms = map[string]string{}
+		}
+		entry.Platforms[platform] = desc.Digest
+	}
+	return entry, nil
+}
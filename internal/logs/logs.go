@@ -0,0 +1,173 @@
+// Package logs parses the multiplexed output of runtime log commands into
+// structured entries, and filters and prints them.
+package logs
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Entry is one log line of a service.
+type Entry struct {
+	Service   string     `json:"service"`
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+	// Stream is stdout or stderr where the source reports it.
+	Stream string `json:"stream,omitempty"`
+	// Level is trace, debug, info, warn, error or fatal when the line
+	// states one.
+	Level   string `json:"level,omitempty"`
+	Message string `json:"message"`
+	// Fields holds the remaining fields of lines the service wrote as JSON.
+	Fields map[string]any `json:"fields,omitempty"`
+}
+
+var (
+	// composePrefix matches the "api-1  | " prefix of docker compose logs.
+	composePrefix = regexp.MustCompile(`^([A-Za-z0-9][\w.-]*)\s+\| ?(.*)$`)
+	// kubectlPrefix matches the "[pod/my-app-api-7d9f-x2x/api] " prefix of
+	// kubectl logs --prefix.
+	kubectlPrefix = regexp.MustCompile(`^\[pod/[^/\]]+/([^\]]+)\] (.*)$`)
+	replicaSuffix = regexp.MustCompile(`[-_]\d+$`)
+)
+
+// Parse splits a line of `compose logs --timestamps` or `kubectl logs
+// --prefix --timestamps` into an entry. Lines of JSON objects have their
+// message and level lifted out of the object.
+func Parse(line string, project string) Entry {
+	var e Entry
+	rest := line
+	if m := kubectlPrefix.FindStringSubmatch(line); m != nil {
+		e.Service, rest = m[1], m[2]
+	} else if m := composePrefix.FindStringSubmatch(line); m != nil {
+		e.Service, rest = composeService(m[1], project), m[2]
+	}
+
+	if first, tail, ok := strings.Cut(rest, " "); ok {
+		if ts, err := time.Parse(time.RFC3339Nano, first); err == nil {
+			e.Timestamp = &ts
+			rest = tail
+		}
+	}
+
+	e.setMessage(rest)
+	return e
+}
+
+// NewEntry builds the entry of a message read from a source that labels
+// lines itself, such as Loki, detecting its level and lifting JSON fields as
+// Parse does.
+func NewEntry(service string, ts time.Time, stream string, message string) Entry {
+	e := Entry{Service: service, Timestamp: &ts, Stream: stream}
+	e.setMessage(message)
+	return e
+}
+
+func (e *Entry) setMessage(message string) {
+	e.Message = message
+	if strings.HasPrefix(strings.TrimSpace(message), "{") {
+		var fields map[string]any
+		if err := json.Unmarshal([]byte(message), &fields); err == nil {
+			e.lift(fields)
+			return
+		}
+	}
+	e.Level = textLevel(message)
+}
+
+// composeService strips the project and replica number compose adds to
+// container names: shop-api-1 and shop_api_1 are service api.
+func composeService(name string, project string) string {
+	name = replicaSuffix.ReplaceAllString(name, "")
+	for _, sep := range []string{"-", "_"} {
+		if project != "" && strings.HasPrefix(name, project+sep) {
+			return strings.TrimPrefix(name, project+sep)
+		}
+	}
+	return name
+}
+
+var (
+	messageKeys = []string{"msg", "message"}
+	levelKeys   = []string{"level", "lvl", "severity"}
+)
+
+func (e *Entry) lift(fields map[string]any) {
+	e.Message = ""
+	for _, key := range messageKeys {
+		if msg, ok := fields[key].(string); ok {
+			e.Message = msg
+			delete(fields, key)
+			break
+		}
+	}
+	for _, key := range levelKeys {
+		if level, ok := fields[key].(string); ok {
+			e.Level = normalizeLevel(level)
+			delete(fields, key)
+			break
+		}
+	}
+	if len(fields) > 0 {
+		e.Fields = fields
+	}
+}
+
+// Levels from least to most severe.
+var levels = []string{"trace", "debug", "info", "warn", "error", "fatal"}
+
+var logfmtLevel = regexp.MustCompile(`\blevel=["']?(\w+)`)
+
+// textLevel finds the level of a plain text line: a logfmt level= field, or
+// a level word among its first tokens, as in "[warn] ..." or
+// "2024/05/01 10:00:00 ERROR ...".
+func textLevel(line string) string {
+	if m := logfmtLevel.FindStringSubmatch(line); m != nil {
+		return normalizeLevel(m[1])
+	}
+	tokens := strings.Fields(line)
+	if len(tokens) > 4 {
+		tokens = tokens[:4]
+	}
+	for _, token := range tokens {
+		// Lower-case words only count when marked up, as in "[warn]" or
+		// "error:", so that prose such as "no error" is not a level.
+		word := strings.Trim(token, "[]():")
+		if word != strings.ToUpper(word) && word == token {
+			continue
+		}
+		if level := normalizeLevel(word); level != "" {
+			return level
+		}
+	}
+	return ""
+}
+
+func normalizeLevel(level string) string {
+	switch strings.ToLower(level) {
+	case "trace":
+		return "trace"
+	case "debug", "dbug":
+		return "debug"
+	case "info", "inf":
+		return "info"
+	case "warn", "warning", "wrn":
+		return "warn"
+	case "error", "err", "eror":
+		return "error"
+	case "fatal", "panic", "critical", "crit":
+		return "fatal"
+	default:
+		return ""
+	}
+}
+
+func severity(level string) int {
+	for i, l := range levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
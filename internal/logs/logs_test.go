@@ -0,0 +1,129 @@
+package logs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		line    string
+		service string
+		level   string
+		message string
+		ts      bool
+	}{
+		{"api-1  | 2024-05-01T10:00:00.123456789Z listening on :8080", "api", "", "listening on :8080", true},
+		{"shop-db-1 | 2024-05-01T10:00:00Z 2024/05/01 10:00:00 [WARN] slow query", "db", "warn", "2024/05/01 10:00:00 [WARN] slow query", true},
+		{"shop_worker_2 | ERROR: job failed | retrying", "worker", "error", "ERROR: job failed | retrying", false},
+		{"[pod/shop-api-7d9f6c-x2x4z/api] 2024-05-01T10:00:00Z time=now level=debug msg=hi", "api", "debug", "time=now level=debug msg=hi", true},
+		{"no such service: web", "", "", "no such service: web", false},
+		{"api-1  | this error is not a level", "api", "", "this error is not a level", false},
+	}
+	for _, c := range cases {
+		e := Parse(c.line, "shop")
+		if e.Service != c.service || e.Level != c.level || e.Message != c.message || (e.Timestamp != nil) != c.ts {
+			t.Errorf("Parse(%q) = %+v", c.line, e)
+		}
+	}
+
+	e := Parse("api-1  | 2024-05-01T10:00:00Z ", "shop")
+	if e.Message != "" || e.Timestamp == nil {
+		t.Errorf("expected an empty message with a timestamp, got %+v", e)
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	e := Parse(`api-1  | 2024-05-01T10:00:00Z {"level":"WARNING","msg":"retrying","attempt":2,"path":"/orders"}`, "shop")
+	if e.Service != "api" || e.Level != "warn" || e.Message != "retrying" {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	if len(e.Fields) != 2 || e.Fields["attempt"] != float64(2) || e.Fields["path"] != "/orders" {
+		t.Errorf("unexpected fields %v", e.Fields)
+	}
+	if got := e.text(); got != "retrying attempt=2 path=/orders" {
+		t.Errorf("unexpected text %q", got)
+	}
+
+	e = NewEntry("api", time.Unix(1714557600, 0), "stderr", `{"message":"boom","severity":"CRITICAL"}`)
+	if e.Service != "api" || e.Stream != "stderr" || e.Level != "fatal" || e.Message != "boom" || e.Fields != nil || e.Timestamp.Unix() != 1714557600 {
+		t.Errorf("unexpected entry %+v", e)
+	}
+
+	if e := Parse(`api-1  | {"broken": `, "shop"); e.Message != `{"broken": ` || e.Fields != nil {
+		t.Errorf("expected invalid JSON to stay text, got %+v", e)
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f, err := NewFilter("order", "warn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]bool{
+		"api-1 | WARN order 42 is late":            true,
+		"api-1 | ERROR order 42 failed":            true,
+		"api-1 | INFO order 42 created":            false,
+		"api-1 | WARN payment is late":             false,
+		"api-1 | order without a level":            false,
+		`api-1 | {"level":"error","id":"order-7"}`: true,
+	}
+	for line, want := range cases {
+		if got := f.Match(Parse(line, "shop")); got != want {
+			t.Errorf("Match(%q) = %v, want %v", line, got, want)
+		}
+	}
+
+	if _, err := NewFilter("(", ""); err == nil {
+		t.Error("expected an invalid --grep error")
+	}
+	if _, err := NewFilter("", "loud"); err == nil || !strings.Contains(err.Error(), "unknown level 'loud'") {
+		t.Errorf("expected an unknown level error, got %v", err)
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	ts := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	entries := []Entry{
+		{Service: "db", Message: "ready"},
+		{Service: "api", Level: "error", Message: "failed", Fields: map[string]any{"id": float64(7)}},
+		{Message: "no prefix"},
+	}
+
+	var buf bytes.Buffer
+	p := &Printer{Out: &buf}
+	for _, e := range entries {
+		if err := p.Print(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if want := "db | ready\napi | failed id=7\nno prefix\n"; buf.String() != want {
+		t.Errorf("expected\n%q\ngot\n%q", want, buf.String())
+	}
+
+	buf.Reset()
+	p = &Printer{Out: &buf, JSON: true}
+	entries[0].Timestamp = &ts
+	for _, e := range entries[:2] {
+		if err := p.Print(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := `{"service":"db","timestamp":"2024-05-01T10:00:00Z","message":"ready"}
+{"service":"api","level":"error","message":"failed","fields":{"id":7}}
+`
+	if buf.String() != want {
+		t.Errorf("expected\n%s\ngot\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	p = &Printer{Out: &buf, Color: true}
+	if err := p.Print(entries[1]); err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); !strings.HasPrefix(got, "\x1b["+serviceColor("api")+"mapi |\x1b[0m \x1b[31mfailed") {
+		t.Errorf("unexpected coloured output %q", got)
+	}
+}
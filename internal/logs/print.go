@@ -0,0 +1,131 @@
+package logs
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Filter selects entries.
+type Filter struct {
+	// Grep keeps entries whose message or fields match.
+	Grep *regexp.Regexp
+	// Level keeps entries of this level or more severe; entries without a
+	// level are dropped.
+	Level string
+}
+
+// NewFilter validates the values of --grep and --level.
+func NewFilter(grep string, level string) (Filter, error) {
+	var f Filter
+	if grep != "" {
+		re, err := regexp.Compile(grep)
+		if err != nil {
+			return f, fmt.Errorf("invalid --grep: %w", err)
+		}
+		f.Grep = re
+	}
+	if level != "" {
+		f.Level = normalizeLevel(level)
+		if f.Level == "" {
+			return f, fmt.Errorf("unknown level '%s' (want %s)", level, strings.Join(levels, ", "))
+		}
+	}
+	return f, nil
+}
+
+// Match reports whether the filter keeps e.
+func (f Filter) Match(e Entry) bool {
+	if f.Level != "" && severity(e.Level) < severity(f.Level) {
+		return false
+	}
+	if f.Grep != nil && !f.Grep.MatchString(e.text()) {
+		return false
+	}
+	return true
+}
+
+// text is the message followed by the lifted fields as key=value pairs.
+func (e Entry) text() string {
+	if len(e.Fields) == 0 {
+		return e.Message
+	}
+	keys := make([]string, 0, len(e.Fields))
+	for key := range e.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := []string{}
+	if e.Message != "" {
+		parts = append(parts, e.Message)
+	}
+	for _, key := range keys {
+		value, ok := e.Fields[key].(string)
+		if !ok {
+			data, _ := json.Marshal(e.Fields[key])
+			value = string(data)
+		}
+		parts = append(parts, key+"="+value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// serviceColors are the ANSI colours services are told apart by.
+var serviceColors = []string{"36", "33", "32", "35", "34", "96", "93", "92", "95", "94"}
+
+// Printer writes entries as JSON lines, or as text prefixed with the
+// service name like docker compose logs.
+type Printer struct {
+	Out  io.Writer
+	JSON bool
+	// Color colours the service prefix of text output, and error lines.
+	Color bool
+
+	width int
+}
+
+// Print writes e.
+func (p *Printer) Print(e Entry) error {
+	if p.JSON {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(p.Out, string(data))
+		return err
+	}
+
+	var b strings.Builder
+	if e.Service != "" {
+		// Pad to the longest service seen so far so that messages line up.
+		if len(e.Service) > p.width {
+			p.width = len(e.Service)
+		}
+		prefix := e.Service + strings.Repeat(" ", p.width-len(e.Service)) + " |"
+		if p.Color {
+			prefix = "\x1b[" + serviceColor(e.Service) + "m" + prefix + "\x1b[0m"
+		}
+		b.WriteString(prefix + " ")
+	}
+	if e.Timestamp != nil {
+		b.WriteString(e.Timestamp.Local().Format("15:04:05.000") + " ")
+	}
+	text := e.text()
+	if p.Color && severity(e.Level) >= severity("error") {
+		text = "\x1b[31m" + text + "\x1b[0m"
+	}
+	b.WriteString(text)
+	_, err := fmt.Fprintln(p.Out, b.String())
+	return err
+}
+
+func serviceColor(service string) string {
+	h := fnv.New32a()
+	h.Write([]byte(service))
+	return serviceColors[h.Sum32()%uint32(len(serviceColors))]
+}
@@ -0,0 +1,136 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/dever-labs/devx/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Metadata is the JSON document a provider prints in response to `describe`.
+type Metadata struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// Plugin is a discovered provider binary together with its metadata.
+type Plugin struct {
+	Path     string
+	Metadata Metadata
+}
+
+// RenderRequest is written as JSON to a provider's stdin on `render`.
+// Profile uses the same field names as devx.yaml.
+type RenderRequest struct {
+	Project     string         `json:"project"`
+	ProfileName string         `json:"profileName"`
+	Profile     map[string]any `json:"profile"`
+}
+
+// Fragment is the compose fragment (JSON or YAML) returned by a provider.
+type Fragment struct {
+	Plugin string
+	Data   []byte
+}
+
+// Load discovers every provider on PATH and runs `describe` on each.
+func Load(ctx context.Context) ([]Plugin, error) {
+	paths, err := Discover()
+	if err != nil {
+		return nil, err
+	}
+
+	plugins := make([]Plugin, 0, len(paths))
+	for _, path := range paths {
+		meta, err := Describe(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		plugins = append(plugins, Plugin{Path: path, Metadata: meta})
+	}
+	return plugins, nil
+}
+
+// Describe runs `<plugin> describe` and decodes its metadata.
+func Describe(ctx context.Context, path string) (Metadata, error) {
+	out, err := invoke(ctx, path, "describe", nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+
+	var meta Metadata
+	if err := json.Unmarshal(out, &meta); err != nil {
+		return Metadata{}, fmt.Errorf("plugin %s describe returned invalid JSON: %w", filepath.Base(path), err)
+	}
+	if meta.Name == "" {
+		meta.Name = strings.TrimPrefix(filepath.Base(path), Prefix)
+	}
+	return meta, nil
+}
+
+// NewRenderRequest builds the request sent to providers for a profile.
+func NewRenderRequest(manifest *config.Manifest, profileName string, profile *config.Profile) (RenderRequest, error) {
+	data, err := yaml.Marshal(profile)
+	if err != nil {
+		return RenderRequest{}, err
+	}
+	spec := map[string]any{}
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return RenderRequest{}, err
+	}
+	return RenderRequest{
+		Project:     manifest.Project.Name,
+		ProfileName: profileName,
+		Profile:     spec,
+	}, nil
+}
+
+// Render runs `<plugin> render` with req on stdin and returns its compose fragment.
+func Render(ctx context.Context, p Plugin, req RenderRequest) (Fragment, error) {
+	input, err := json.Marshal(req)
+	if err != nil {
+		return Fragment{}, err
+	}
+	out, err := invoke(ctx, p.Path, "render", input)
+	if err != nil {
+		return Fragment{}, err
+	}
+	return Fragment{Plugin: p.Metadata.Name, Data: out}, nil
+}
+
+// RenderAll renders req with every plugin, in discovery order.
+func RenderAll(ctx context.Context, plugins []Plugin, req RenderRequest) ([]Fragment, error) {
+	var fragments []Fragment
+	for _, p := range plugins {
+		fragment, err := Render(ctx, p, req)
+		if err != nil {
+			return nil, err
+		}
+		fragments = append(fragments, fragment)
+	}
+	return fragments, nil
+}
+
+func invoke(ctx context.Context, path string, command string, input []byte) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, path, command)
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	out, err := cmd.Output()
+	if err != nil {
+		detail := strings.TrimSpace(stderr.String())
+		if detail != "" {
+			return nil, fmt.Errorf("plugin %s %s failed: %w: %s", filepath.Base(path), command, err, detail)
+		}
+		return nil, fmt.Errorf("plugin %s %s failed: %w", filepath.Base(path), command, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,130 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dever-labs/devx/internal/config"
+)
+
+// writeProvider writes a shell script provider to dir.
+func writeProvider(t *testing.T, dir string, name string, script string) string {
+	t.Helper()
+	path := filepath.Join(dir, Prefix+name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDescribeAndRender(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as providers")
+	}
+	dir := t.TempDir()
+	request := filepath.Join(dir, "request.json")
+	path := writeProvider(t, dir, "queue", `case "$1" in
+describe) echo '{"name": "queue", "version": "1.2.0"}';;
+render) cat > `+request+`; echo 'services: {queue: {image: "nats:2"}}';;
+*) exit 2;;
+esac
+`)
+
+	// Only the stub is discovered; render needs the real PATH for cat.
+	origPath := os.Getenv("PATH")
+	t.Setenv("PATH", dir)
+	loaded, err := Load(context.Background())
+	t.Setenv("PATH", origPath)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if len(loaded) != 1 || loaded[0].Path != path || loaded[0].Metadata != (Metadata{Name: "queue", Version: "1.2.0"}) {
+		t.Fatalf("unexpected plugins %+v", loaded)
+	}
+
+	manifest := &config.Manifest{Project: config.Project{Name: "shop"}}
+	profile := &config.Profile{Services: map[string]config.Service{"api": {Image: "api:1"}}}
+	req, err := NewRenderRequest(manifest, "local", profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fragments, err := RenderAll(context.Background(), loaded, req)
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if len(fragments) != 1 || fragments[0].Plugin != "queue" || !strings.Contains(string(fragments[0].Data), "nats:2") {
+		t.Fatalf("unexpected fragments %+v", fragments)
+	}
+
+	data, err := os.ReadFile(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		Project     string `json:"project"`
+		ProfileName string `json:"profileName"`
+		Profile     struct {
+			Services map[string]struct {
+				Image string `json:"image"`
+			} `json:"services"`
+		} `json:"profile"`
+	}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("provider received invalid JSON %q: %v", data, err)
+	}
+	if got.Project != "shop" || got.ProfileName != "local" || got.Profile.Services["api"].Image != "api:1" {
+		t.Errorf("unexpected render request %s", data)
+	}
+}
+
+func TestDescribeErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as providers")
+	}
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	meta, err := Describe(ctx, writeProvider(t, dir, "anon", "echo '{\"version\": \"0.1.0\"}'\n"))
+	if err != nil || meta.Name != "anon" {
+		t.Errorf("expected the name to default to the binary's, got %+v %v", meta, err)
+	}
+
+	_, err = Describe(ctx, writeProvider(t, dir, "text", "echo hello\n"))
+	if err == nil || !strings.Contains(err.Error(), "plugin devx-provider-text describe returned invalid JSON") {
+		t.Errorf("expected an invalid JSON error, got %v", err)
+	}
+
+	_, err = Describe(ctx, writeProvider(t, dir, "broken", "echo 'missing config' >&2\nexit 3\n"))
+	if err == nil || err.Error() != "plugin devx-provider-broken describe failed: exit status 3: missing config" {
+		t.Errorf("expected the provider's stderr in the error, got %v", err)
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell scripts as providers")
+	}
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	silent := Plugin{Path: writeProvider(t, dir, "silent", "exit 1\n"), Metadata: Metadata{Name: "silent"}}
+	_, err := Render(ctx, silent, RenderRequest{Project: "shop"})
+	if err == nil || err.Error() != "plugin devx-provider-silent render failed: exit status 1" {
+		t.Errorf("unexpected error %v", err)
+	}
+
+	ok := Plugin{Path: writeProvider(t, dir, "ok", "echo '{}'\n"), Metadata: Metadata{Name: "ok"}}
+	if _, err := RenderAll(ctx, []Plugin{ok, silent}, RenderRequest{}); err == nil {
+		t.Error("expected RenderAll to fail when a provider fails")
+	}
+
+	_, err = Render(ctx, Plugin{Path: filepath.Join(dir, Prefix+"missing")}, RenderRequest{})
+	if err == nil || !strings.Contains(err.Error(), "plugin devx-provider-missing render failed") {
+		t.Errorf("expected a missing binary error, got %v", err)
+	}
+}
@@ -0,0 +1,115 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// dockerHubServer is the key the Docker CLI stores Docker Hub credentials
+// under.
+const dockerHubServer = "https://index.docker.io/v1/"
+
+type dockerConfig struct {
+	Auths map[string]struct {
+		Auth     string `json:"auth"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	} `json:"auths"`
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// DockerCredentials looks up the credentials for host the way the Docker CLI
+// does: a credential helper configured for the host, else the default
+// credential store, else the auths of config.json in $DOCKER_CONFIG or
+// ~/.docker. It returns empty strings when there are none.
+func DockerCredentials(ctx context.Context, host string) (string, string, error) {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", "", nil
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "config.json"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", err
+	}
+	var cfg dockerConfig
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return "", "", fmt.Errorf("parse %s: %w", filepath.Join(dir, "config.json"), err)
+	}
+
+	keys := []string{host, "https://" + host}
+	if host == dockerHub {
+		keys = []string{dockerHubServer, "index.docker.io", dockerHub}
+	}
+
+	for _, key := range keys {
+		if helper := cfg.CredHelpers[key]; helper != "" {
+			return helperCredentials(ctx, helper, key)
+		}
+	}
+	if cfg.CredsStore != "" {
+		return helperCredentials(ctx, cfg.CredsStore, keys[0])
+	}
+	for _, key := range keys {
+		auth, ok := cfg.Auths[key]
+		if !ok {
+			continue
+		}
+		if auth.Auth == "" {
+			return auth.Username, auth.Password, nil
+		}
+		decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+		if err != nil {
+			return "", "", fmt.Errorf("auth for %s is not valid base64", key)
+		}
+		username, password, _ := strings.Cut(string(decoded), ":")
+		return username, password, nil
+	}
+	return "", "", nil
+}
+
+// helperCredentials runs docker-credential-<helper> get for server. A helper
+// that has nothing stored for it means anonymous access.
+func helperCredentials(ctx context.Context, helper string, server string) (string, string, error) {
+	cmd := exec.CommandContext(ctx, "docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(server)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String() + string(out))
+		if strings.Contains(msg, "credentials not found") {
+			return "", "", nil
+		}
+		if msg != "" {
+			return "", "", fmt.Errorf("docker-credential-%s: %s", helper, msg)
+		}
+		return "", "", fmt.Errorf("docker-credential-%s: %w", helper, err)
+	}
+
+	var creds struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(out, &creds); err != nil {
+		return "", "", fmt.Errorf("docker-credential-%s: %w", helper, err)
+	}
+	return creds.Username, creds.Secret, nil
+}
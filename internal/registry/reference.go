@@ -0,0 +1,94 @@
+package registry
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	dockerHub     = "docker.io"
+	dockerHubAPI  = "registry-1.docker.io"
+	defaultTag    = "latest"
+	libraryPrefix = "library/"
+)
+
+// Reference is an image reference split the way the distribution API
+// addresses it.
+type Reference struct {
+	// Host is the registry as written in the image, docker.io when omitted.
+	Host       string
+	Repository string
+	// Tag is empty when the reference is by digest.
+	Tag    string
+	Digest string
+}
+
+// ParseReference normalises an image reference, e.g. "redis:7" becomes
+// docker.io/library/redis:7.
+func ParseReference(image string) (Reference, error) {
+	if image == "" {
+		return Reference{}, fmt.Errorf("empty image reference")
+	}
+
+	var ref Reference
+	name := image
+	if at := strings.Index(name, "@"); at >= 0 {
+		ref.Digest = name[at+1:]
+		name = name[:at]
+		if !strings.Contains(ref.Digest, ":") {
+			return Reference{}, fmt.Errorf("image '%s' has an invalid digest", image)
+		}
+	}
+	if colon := strings.LastIndex(name, ":"); colon > strings.LastIndex(name, "/") {
+		ref.Tag = name[colon+1:]
+		name = name[:colon]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = defaultTag
+	}
+
+	first, rest, found := strings.Cut(name, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		ref.Host = first
+		ref.Repository = rest
+	} else {
+		ref.Host = dockerHub
+		ref.Repository = name
+	}
+	if ref.Host == dockerHub && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = libraryPrefix + ref.Repository
+	}
+	if ref.Repository == "" || strings.ToLower(ref.Repository) != ref.Repository {
+		return Reference{}, fmt.Errorf("image '%s' has an invalid repository name", image)
+	}
+	return ref, nil
+}
+
+// apiHost is the host serving the registry's distribution API.
+func (r Reference) apiHost() string {
+	if r.Host == dockerHub {
+		return dockerHubAPI
+	}
+	return r.Host
+}
+
+// scheme is https except for registries on the local machine, which, like
+// the Docker daemon, devx talks to over plain http.
+func (r Reference) scheme() string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if host == "localhost" {
+		return "http"
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return "http"
+	}
+	return "https"
+}
+
+func (r Reference) manifestURL(reference string) string {
+	return fmt.Sprintf("%s://%s/v2/%s/manifests/%s", r.scheme(), r.apiHost(), r.Repository, reference)
+}
@@ -0,0 +1,254 @@
+// Package registry is a minimal client for the OCI distribution API, enough to
+// resolve image tags to digests without a container runtime.
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/dever-labs/devx/internal/lock"
+)
+
+// manifestTypes are the manifest media types Resolve accepts, most preferred
+// first.
+var manifestTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+var indexTypes = map[string]bool{
+	"application/vnd.oci.image.index.v1+json":                   true,
+	"application/vnd.docker.distribution.manifest.list.v2+json": true,
+}
+
+// maxManifestSize bounds how much of a manifest Resolve reads.
+const maxManifestSize = 4 << 20
+
+type Client struct {
+	HTTP *http.Client
+	// Credentials returns the username and password for a registry host as
+	// written in image references, or empty strings for anonymous access.
+	Credentials func(ctx context.Context, host string) (string, string, error)
+
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// New returns a client that authenticates with the credentials of the Docker
+// CLI configuration.
+func New() *Client {
+	return &Client{HTTP: http.DefaultClient, Credentials: DockerCredentials}
+}
+
+// Resolve looks up the digests image resolves to. The tag is resolved with a
+// HEAD request on its manifest; only a multi-arch index is then fetched, to
+// read the digests of its platforms.
+func (c *Client) Resolve(ctx context.Context, image string) (lock.Entry, error) {
+	ref, err := ParseReference(image)
+	if err != nil {
+		return lock.Entry{}, err
+	}
+	reference := ref.Tag
+	if ref.Digest != "" {
+		reference = ref.Digest
+	}
+
+	resp, err := c.do(ctx, http.MethodHead, ref, ref.manifestURL(reference))
+	if err != nil {
+		return lock.Entry{}, fmt.Errorf("resolve %s: %w", image, err)
+	}
+	resp.Body.Close()
+	digest := resp.Header.Get("Docker-Content-Digest")
+	mediaType, _, _ := strings.Cut(resp.Header.Get("Content-Type"), ";")
+
+	// Registries may leave the digest out of HEAD responses; the manifest
+	// itself is then fetched and hashed.
+	if digest != "" && !indexTypes[mediaType] {
+		return lock.Entry{Digest: digest}, nil
+	}
+	if digest != "" {
+		reference = digest
+	}
+
+	resp, err = c.do(ctx, http.MethodGet, ref, ref.manifestURL(reference))
+	if err != nil {
+		return lock.Entry{}, fmt.Errorf("resolve %s: %w", image, err)
+	}
+	defer resp.Body.Close()
+	raw, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestSize))
+	if err != nil {
+		return lock.Entry{}, fmt.Errorf("resolve %s: %w", image, err)
+	}
+	entry, err := lock.EntryFromManifest(raw)
+	if err != nil {
+		return lock.Entry{}, fmt.Errorf("resolve %s: %w", image, err)
+	}
+	if digest != "" && entry.Digest != digest {
+		return lock.Entry{}, fmt.Errorf("resolve %s: manifest does not match digest %s", image, digest)
+	}
+	return entry, nil
+}
+
+// do sends a request for ref's repository, authenticating when the registry
+// challenges it, and fails on any status but 200.
+func (c *Client) do(ctx context.Context, method string, ref Reference, target string) (*http.Response, error) {
+	scope := "repository:" + ref.Repository + ":pull"
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, target, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Accept", strings.Join(manifestTypes, ", "))
+		if auth := c.cachedAuth(ref.Host, scope); auth != "" {
+			req.Header.Set("Authorization", auth)
+		}
+
+		resp, err := c.httpClient().Do(req)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode == http.StatusOK {
+			return resp, nil
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusUnauthorized || attempt > 0 {
+			return nil, fmt.Errorf("%s %s: %s", method, target, resp.Status)
+		}
+		if err := c.authenticate(ctx, ref.Host, scope, resp.Header.Get("WWW-Authenticate")); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// authenticate answers a registry's WWW-Authenticate challenge: Basic with the
+// host's credentials, or Bearer with a token from the realm, anonymous when
+// there are no credentials.
+func (c *Client) authenticate(ctx context.Context, host string, scope string, challenge string) error {
+	scheme, params := parseChallenge(challenge)
+	username, password, err := c.credentials(ctx, host)
+	if err != nil {
+		return fmt.Errorf("credentials for %s: %w", host, err)
+	}
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if username == "" {
+			return fmt.Errorf("%s requires credentials; run 'docker login %s'", host, host)
+		}
+		c.storeAuth(host, scope, "Basic "+base64.StdEncoding.EncodeToString([]byte(username+":"+password)))
+		return nil
+	case "bearer":
+		token, err := c.fetchToken(ctx, params, scope, username, password)
+		if err != nil {
+			return fmt.Errorf("token for %s: %w", host, err)
+		}
+		c.storeAuth(host, scope, "Bearer "+token)
+		return nil
+	default:
+		return fmt.Errorf("%s asked for unsupported authentication '%s'", host, scheme)
+	}
+}
+
+func (c *Client) fetchToken(ctx context.Context, params map[string]string, scope string, username string, password string) (string, error) {
+	realm := params["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("challenge has no realm")
+	}
+	query := url.Values{}
+	if service := params["service"]; service != "" {
+		query.Set("service", service)
+	}
+	if s := params["scope"]; s != "" {
+		scope = s
+	}
+	query.Set("scope", scope)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", realm, resp.Status)
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", err
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	if body.AccessToken != "" {
+		return body.AccessToken, nil
+	}
+	return "", fmt.Errorf("%s returned no token", realm)
+}
+
+// parseChallenge splits `Bearer realm="...",service="..."` into its scheme
+// and parameters.
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		if key != "" {
+			params[strings.ToLower(strings.TrimSpace(key))] = value
+		}
+	}
+	return scheme, params
+}
+
+func (c *Client) credentials(ctx context.Context, host string) (string, string, error) {
+	if c.Credentials == nil {
+		return "", "", nil
+	}
+	return c.Credentials(ctx, host)
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTP == nil {
+		return http.DefaultClient
+	}
+	return c.HTTP
+}
+
+func (c *Client) cachedAuth(host string, scope string) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens[host+" "+scope]
+}
+
+func (c *Client) storeAuth(host string, scope string, auth string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.tokens == nil {
+		c.tokens = map[string]string{}
+	}
+	c.tokens[host+" "+scope] = auth
+}
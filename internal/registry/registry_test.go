@@ -0,0 +1,243 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeRegistry serves manifests behind token authentication, like Docker Hub.
+type fakeRegistry struct {
+	server    *httptest.Server
+	manifests map[string][]byte // "repo:tag" or "repo@digest"
+	types     map[string]string
+	username  string
+	password  string
+
+	mu       sync.Mutex
+	requests []string
+}
+
+func newFakeRegistry(t *testing.T) *fakeRegistry {
+	r := &fakeRegistry{manifests: map[string][]byte{}, types: map[string]string{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, req *http.Request) {
+		if r.username != "" {
+			user, pass, ok := req.BasicAuth()
+			if !ok || user != r.username || pass != r.password {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+		fmt.Fprintf(w, `{"token": "tok-%s"}`, req.URL.Query().Get("scope"))
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		r.requests = append(r.requests, req.Method+" "+req.URL.Path)
+		r.mu.Unlock()
+
+		repo, reference, _ := strings.Cut(strings.TrimPrefix(req.URL.Path, "/v2/"), "/manifests/")
+		if req.Header.Get("Authorization") != "Bearer tok-repository:"+repo+":pull" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="fake"`, r.server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		key := repo + ":" + reference
+		if strings.HasPrefix(reference, "sha256:") {
+			key = repo + "@" + reference
+		}
+		body, ok := r.manifests[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", r.types[key])
+		w.Header().Set("Docker-Content-Digest", digestOf(body))
+		if req.Method == http.MethodGet {
+			_, _ = w.Write(body)
+		}
+	})
+	r.server = httptest.NewServer(mux)
+	t.Cleanup(r.server.Close)
+	return r
+}
+
+func (r *fakeRegistry) push(repo string, tag string, mediaType string, body string) string {
+	digest := digestOf([]byte(body))
+	for _, key := range []string{repo + ":" + tag, repo + "@" + digest} {
+		r.manifests[key] = []byte(body)
+		r.types[key] = mediaType
+	}
+	return digest
+}
+
+func (r *fakeRegistry) host() string {
+	return strings.TrimPrefix(r.server.URL, "http://")
+}
+
+func digestOf(body []byte) string {
+	sum := sha256.Sum256(body)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func TestResolve(t *testing.T) {
+	reg := newFakeRegistry(t)
+	single := reg.push("team/api", "1.0", "application/vnd.oci.image.manifest.v1+json", `{"schemaVersion": 2, "layers": []}`)
+	index := reg.push("team/web", "2.0", "application/vnd.oci.image.index.v1+json", `{
+  "schemaVersion": 2,
+  "manifests": [
+    {"digest": "sha256:amd", "platform": {"os": "linux", "architecture": "amd64"}},
+    {"digest": "sha256:arm", "platform": {"os": "linux", "architecture": "arm64"}}
+  ]
+}`)
+
+	client := &Client{}
+	ctx := context.Background()
+
+	entry, err := client.Resolve(ctx, reg.host()+"/team/api:1.0")
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if entry.Digest != single || entry.Platforms != nil {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+
+	entry, err = client.Resolve(ctx, reg.host()+"/team/web:2.0")
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if entry.Digest != index || !reflect.DeepEqual(entry.Platforms, map[string]string{"linux/amd64": "sha256:amd", "linux/arm64": "sha256:arm"}) {
+		t.Errorf("unexpected entry %+v", entry)
+	}
+
+	// A single manifest is resolved with HEAD alone and tokens are reused.
+	want := []string{
+		"HEAD /v2/team/api/manifests/1.0",
+		"HEAD /v2/team/api/manifests/1.0",
+		"HEAD /v2/team/web/manifests/2.0",
+		"HEAD /v2/team/web/manifests/2.0",
+		"GET /v2/team/web/manifests/" + index,
+	}
+	if !reflect.DeepEqual(reg.requests, want) {
+		t.Errorf("expected requests %v, got %v", want, reg.requests)
+	}
+
+	if _, err := client.Resolve(ctx, reg.host()+"/team/api:missing"); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+}
+
+func TestResolveWithCredentials(t *testing.T) {
+	reg := newFakeRegistry(t)
+	reg.username, reg.password = "ci", "s3cret"
+	digest := reg.push("private/app", "latest", "application/vnd.docker.distribution.manifest.v2+json", `{"schemaVersion": 2, "layers": []}`)
+
+	client := &Client{}
+	if _, err := client.Resolve(context.Background(), reg.host()+"/private/app"); err == nil {
+		t.Fatal("expected anonymous access to fail")
+	}
+
+	var hosts []string
+	client = &Client{Credentials: func(ctx context.Context, host string) (string, string, error) {
+		hosts = append(hosts, host)
+		return "ci", "s3cret", nil
+	}}
+	entry, err := client.Resolve(context.Background(), reg.host()+"/private/app")
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if entry.Digest != digest {
+		t.Errorf("expected %s, got %s", digest, entry.Digest)
+	}
+	if !reflect.DeepEqual(hosts, []string{reg.host()}) {
+		t.Errorf("expected credentials looked up once for %s, got %v", reg.host(), hosts)
+	}
+}
+
+func TestParseReference(t *testing.T) {
+	cases := map[string]Reference{
+		"redis":                              {Host: "docker.io", Repository: "library/redis", Tag: "latest"},
+		"redis:7":                            {Host: "docker.io", Repository: "library/redis", Tag: "7"},
+		"grafana/loki:2.9.2":                 {Host: "docker.io", Repository: "grafana/loki", Tag: "2.9.2"},
+		"localhost:5000/app":                 {Host: "localhost:5000", Repository: "app", Tag: "latest"},
+		"myregistry.azurecr.io/team/api:1.0": {Host: "myregistry.azurecr.io", Repository: "team/api", Tag: "1.0"},
+		"ghcr.io/org/app:1@sha256:abc":       {Host: "ghcr.io", Repository: "org/app", Tag: "1", Digest: "sha256:abc"},
+		"postgres@sha256:abc":                {Host: "docker.io", Repository: "library/postgres", Digest: "sha256:abc"},
+	}
+	for image, want := range cases {
+		got, err := ParseReference(image)
+		if err != nil {
+			t.Errorf("%s: %v", image, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: expected %+v, got %+v", image, want, got)
+		}
+	}
+
+	for _, image := range []string{"", "Upper/Case", "app@nodigest"} {
+		if _, err := ParseReference(image); err == nil {
+			t.Errorf("%q: expected an error", image)
+		}
+	}
+
+	if got := (Reference{Host: "docker.io", Repository: "library/redis"}).manifestURL("7"); got != "https://registry-1.docker.io/v2/library/redis/manifests/7" {
+		t.Errorf("unexpected Docker Hub URL %s", got)
+	}
+}
+
+func TestDockerCredentials(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dir)
+	ctx := context.Background()
+
+	if user, pass, err := DockerCredentials(ctx, "ghcr.io"); err != nil || user != "" || pass != "" {
+		t.Fatalf("expected anonymous access without a config, got %q %q %v", user, pass, err)
+	}
+
+	// A credential helper on PATH that knows one server.
+	bin := t.TempDir()
+	helper := "#!/bin/sh\nread server\nif [ \"$server\" = \"ghcr.io\" ]; then\n  echo '{\"Username\": \"bot\", \"Secret\": \"from-helper\"}'\nelse\n  echo 'credentials not found in native keychain'\n  exit 1\nfi\n"
+	if err := os.WriteFile(filepath.Join(bin, "docker-credential-fake"), []byte(helper), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	config := `{
+  "auths": {
+    "https://index.docker.io/v1/": {"auth": "` + "dXNlcjpodWItcGFzcw==" + `"},
+    "registry.example.com": {"username": "plain", "password": "text"}
+  },
+  "credHelpers": {"ghcr.io": "fake", "quay.io": "fake"}
+}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct{ host, user, pass string }{
+		{"docker.io", "user", "hub-pass"},
+		{"registry.example.com", "plain", "text"},
+		{"ghcr.io", "bot", "from-helper"},
+		{"quay.io", "", ""},
+		{"other.io", "", ""},
+	}
+	for _, tc := range cases {
+		user, pass, err := DockerCredentials(ctx, tc.host)
+		if err != nil {
+			t.Errorf("%s: %v", tc.host, err)
+			continue
+		}
+		if user != tc.user || pass != tc.pass {
+			t.Errorf("%s: expected %q %q, got %q %q", tc.host, tc.user, tc.pass, user, pass)
+		}
+	}
+}
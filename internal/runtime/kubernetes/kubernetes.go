@@ -0,0 +1,409 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dever-labs/devx/internal/k8s"
+	"github.com/dever-labs/devx/internal/runtime"
+)
+
+// Runtime implements runtime.Runtime on top of kubectl. The composePath
+// arguments of the interface refer to the rendered k8s manifest, and services
+// are located through the `app` label written by k8s.Render.
+type Runtime struct {
+	Binary    string
+	Namespace string
+	// Cluster selects the kubeconfig and context kubectl talks to.
+	Cluster k8s.Kubectl
+}
+
+func New() *Runtime {
+	return &Runtime{Binary: "kubectl"}
+}
+
+func (r *Runtime) Name() string {
+	return "k8s"
+}
+
+func (r *Runtime) Detect(ctx context.Context) (bool, error) {
+	cmd := exec.CommandContext(ctx, r.Binary, "version", "--client")
+	if err := cmd.Run(); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+func (r *Runtime) Up(ctx context.Context, manifestPath string, projectName string, opts runtime.UpOptions) error {
+	args := []string{"apply", "-f", manifestPath}
+	args = append(args, appSelector(projectName, opts.Services)...)
+	return run(ctx, r.Binary, r.args(args...)...)
+}
+
+func (r *Runtime) Down(ctx context.Context, manifestPath string, projectName string, opts runtime.DownOptions) error {
+	args := []string{"delete", "-f", manifestPath, "--ignore-not-found"}
+	args = append(args, appSelector(projectName, opts.Services)...)
+	return run(ctx, r.Binary, r.args(args...)...)
+}
+
+// appSelector returns a label selector argument matching the given services,
+// or nothing when no services are named.
+func appSelector(projectName string, services []string) []string {
+	if len(services) == 0 {
+		return nil
+	}
+	apps := make([]string, 0, len(services))
+	for _, service := range services {
+		apps = append(apps, k8s.AppName(projectName, service))
+	}
+	return []string{"-l", "app in (" + strings.Join(apps, ",") + ")"}
+}
+
+func (r *Runtime) Logs(ctx context.Context, manifestPath string, projectName string, opts runtime.LogsOptions) (io.ReadCloser, error) {
+	var apps []string
+	if opts.Service != "" {
+		apps = []string{k8s.AppName(projectName, opts.Service)}
+	} else {
+		workloads, err := r.workloads(ctx, projectName)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range workloads {
+			apps = append(apps, d.app())
+		}
+	}
+	if len(apps) == 0 {
+		return nil, fmt.Errorf("no workloads found for project %s", projectName)
+	}
+
+	args := []string{"logs", "-l", "app in (" + strings.Join(apps, ",") + ")",
+		"--prefix", "--timestamps", "--all-containers",
+		"--max-log-requests", fmt.Sprintf("%d", len(apps)+5)}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	if opts.Since != "" {
+		if _, err := time.Parse(time.RFC3339, opts.Since); err == nil {
+			args = append(args, "--since-time", opts.Since)
+		} else {
+			args = append(args, "--since", opts.Since)
+		}
+	}
+	if opts.Tail > 0 {
+		args = append(args, "--tail", strconv.Itoa(opts.Tail))
+	}
+
+	cmd := exec.CommandContext(ctx, r.Binary, r.args(args...)...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return newCommandReader(cmd, stdout), nil
+}
+
+func (r *Runtime) Exec(ctx context.Context, manifestPath string, projectName string, service string, cmdArgs []string) (int, error) {
+	pod, err := r.readyPod(ctx, k8s.AppName(projectName, service))
+	if err != nil {
+		return 1, err
+	}
+
+	args := append(r.args("exec", pod, "--"), cmdArgs...)
+	cmd := exec.CommandContext(ctx, r.Binary, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		if exit, ok := err.(*exec.ExitError); ok {
+			return exit.ExitCode(), nil
+		}
+		return 1, err
+	}
+	return 0, nil
+}
+
+// Attach runs cmd with a TTY in a ready pod of the service.
+func (r *Runtime) Attach(ctx context.Context, manifestPath string, projectName string, service string, cmdArgs []string) error {
+	pod, err := r.readyPod(ctx, k8s.AppName(projectName, service))
+	if err != nil {
+		return err
+	}
+
+	args := append(r.args("exec", "-it", pod, "--"), cmdArgs...)
+	cmd := exec.CommandContext(ctx, r.Binary, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func (r *Runtime) Status(ctx context.Context, manifestPath string, projectName string) ([]runtime.ServiceStatus, error) {
+	workloads, err := r.workloads(ctx, projectName)
+	if err != nil {
+		return nil, err
+	}
+	apps := make([]string, 0, len(workloads))
+	for _, d := range workloads {
+		apps = append(apps, d.app())
+	}
+	pods, err := r.pods(ctx, apps...)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := k8s.AppPrefix(projectName)
+	var results []runtime.ServiceStatus
+	for _, d := range workloads {
+		app := d.app()
+		startedAt, restarts := podUptime(pods, app)
+		results = append(results, runtime.ServiceStatus{
+			Name:      strings.TrimPrefix(app, prefix),
+			State:     podState(pods, app),
+			Health:    d.health(),
+			Ports:     d.ports(),
+			StartedAt: startedAt,
+			Restarts:  restarts,
+		})
+	}
+	return results, nil
+}
+
+// args prepends the cluster and namespace selection to a kubectl argument
+// list.
+func (r *Runtime) args(args ...string) []string {
+	if r.Namespace != "" {
+		args = append([]string{"--namespace", r.Namespace}, args...)
+	}
+	return r.Cluster.Args(args...)
+}
+
+type objectMeta struct {
+	Name   string            `json:"name"`
+	Labels map[string]string `json:"labels"`
+}
+
+// workload holds the fields devx reads from a Deployment or StatefulSet.
+type workload struct {
+	Metadata objectMeta `json:"metadata"`
+	Spec     struct {
+		Replicas *int `json:"replicas"`
+		Template struct {
+			Spec struct {
+				Containers []struct {
+					Ports []struct {
+						ContainerPort int    `json:"containerPort"`
+						Protocol      string `json:"protocol"`
+					} `json:"ports"`
+				} `json:"containers"`
+			} `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+	Status struct {
+		ReadyReplicas int `json:"readyReplicas"`
+	} `json:"status"`
+}
+
+func (d workload) app() string {
+	if app := d.Metadata.Labels["app"]; app != "" {
+		return app
+	}
+	return d.Metadata.Name
+}
+
+func (d workload) health() string {
+	desired := 1
+	if d.Spec.Replicas != nil {
+		desired = *d.Spec.Replicas
+	}
+	switch {
+	case desired == 0:
+		return "stopped"
+	case d.Status.ReadyReplicas >= desired:
+		return "healthy"
+	case d.Status.ReadyReplicas > 0:
+		return fmt.Sprintf("degraded (%d/%d ready)", d.Status.ReadyReplicas, desired)
+	default:
+		return "starting"
+	}
+}
+
+func (d workload) ports() string {
+	var ports []string
+	for _, c := range d.Spec.Template.Spec.Containers {
+		for _, p := range c.Ports {
+			protocol := p.Protocol
+			if protocol == "" {
+				protocol = "TCP"
+			}
+			ports = append(ports, fmt.Sprintf("%d/%s", p.ContainerPort, protocol))
+		}
+	}
+	return strings.Join(ports, ", ")
+}
+
+type pod struct {
+	Metadata objectMeta `json:"metadata"`
+	Status   struct {
+		Phase      string `json:"phase"`
+		Conditions []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+		ContainerStatuses []struct {
+			RestartCount int `json:"restartCount"`
+			State        struct {
+				Waiting *struct {
+					Reason string `json:"reason"`
+				} `json:"waiting"`
+				Running *struct {
+					StartedAt time.Time `json:"startedAt"`
+				} `json:"running"`
+			} `json:"state"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+func (p pod) ready() bool {
+	for _, c := range p.Status.Conditions {
+		if c.Type == "Ready" {
+			return c.Status == "True"
+		}
+	}
+	return false
+}
+
+// podState summarises the pods behind an app label: the waiting reason of a
+// failing container (e.g. CrashLoopBackOff) wins over the pod phase.
+func podState(pods []pod, app string) string {
+	state := "missing"
+	for _, p := range pods {
+		if p.Metadata.Labels["app"] != app {
+			continue
+		}
+		for _, cs := range p.Status.ContainerStatuses {
+			if cs.State.Waiting != nil && cs.State.Waiting.Reason != "" {
+				return cs.State.Waiting.Reason
+			}
+		}
+		phase := strings.ToLower(p.Status.Phase)
+		if state == "missing" || phase == "running" {
+			state = phase
+		}
+	}
+	return state
+}
+
+// podUptime returns when the longest-running container behind an app label
+// started and how often its containers restarted in total.
+func podUptime(pods []pod, app string) (time.Time, int) {
+	var startedAt time.Time
+	restarts := 0
+	for _, p := range pods {
+		if p.Metadata.Labels["app"] != app {
+			continue
+		}
+		for _, cs := range p.Status.ContainerStatuses {
+			restarts += cs.RestartCount
+			if running := cs.State.Running; running != nil && (startedAt.IsZero() || running.StartedAt.Before(startedAt)) {
+				startedAt = running.StartedAt
+			}
+		}
+	}
+	return startedAt, restarts
+}
+
+// workloads returns the Deployments and StatefulSets devx applied for the
+// project, selected by their ownership labels: an app prefix would also match
+// the workloads of a project whose name extends this one.
+func (r *Runtime) workloads(ctx context.Context, projectName string) ([]workload, error) {
+	var list struct {
+		Items []workload `json:"items"`
+	}
+	if err := r.getJSON(ctx, &list, "get", "deployments,statefulsets", "-l", k8s.OwnerSelector(projectName), "-o", "json"); err != nil {
+		return nil, err
+	}
+
+	out := list.Items
+	sort.Slice(out, func(i, j int) bool { return out[i].app() < out[j].app() })
+	return out, nil
+}
+
+// pods returns the pods with one of the given app labels.
+func (r *Runtime) pods(ctx context.Context, apps ...string) ([]pod, error) {
+	if len(apps) == 0 {
+		return nil, nil
+	}
+	args := []string{"get", "pods", "-o", "json", "-l", "app in (" + strings.Join(apps, ",") + ")"}
+	var list struct {
+		Items []pod `json:"items"`
+	}
+	if err := r.getJSON(ctx, &list, args...); err != nil {
+		return nil, err
+	}
+	return list.Items, nil
+}
+
+func (r *Runtime) readyPod(ctx context.Context, app string) (string, error) {
+	pods, err := r.pods(ctx, app)
+	if err != nil {
+		return "", err
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Metadata.Name < pods[j].Metadata.Name })
+	for _, p := range pods {
+		if p.ready() {
+			return p.Metadata.Name, nil
+		}
+	}
+	return "", fmt.Errorf("no ready pod found for app=%s", app)
+}
+
+func (r *Runtime) getJSON(ctx context.Context, v any, args ...string) error {
+	cmd := exec.CommandContext(ctx, r.Binary, r.args(args...)...)
+	out, err := cmd.Output()
+	if err != nil {
+		if exit, ok := err.(*exec.ExitError); ok && len(exit.Stderr) > 0 {
+			return fmt.Errorf("kubectl %s: %s", strings.Join(args[:2], " "), strings.TrimSpace(string(exit.Stderr)))
+		}
+		return err
+	}
+	return json.Unmarshal(out, v)
+}
+
+func run(ctx context.Context, binary string, args ...string) error {
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+type commandReader struct {
+	cmd *exec.Cmd
+	rc  io.ReadCloser
+}
+
+func newCommandReader(cmd *exec.Cmd, rc io.ReadCloser) io.ReadCloser {
+	return &commandReader{cmd: cmd, rc: rc}
+}
+
+func (c *commandReader) Read(p []byte) (int, error) {
+	return c.rc.Read(p)
+}
+
+// Close stops the command and reaps it.
+func (c *commandReader) Close() error {
+	_ = c.cmd.Process.Kill()
+	err := c.rc.Close()
+	_ = c.cmd.Wait()
+	return err
+}
@@ -0,0 +1,142 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	devxruntime "github.com/dever-labs/devx/internal/runtime"
+)
+
+func TestDeploymentHealth(t *testing.T) {
+	var d workload
+	data := `{"metadata":{"name":"my-app-api","labels":{"app":"my-app-api"}},"spec":{"replicas":2},"status":{"readyReplicas":1}}`
+	if err := json.Unmarshal([]byte(data), &d); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+	if got := d.health(); got != "degraded (1/2 ready)" {
+		t.Fatalf("unexpected health %q", got)
+	}
+
+	d.Status.ReadyReplicas = 2
+	if got := d.health(); got != "healthy" {
+		t.Fatalf("unexpected health %q", got)
+	}
+}
+
+func TestPodState(t *testing.T) {
+	var list struct {
+		Items []pod `json:"items"`
+	}
+	data := `{"items":[
+		{"metadata":{"name":"api-1","labels":{"app":"my-app-api"}},"status":{"phase":"Running","conditions":[{"type":"Ready","status":"True"}]}},
+		{"metadata":{"name":"db-1","labels":{"app":"my-app-db"}},"status":{"phase":"Running","containerStatuses":[{"state":{"waiting":{"reason":"CrashLoopBackOff"}}}]}}
+	]}`
+	if err := json.Unmarshal([]byte(data), &list); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	if got := podState(list.Items, "my-app-api"); got != "running" {
+		t.Fatalf("unexpected api state %q", got)
+	}
+	if got := podState(list.Items, "my-app-db"); got != "CrashLoopBackOff" {
+		t.Fatalf("unexpected db state %q", got)
+	}
+	if got := podState(list.Items, "my-app-worker"); got != "missing" {
+		t.Fatalf("unexpected worker state %q", got)
+	}
+	if !list.Items[0].ready() || list.Items[1].ready() {
+		t.Fatalf("unexpected readiness")
+	}
+}
+
+func TestPodUptime(t *testing.T) {
+	var list struct {
+		Items []pod `json:"items"`
+	}
+	data := `{"items":[
+		{"metadata":{"name":"api-1","labels":{"app":"my-app-api"}},"status":{"containerStatuses":[{"restartCount":2,"state":{"running":{"startedAt":"2024-05-01T10:00:00Z"}}}]}},
+		{"metadata":{"name":"api-2","labels":{"app":"my-app-api"}},"status":{"containerStatuses":[{"restartCount":1,"state":{"running":{"startedAt":"2024-05-01T09:00:00Z"}}}]}},
+		{"metadata":{"name":"db-1","labels":{"app":"my-app-db"}},"status":{"containerStatuses":[{"restartCount":5,"state":{"waiting":{"reason":"CrashLoopBackOff"}}}]}}
+	]}`
+	if err := json.Unmarshal([]byte(data), &list); err != nil {
+		t.Fatalf("unmarshal failed: %v", err)
+	}
+
+	startedAt, restarts := podUptime(list.Items, "my-app-api")
+	if want := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC); !startedAt.Equal(want) || restarts != 3 {
+		t.Fatalf("unexpected api uptime %v, %d restarts", startedAt, restarts)
+	}
+	startedAt, restarts = podUptime(list.Items, "my-app-db")
+	if !startedAt.IsZero() || restarts != 5 {
+		t.Fatalf("unexpected db uptime %v, %d restarts", startedAt, restarts)
+	}
+}
+
+func TestStatusSelectsProjectWorkloads(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as kubectl")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	// A namespace shared with the project shop-admin, whose workloads the
+	// label selector leaves out.
+	script := "#!/bin/sh\necho \"$@\" >> " + log + "\ncase \"$*\" in\n" +
+		"*deployments*) echo '{\"items\":[{\"metadata\":{\"name\":\"shop-api\",\"labels\":{\"app\":\"shop-api\"}},\"status\":{\"readyReplicas\":1}}]}';;\n" +
+		"*pods*) echo '{\"items\":[{\"metadata\":{\"name\":\"shop-api-1\",\"labels\":{\"app\":\"shop-api\"}},\"status\":{\"phase\":\"Running\"}}]}';;\n" +
+		"esac\n"
+	kubectl := filepath.Join(dir, "kubectl")
+	if err := os.WriteFile(kubectl, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Runtime{Binary: kubectl, Namespace: "dev"}
+	statuses, err := r.Status(context.Background(), "", "shop")
+	if err != nil {
+		t.Fatalf("status failed: %v", err)
+	}
+	if len(statuses) != 1 || statuses[0].Name != "api" || statuses[0].State != "running" {
+		t.Errorf("unexpected statuses %+v", statuses)
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := string(data)
+	if !strings.Contains(calls, "get deployments,statefulsets -l app.kubernetes.io/managed-by=devx,app.kubernetes.io/part-of=shop -o json") {
+		t.Errorf("expected workloads selected by owner labels, got\n%s", calls)
+	}
+	if !strings.Contains(calls, "get pods -o json -l app in (shop-api)") {
+		t.Errorf("expected pods selected by app label, got\n%s", calls)
+	}
+}
+
+func TestLogsCloseReapsKubectl(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as kubectl")
+	}
+	kubectl := filepath.Join(t.TempDir(), "kubectl")
+	if err := os.WriteFile(kubectl, []byte("#!/bin/sh\necho started\nexec sleep 60\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Runtime{Binary: kubectl}
+	reader, err := r.Logs(context.Background(), "", "shop", devxruntime.LogsOptions{Service: "api", Follow: true})
+	if err != nil {
+		t.Fatalf("logs failed: %v", err)
+	}
+	buf := make([]byte, 7)
+	if _, err := reader.Read(buf); err != nil {
+		t.Fatal(err)
+	}
+	reader.Close()
+	if cmd := reader.(*commandReader).cmd; cmd.ProcessState == nil {
+		t.Error("expected kubectl to be waited for after close")
+	}
+}
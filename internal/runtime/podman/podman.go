@@ -1,15 +1,15 @@
 package podman
 
 import (
-	"bufio"
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/dever-labs/devx/internal/runtime"
 )
@@ -43,17 +43,36 @@ func (r *Runtime) Up(ctx context.Context, composePath string, projectName string
 	if opts.Pull {
 		args = append(args, "--pull", "always")
 	}
+	args = append(args, opts.Services...)
 	return run(ctx, r.Binary, args...)
 }
 
-func (r *Runtime) Down(ctx context.Context, composePath string, projectName string, removeVolumes bool) error {
+func (r *Runtime) Down(ctx context.Context, composePath string, projectName string, opts runtime.DownOptions) error {
+	if len(opts.Services) > 0 {
+		args := []string{"compose", "-f", composePath, "-p", projectName, "rm", "--stop", "--force"}
+		if opts.RemoveVolumes {
+			args = append(args, "--volumes")
+		}
+		return run(ctx, r.Binary, append(args, opts.Services...)...)
+	}
+
 	args := []string{"compose", "-f", composePath, "-p", projectName, "down"}
-	if removeVolumes {
+	if opts.RemoveVolumes {
 		args = append(args, "--volumes")
 	}
 	return run(ctx, r.Binary, args...)
 }
 
+func (r *Runtime) Restart(ctx context.Context, composePath string, projectName string, services []string) error {
+	args := []string{"compose", "-f", composePath, "-p", projectName, "restart"}
+	return run(ctx, r.Binary, append(args, services...)...)
+}
+
+func (r *Runtime) Sync(ctx context.Context, composePath string, projectName string, service string, src string, dst string) error {
+	args := []string{"compose", "-f", composePath, "-p", projectName, "cp", src, service + ":" + dst}
+	return run(ctx, r.Binary, args...)
+}
+
 func (r *Runtime) Logs(ctx context.Context, composePath string, projectName string, opts runtime.LogsOptions) (io.ReadCloser, error) {
 	args := []string{"compose", "-f", composePath, "-p", projectName, "logs", "--timestamps"}
 	if opts.Follow {
@@ -62,6 +81,9 @@ func (r *Runtime) Logs(ctx context.Context, composePath string, projectName stri
 	if opts.Since != "" {
 		args = append(args, "--since", opts.Since)
 	}
+	if opts.Tail > 0 {
+		args = append(args, "--tail", strconv.Itoa(opts.Tail))
+	}
 	if opts.Service != "" {
 		args = append(args, opts.Service)
 	}
@@ -117,6 +139,7 @@ func (r *Runtime) Status(ctx context.Context, composePath string, projectName st
 	}
 
 	var results []runtime.ServiceStatus
+	var ids []string
 	for _, entry := range entries {
 		name, _ := entry["Service"].(string)
 		state, _ := entry["State"].(string)
@@ -137,42 +160,56 @@ func (r *Runtime) Status(ctx context.Context, composePath string, projectName st
 			Ports:      ports,
 			Publishers: publishers,
 		})
+		if id, _ := entry["ID"].(string); id != "" {
+			ids = append(ids, id)
+		}
+	}
+
+	// Uptime and restarts are not part of `compose ps`; they are best effort.
+	if len(ids) == len(results) && len(ids) > 0 {
+		if containers, err := r.inspect(ctx, ids); err == nil {
+			for i, id := range ids {
+				for _, c := range containers {
+					if strings.HasPrefix(c.ID, id) {
+						results[i].StartedAt = c.State.StartedAt
+						results[i].Restarts = c.RestartCount
+					}
+				}
+			}
+		}
 	}
 
 	return results, nil
 }
 
-func (r *Runtime) ResolveImageDigest(ctx context.Context, image string) (string, error) {
-	digest, err := resolveRepoDigest(ctx, r.Binary, image)
-	if err == nil {
-		return digest, nil
-	}
-
-	if err := run(ctx, r.Binary, "pull", image); err != nil {
-		return "", err
-	}
+// Attach runs cmd in the service with a TTY and the caller's stdio.
+func (r *Runtime) Attach(ctx context.Context, composePath string, projectName string, service string, cmdArgs []string) error {
+	args := []string{"compose", "-f", composePath, "-p", projectName, "exec", service}
+	cmd := exec.CommandContext(ctx, r.Binary, append(args, cmdArgs...)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
 
-	return resolveRepoDigest(ctx, r.Binary, image)
+type container struct {
+	ID           string `json:"Id"`
+	RestartCount int    `json:"RestartCount"`
+	State        struct {
+		StartedAt time.Time `json:"StartedAt"`
+	} `json:"State"`
 }
 
-func resolveRepoDigest(ctx context.Context, binary string, image string) (string, error) {
-	cmd := exec.CommandContext(ctx, binary, "image", "inspect", "--format", "{{join .RepoDigests \"\\n\"}}", image)
-	out, err := cmd.Output()
+func (r *Runtime) inspect(ctx context.Context, ids []string) ([]container, error) {
+	out, err := exec.CommandContext(ctx, r.Binary, append([]string{"inspect"}, ids...)...).Output()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	scanner := bufio.NewScanner(bytes.NewReader(out))
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-		parts := strings.Split(line, "@")
-		if len(parts) == 2 {
-			return parts[1], nil
-		}
+	var containers []container
+	if err := json.Unmarshal(out, &containers); err != nil {
+		return nil, err
 	}
-	return "", fmt.Errorf("no digest found for %s", image)
+	return containers, nil
 }
 
 func run(ctx context.Context, binary string, args ...string) error {
@@ -195,7 +232,10 @@ func (c *commandReader) Read(p []byte) (int, error) {
 	return c.rc.Read(p)
 }
 
+// Close stops the command and reaps it.
 func (c *commandReader) Close() error {
 	_ = c.cmd.Process.Kill()
-	return c.rc.Close()
+	err := c.rc.Close()
+	_ = c.cmd.Wait()
+	return err
 }
@@ -4,18 +4,30 @@ import (
 	"context"
 	"errors"
 	"io"
+	"time"
 )
 
 type UpOptions struct {
 	Build bool
 	Pull  bool
+	// Services limits the command to these services; empty means all.
+	Services []string
+}
+
+type DownOptions struct {
+	RemoveVolumes bool
+	// Services limits the command to these services; empty means all.
+	Services []string
 }
 
 type LogsOptions struct {
 	Service string
 	Follow  bool
 	Since   string
-	JSON    bool
+	// Tail limits the output to the last Tail lines of each container; zero
+	// means all.
+	Tail int
+	JSON bool
 }
 
 type ServiceStatus struct {
@@ -24,6 +36,11 @@ type ServiceStatus struct {
 	Health     string
 	Ports      string
 	Publishers []Publisher
+	// StartedAt is when the service's container last started; zero if the
+	// runtime does not report it.
+	StartedAt time.Time
+	// Restarts counts the restarts of the service's containers.
+	Restarts int
 }
 
 // Publisher represents an actual host-port binding as reported by the container runtime.
@@ -38,14 +55,26 @@ type Runtime interface {
 	Name() string
 	Detect(ctx context.Context) (bool, error)
 	Up(ctx context.Context, composePath string, projectName string, opts UpOptions) error
-	Down(ctx context.Context, composePath string, projectName string, removeVolumes bool) error
+	Down(ctx context.Context, composePath string, projectName string, opts DownOptions) error
 	Logs(ctx context.Context, composePath string, projectName string, opts LogsOptions) (io.ReadCloser, error)
 	Exec(ctx context.Context, composePath string, projectName string, service string, cmd []string) (int, error)
 	Status(ctx context.Context, composePath string, projectName string) ([]ServiceStatus, error)
 }
 
-type DigestResolver interface {
-	ResolveImageDigest(ctx context.Context, image string) (string, error)
+// Restarter is implemented by runtimes that can restart services in place.
+type Restarter interface {
+	Restart(ctx context.Context, composePath string, projectName string, services []string) error
+}
+
+// Syncer is implemented by runtimes that can copy files into a running service.
+type Syncer interface {
+	Sync(ctx context.Context, composePath string, projectName string, service string, src string, dst string) error
+}
+
+// Attacher is implemented by runtimes that can run an interactive command in
+// a service, attached to the caller's terminal.
+type Attacher interface {
+	Attach(ctx context.Context, composePath string, projectName string, service string, cmd []string) error
 }
 
 type RuntimeInfo struct {
@@ -0,0 +1,74 @@
+// Package secrets resolves the values of secrets declared in devx.yaml.
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+
+	"github.com/dever-labs/devx/internal/config"
+)
+
+// Resolve returns the value of a secret. File paths are resolved from
+// baseDir. A single trailing newline is stripped from file and command output.
+func Resolve(ctx context.Context, baseDir string, secret config.Secret) ([]byte, error) {
+	switch {
+	case secret.File != "":
+		path := secret.File
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		return trimNewline(data), nil
+	case secret.Env != "":
+		value, ok := os.LookupEnv(secret.Env)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", secret.Env)
+		}
+		return []byte(value), nil
+	case secret.Command != "":
+		var cmd *exec.Cmd
+		if goruntime.GOOS == "windows" {
+			cmd = exec.CommandContext(ctx, "cmd", "/c", secret.Command)
+		} else {
+			cmd = exec.CommandContext(ctx, "sh", "-c", secret.Command)
+		}
+		cmd.Stderr = os.Stderr
+		out, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("command failed: %w", err)
+		}
+		return trimNewline(out), nil
+	default:
+		return nil, fmt.Errorf("no source configured")
+	}
+}
+
+// ResolveAll resolves the named secrets of a manifest.
+func ResolveAll(ctx context.Context, baseDir string, manifest *config.Manifest, names []string) (map[string][]byte, error) {
+	values := make(map[string][]byte, len(names))
+	for _, name := range names {
+		secret, ok := manifest.Secrets[name]
+		if !ok {
+			return nil, fmt.Errorf("secret '%s' is not defined", name)
+		}
+		value, err := Resolve(ctx, baseDir, secret)
+		if err != nil {
+			return nil, fmt.Errorf("secret '%s': %w", name, err)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+func trimNewline(data []byte) []byte {
+	data = bytes.TrimSuffix(data, []byte("\n"))
+	return bytes.TrimSuffix(data, []byte("\r"))
+}
@@ -0,0 +1,37 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/dever-labs/devx/internal/config"
+)
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "pw.txt"), []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("DEVX_TEST_SECRET", "from-env")
+
+	ctx := context.Background()
+	cases := map[string]config.Secret{
+		"from-file": {File: "pw.txt"},
+		"from-env":  {Env: "DEVX_TEST_SECRET"},
+	}
+	for want, secret := range cases {
+		got, err := Resolve(ctx, dir, secret)
+		if err != nil {
+			t.Fatalf("%s: resolve failed: %v", want, err)
+		}
+		if string(got) != want {
+			t.Fatalf("expected %q, got %q", want, got)
+		}
+	}
+
+	if _, err := Resolve(ctx, dir, config.Secret{Env: "DEVX_TEST_UNSET_SECRET"}); err == nil {
+		t.Fatalf("expected error for unset env var")
+	}
+}
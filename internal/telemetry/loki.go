@@ -0,0 +1,155 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Loki is a client for the Loki HTTP API.
+type Loki struct {
+	// URL is the base URL of the API, e.g. http://localhost:3100.
+	URL  string
+	HTTP *http.Client
+}
+
+// NewLoki returns a client for the Loki at baseURL.
+func NewLoki(baseURL string) *Loki {
+	return &Loki{URL: baseURL, HTTP: http.DefaultClient}
+}
+
+// LogLine is a log line with the labels of its stream.
+type LogLine struct {
+	Labels map[string]string
+	Time   time.Time
+	Line   string
+}
+
+// lokiStream is a stream of query_range and tail results.
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	// Values are [<unix nanoseconds>, <line>] pairs.
+	Values [][2]string `json:"values"`
+}
+
+func (s lokiStream) lines() ([]LogLine, error) {
+	lines := make([]LogLine, 0, len(s.Values))
+	for _, v := range s.Values {
+		ns, err := strconv.ParseInt(v[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("loki: invalid timestamp %q", v[0])
+		}
+		lines = append(lines, LogLine{Labels: s.Stream, Time: time.Unix(0, ns), Line: strings.TrimSuffix(v[1], "\n")})
+	}
+	return lines, nil
+}
+
+// QueryRange returns the newest limit lines a LogQL log query selects
+// between start and end, oldest first.
+func (l *Loki) QueryRange(ctx context.Context, query string, start time.Time, end time.Time, limit int) ([]LogLine, error) {
+	params := url.Values{
+		"query":     {query},
+		"start":     {strconv.FormatInt(start.UnixNano(), 10)},
+		"end":       {strconv.FormatInt(end.UnixNano(), 10)},
+		"limit":     {strconv.Itoa(limit)},
+		"direction": {"backward"},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.URL+"/loki/api/v1/query_range?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := l.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		// Loki explains bad queries in a plain text body.
+		return nil, fmt.Errorf("loki query: %s", strings.TrimSpace(string(data)))
+	}
+	var body struct {
+		Data struct {
+			ResultType string       `json:"resultType"`
+			Result     []lokiStream `json:"result"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("loki query: %w", err)
+	}
+	if body.Data.ResultType != "streams" {
+		return nil, fmt.Errorf("loki query: expected a log query, got a %s result", body.Data.ResultType)
+	}
+
+	var lines []LogLine
+	for _, stream := range body.Data.Result {
+		streamLines, err := stream.lines()
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, streamLines...)
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time.Before(lines[j].Time) })
+	return lines, nil
+}
+
+// Tail streams the lines a LogQL log query selects from start on to fn,
+// until ctx is done or fn fails.
+func (l *Loki) Tail(ctx context.Context, query string, start time.Time, fn func(LogLine) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	params := url.Values{
+		"query": {query},
+		"start": {strconv.FormatInt(start.UnixNano(), 10)},
+	}
+	ws, err := dialWebSocket(ctx, l.URL+"/loki/api/v1/tail?"+params.Encode())
+	if err != nil {
+		return fmt.Errorf("loki tail: %w", err)
+	}
+	defer ws.Close()
+
+	for {
+		message, err := ws.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil || err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("loki tail: %w", err)
+		}
+		// Entries Loki drops when the client falls behind are only counted
+		// in dropped_entries; they are skipped.
+		var body struct {
+			Streams []lokiStream `json:"streams"`
+		}
+		if err := json.Unmarshal(message, &body); err != nil {
+			return fmt.Errorf("loki tail: %w", err)
+		}
+
+		var lines []LogLine
+		for _, stream := range body.Streams {
+			streamLines, err := stream.lines()
+			if err != nil {
+				return err
+			}
+			lines = append(lines, streamLines...)
+		}
+		sort.SliceStable(lines, func(i, j int) bool { return lines[i].Time.Before(lines[j].Time) })
+		for _, line := range lines {
+			if err := fn(line); err != nil {
+				return err
+			}
+		}
+	}
+}
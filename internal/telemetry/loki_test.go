@@ -0,0 +1,129 @@
+package telemetry
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLokiQueryRange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		if r.URL.Path != "/loki/api/v1/query_range" || q.Get("limit") != "50" || q.Get("direction") != "backward" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if q.Get("query") == "{" {
+			http.Error(w, "parse error at line 1, col 2: syntax error", http.StatusBadRequest)
+			return
+		}
+		if q.Get("start") != "1714557600000000000" || q.Get("end") != "1714561200000000000" {
+			http.Error(w, "unexpected range", http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"status":"success","data":{"resultType":"streams","result":[
+			{"stream":{"compose_service":"api"},"values":[["1714557602000000000","second\n"],["1714557600000000000","first"]]},
+			{"stream":{"compose_service":"db"},"values":[["1714557601000000000","ready"]]}]}}`))
+	}))
+	defer srv.Close()
+
+	start := time.Unix(1714557600, 0)
+	lines, err := NewLoki(srv.URL).QueryRange(context.Background(), `{compose_project="shop"}`, start, start.Add(time.Hour), 50)
+	if err != nil {
+		t.Fatalf("query failed: %v", err)
+	}
+	var got []string
+	for _, l := range lines {
+		got = append(got, l.Labels["compose_service"]+":"+l.Line)
+	}
+	if strings.Join(got, ",") != "api:first,db:ready,api:second" {
+		t.Errorf("unexpected lines %v", got)
+	}
+
+	_, err = NewLoki(srv.URL).QueryRange(context.Background(), "{", start, start.Add(time.Hour), 50)
+	if err == nil || !strings.Contains(err.Error(), "syntax error") {
+		t.Errorf("expected Loki's parse error, got %v", err)
+	}
+}
+
+func TestLokiTail(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/tail" || r.URL.Query().Get("query") != `{compose_service="api"}` {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+			"Sec-WebSocket-Accept: " + acceptKey(r.Header.Get("Sec-WebSocket-Key")) + "\r\n\r\n")
+
+		message := `{"streams":[{"stream":{"compose_service":"api"},"values":[["1714557600000000000","hello"]]}]}`
+		// A fragmented message with a ping in between, which must be answered.
+		writeServerFrame(rw, false, opText, []byte(message[:10]))
+		writeServerFrame(rw, true, opPing, []byte("ping"))
+		writeServerFrame(rw, true, opContinuation, []byte(message[10:]))
+		rw.Flush()
+
+		opcode, payload := readClientFrame(t, rw.Reader)
+		if opcode != opPong || string(payload) != "ping" {
+			t.Errorf("expected a pong, got opcode %d %q", opcode, payload)
+		}
+
+		writeServerFrame(rw, true, opText, []byte(`{"streams":[{"stream":{"compose_service":"api"},"values":[["1714557601000000000","`+strings.Repeat("x", 300)+`"]]}]}`))
+		writeServerFrame(rw, true, opClose, nil)
+		rw.Flush()
+		readClientFrame(t, rw.Reader)
+	}))
+	defer srv.Close()
+
+	var got []string
+	err := NewLoki(srv.URL).Tail(context.Background(), `{compose_service="api"}`, time.Now(), func(l LogLine) error {
+		got = append(got, l.Line)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("tail failed: %v", err)
+	}
+	if len(got) != 2 || got[0] != "hello" || len(got[1]) != 300 {
+		t.Errorf("unexpected lines %q", got)
+	}
+
+	err = NewLoki(srv.URL).Tail(context.Background(), `{}`, time.Now(), func(LogLine) error { return nil })
+	if err == nil || !strings.Contains(err.Error(), "400 Bad Request") {
+		t.Errorf("expected a handshake error, got %v", err)
+	}
+}
+
+func writeServerFrame(w io.Writer, fin bool, opcode byte, payload []byte) {
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	frame := []byte{first}
+	if len(payload) < 126 {
+		frame = append(frame, byte(len(payload)))
+	} else {
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	}
+	w.Write(append(frame, payload...))
+}
+
+func readClientFrame(t *testing.T, r *bufio.Reader) (byte, []byte) {
+	ws := &websocket{r: r}
+	_, opcode, payload, err := ws.readFrame()
+	if err != nil {
+		t.Errorf("reading client frame: %v", err)
+	}
+	return opcode, payload
+}
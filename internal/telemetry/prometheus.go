@@ -0,0 +1,119 @@
+// Package telemetry queries the telemetry stack devx runs next to compose
+// profiles over the HTTP APIs of its published services.
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Prometheus is a client for the Prometheus HTTP API.
+type Prometheus struct {
+	// URL is the base URL of the API, e.g. http://localhost:9090.
+	URL  string
+	HTTP *http.Client
+}
+
+// NewPrometheus returns a client for the Prometheus at baseURL.
+func NewPrometheus(baseURL string) *Prometheus {
+	return &Prometheus{URL: baseURL, HTTP: http.DefaultClient}
+}
+
+// Sample is one series of an instant query result.
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Query runs an instant query, which must return a vector.
+func (p *Prometheus) Query(ctx context.Context, query string) ([]Sample, error) {
+	endpoint := p.URL + "/api/v1/query?" + url.Values{"query": {query}}.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			ResultType string `json:"resultType"`
+			Result     []struct {
+				Metric map[string]string `json:"metric"`
+				Value  [2]any            `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return nil, fmt.Errorf("prometheus query: %s returned %s", p.URL, resp.Status)
+	}
+	if body.Status != "success" {
+		return nil, fmt.Errorf("prometheus query: %s", body.Error)
+	}
+	if body.Data.ResultType != "vector" {
+		return nil, fmt.Errorf("prometheus query: expected a vector, got %s", body.Data.ResultType)
+	}
+
+	samples := make([]Sample, 0, len(body.Data.Result))
+	for _, r := range body.Data.Result {
+		// Values are [<unix time>, "<number>"].
+		text, _ := r.Value[1].(string)
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("prometheus query: invalid sample value %q", text)
+		}
+		samples = append(samples, Sample{Labels: r.Metric, Value: value})
+	}
+	return samples, nil
+}
+
+// Usage is the resource usage of a compose service.
+type Usage struct {
+	// CPU is in percent of one core.
+	CPU float64
+	// Memory is in bytes.
+	Memory float64
+}
+
+// ServiceUsage returns the CPU and memory usage of the services of a compose
+// project, keyed by service. cAdvisor only labels its series with container
+// IDs, so they are joined with docker_container_info from the docker-meta
+// exporter, as the Container Resources dashboard does.
+func (p *Prometheus) ServiceUsage(ctx context.Context, project string) (map[string]Usage, error) {
+	info := fmt.Sprintf("docker_container_info{compose_project=%q}", project)
+	cpu, err := p.Query(ctx, `sum by (compose_service) (rate(container_cpu_usage_seconds_total{id=~"/docker/.+"}[1m]) * on(id) group_left(compose_service) `+info+`) * 100`)
+	if err != nil {
+		return nil, err
+	}
+	memory, err := p.Query(ctx, `sum by (compose_service) (container_memory_usage_bytes{id=~"/docker/.+"} * on(id) group_left(compose_service) `+info+`)`)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := map[string]Usage{}
+	for _, s := range cpu {
+		u := usage[s.Labels["compose_service"]]
+		u.CPU = s.Value
+		usage[s.Labels["compose_service"]] = u
+	}
+	for _, s := range memory {
+		u := usage[s.Labels["compose_service"]]
+		u.Memory = s.Value
+		usage[s.Labels["compose_service"]] = u
+	}
+	return usage, nil
+}
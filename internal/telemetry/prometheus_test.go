@@ -0,0 +1,49 @@
+package telemetry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServiceUsage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		if r.URL.Path != "/api/v1/query" || strings.Contains(query, `compose_project="other"`) {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		switch {
+		case strings.Contains(query, "container_cpu_usage_seconds_total"):
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"compose_service":"api"},"value":[1714557600.1,"12.5"]},
+				{"metric":{"compose_service":"db"},"value":[1714557600.1,"0.25"]}]}}`))
+		case strings.Contains(query, "container_memory_usage_bytes"):
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"compose_service":"api"},"value":[1714557600.1,"52428800"]}]}}`))
+		default:
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"unknown metric"}`))
+		}
+	}))
+	defer srv.Close()
+
+	usage, err := NewPrometheus(srv.URL).ServiceUsage(context.Background(), "shop")
+	if err != nil {
+		t.Fatalf("usage failed: %v", err)
+	}
+	if got := usage["api"]; got.CPU != 12.5 || got.Memory != 52428800 {
+		t.Errorf("unexpected api usage %+v", got)
+	}
+	if got := usage["db"]; got.CPU != 0.25 || got.Memory != 0 {
+		t.Errorf("unexpected db usage %+v", got)
+	}
+
+	if _, err := NewPrometheus(srv.URL).Query(context.Background(), "up"); err == nil || !strings.Contains(err.Error(), "unknown metric") {
+		t.Errorf("expected the query error, got %v", err)
+	}
+	if _, err := NewPrometheus(srv.URL).ServiceUsage(context.Background(), "other"); err == nil {
+		t.Error("expected an error for a bad request")
+	}
+}
@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// APIVersion versions the documents commands print with --output json, yaml
+// or template. Fields are only ever added within a version; renaming or
+// removing one requires a new version. schemas/output describes each kind.
+const APIVersion = "devx/v1"
+
+// Output formats accepted by --output.
+const (
+	FormatTable    = "table"
+	FormatJSON     = "json"
+	FormatYAML     = "yaml"
+	FormatTemplate = "template"
+)
+
+// OutputUsage is the help text of --output flags.
+const OutputUsage = "Output format: table, json, yaml or template=<go template>"
+
+// TypeMeta identifies a machine-readable document. It is embedded first in
+// every document so that apiVersion and kind lead the output.
+type TypeMeta struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+}
+
+// Meta returns the TypeMeta of a document of the given kind.
+func Meta(kind string) TypeMeta {
+	return TypeMeta{APIVersion: APIVersion, Kind: kind}
+}
+
+// Printer writes command results in the format selected with --output.
+type Printer struct {
+	Format   string
+	template *template.Template
+}
+
+// NewPrinter parses an --output value. An empty value selects the table.
+func NewPrinter(output string) (*Printer, error) {
+	format, text, hasText := strings.Cut(output, "=")
+	switch format {
+	case "", FormatTable:
+		return &Printer{Format: FormatTable}, nil
+	case FormatJSON, FormatYAML:
+		return &Printer{Format: format}, nil
+	case FormatTemplate:
+		if !hasText || text == "" {
+			return nil, fmt.Errorf("--output template requires a template, e.g. template='{{.kind}}'")
+		}
+		tmpl, err := template.New("output").Funcs(template.FuncMap{
+			"json": func(v any) (string, error) {
+				data, err := json.Marshal(v)
+				return string(data), err
+			},
+			"join": func(sep string, items []any) string {
+				parts := make([]string, len(items))
+				for i, item := range items {
+					parts[i] = fmt.Sprint(item)
+				}
+				return strings.Join(parts, sep)
+			},
+		}).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("--output template: %w", err)
+		}
+		return &Printer{Format: FormatTemplate, template: tmpl}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format '%s' (want table, json, yaml or template=...)", format)
+	}
+}
+
+// Structured reports whether the printer writes a document rather than the
+// human-readable table.
+func (p *Printer) Structured() bool {
+	return p.Format != FormatTable
+}
+
+// Print writes doc, whose fields are named by their json tags, in the
+// printer's format. table writes the human-readable form.
+func (p *Printer) Print(w io.Writer, doc any, table func(io.Writer)) error {
+	switch p.Format {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(doc)
+	case FormatYAML:
+		return writeYAML(w, doc)
+	case FormatTemplate:
+		// Templates see the document as JSON does, so field names match.
+		data, err := json.Marshal(doc)
+		if err != nil {
+			return err
+		}
+		var generic any
+		if err := json.Unmarshal(data, &generic); err != nil {
+			return err
+		}
+		if err := p.template.Execute(w, generic); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w)
+		return err
+	default:
+		table(w)
+		return nil
+	}
+}
+
+// writeYAML writes doc as YAML with the keys and key order of its JSON form.
+func writeYAML(w io.Writer, doc any) error {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	// JSON is YAML, so parsing it keeps the field order; clearing the flow
+	// and quoting styles turns it into block YAML.
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	plainStyle(&node)
+
+	buf := &bytes.Buffer{}
+	enc := yaml.NewEncoder(buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func plainStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, child := range n.Content {
+		plainStyle(child)
+	}
+}
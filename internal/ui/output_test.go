@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+type testDocument struct {
+	TypeMeta
+	Name  string   `json:"name"`
+	Port  string   `json:"port"`
+	Items []string `json:"items"`
+}
+
+func TestPrinter(t *testing.T) {
+	doc := testDocument{TypeMeta: Meta("Test"), Name: "api", Port: "8080", Items: []string{"a", "true"}}
+	table := func(w io.Writer) { io.WriteString(w, "table\n") }
+
+	cases := map[string]string{
+		"":      "table\n",
+		"table": "table\n",
+		"json": `{
+  "apiVersion": "devx/v1",
+  "kind": "Test",
+  "name": "api",
+  "port": "8080",
+  "items": [
+    "a",
+    "true"
+  ]
+}
+`,
+		"yaml": `apiVersion: devx/v1
+kind: Test
+name: api
+port: "8080"
+items:
+  - a
+  - "true"
+`,
+		`template={{.kind}} {{.name}}:{{.port}} {{join "," .items}} {{json .items}}`: "Test api:8080 a,true [\"a\",\"true\"]\n",
+	}
+	for output, want := range cases {
+		printer, err := NewPrinter(output)
+		if err != nil {
+			t.Fatalf("%q: %v", output, err)
+		}
+		var buf bytes.Buffer
+		if err := printer.Print(&buf, doc, table); err != nil {
+			t.Fatalf("%q: print failed: %v", output, err)
+		}
+		if buf.String() != want {
+			t.Errorf("%q: expected\n%s\ngot\n%s", output, want, buf.String())
+		}
+		if printer.Structured() != (output != "" && output != "table") {
+			t.Errorf("%q: unexpected Structured() %v", output, printer.Structured())
+		}
+	}
+
+	for _, output := range []string{"xml", "template", "template={{.name"} {
+		if _, err := NewPrinter(output); err == nil {
+			t.Errorf("%q: expected an error", output)
+		}
+	}
+	if _, err := NewPrinter("template"); err == nil || !strings.Contains(err.Error(), "requires a template") {
+		t.Errorf("expected a missing template error, got %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package ui
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ANSI sequences used by the full-screen views.
+const (
+	enterScreen = "\x1b[?1049h\x1b[?25l" // alternate screen, hide cursor
+	leaveScreen = "\x1b[?25h\x1b[?1049l"
+	clearScreen = "\x1b[H\x1b[2J"
+	reverse     = "\x1b[7m"
+	bold        = "\x1b[1m"
+	reset       = "\x1b[0m"
+)
+
+// IsTerminal reports whether f is a terminal.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Terminal is a full-screen session on the controlling terminal. Keys are
+// read one at a time without echo while Ctrl+C still raises SIGINT. The mode
+// is switched with stty, so where stty is missing (e.g. Windows) the screen
+// still works but ReadKey only waits.
+type Terminal struct {
+	in    *os.File
+	out   io.Writer
+	saved string
+}
+
+// OpenTerminal switches the terminal to the alternate screen and key mode.
+// Close restores it.
+func OpenTerminal() *Terminal {
+	t := &Terminal{in: os.Stdin, out: os.Stdout}
+	if out, err := t.stty("-g"); err == nil {
+		t.saved = strings.TrimSpace(out)
+	}
+	t.Resume()
+	return t
+}
+
+// Keys reports whether ReadKey reads keys.
+func (t *Terminal) Keys() bool {
+	return t.saved != ""
+}
+
+// Suspend restores the terminal for a command that needs it, such as a shell.
+func (t *Terminal) Suspend() {
+	if t.saved != "" {
+		_, _ = t.stty(t.saved)
+	}
+	io.WriteString(t.out, leaveScreen)
+}
+
+// Resume undoes Suspend.
+func (t *Terminal) Resume() {
+	if t.saved != "" {
+		// min 0 time 1: reads return after 100ms even without input.
+		_, _ = t.stty("-icanon", "-echo", "min", "0", "time", "1")
+	}
+	io.WriteString(t.out, enterScreen)
+}
+
+// Close restores the terminal.
+func (t *Terminal) Close() {
+	t.Suspend()
+}
+
+// ReadKey waits up to 100ms for a key and returns it: "up", "down", "enter"
+// or the character typed. It returns "" if no key was pressed.
+func (t *Terminal) ReadKey() (string, error) {
+	if t.saved == "" {
+		time.Sleep(100 * time.Millisecond)
+		return "", nil
+	}
+	buf := make([]byte, 16)
+	n, err := t.in.Read(buf)
+	if errors.Is(err, io.EOF) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return parseKey(buf[:n]), nil
+}
+
+func (t *Terminal) stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = t.in
+	out, err := cmd.Output()
+	return string(out), err
+}
+
+func parseKey(b []byte) string {
+	switch s := string(b); s {
+	case "":
+		return ""
+	case "\x1b[A", "\x1bOA":
+		return "up"
+	case "\x1b[B", "\x1bOB":
+		return "down"
+	case "\r", "\n":
+		return "enter"
+	default:
+		if len(b) == 1 {
+			return s
+		}
+		return ""
+	}
+}
+
+// Dashboard is a full-screen table with a selected row.
+type Dashboard struct {
+	Title    string
+	Headers  []string
+	Rows     [][]string
+	Selected int
+	// Message is shown below the table, e.g. the result of an action.
+	Message string
+	Help    string
+}
+
+// Render redraws the screen with the dashboard.
+func (d Dashboard) Render(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString(clearScreen)
+	fmt.Fprintf(&buf, "%s%s%s\n\n", bold, d.Title, reset)
+	for i, line := range TableLines(d.Headers, d.Rows) {
+		if row := i - 2; row >= 0 && row == d.Selected {
+			line = reverse + line + reset
+		}
+		buf.WriteString(line + "\n")
+	}
+	if d.Message != "" {
+		fmt.Fprintf(&buf, "\n%s\n", d.Message)
+	}
+	if d.Help != "" {
+		fmt.Fprintf(&buf, "\n%s\n", d.Help)
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,48 @@
+package ui
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseKey(t *testing.T) {
+	cases := map[string]string{
+		"":       "",
+		"\x1b[A": "up",
+		"\x1bOB": "down",
+		"\r":     "enter",
+		"l":      "l",
+		"\x1b[C": "",
+		"ll":     "",
+	}
+	for in, want := range cases {
+		if got := parseKey([]byte(in)); got != want {
+			t.Errorf("parseKey(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDashboardRender(t *testing.T) {
+	d := Dashboard{
+		Title:    "shop",
+		Headers:  []string{"Service", "State"},
+		Rows:     [][]string{{"api", "running"}, {"db", "exited"}},
+		Selected: 1,
+		Message:  "Restarted db",
+		Help:     "q quit",
+	}
+	var buf bytes.Buffer
+	if err := d.Render(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := clearScreen + bold + "shop" + reset + "\n\n" +
+		"Service  State  \n" +
+		"                \n" +
+		"api      running\n" +
+		reverse + "db       exited " + reset + "\n" +
+		"\nRestarted db\n" +
+		"\nq quit\n"
+	if buf.String() != want {
+		t.Errorf("expected\n%q\ngot\n%q", want, buf.String())
+	}
+}
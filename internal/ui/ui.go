@@ -7,6 +7,14 @@ import (
 )
 
 func PrintTable(w io.Writer, headers []string, rows [][]string) {
+	for _, line := range TableLines(headers, rows) {
+		fmt.Fprintln(w, line)
+	}
+}
+
+// TableLines formats a table as PrintTable prints it: the header, a blank
+// separator line and one line per row.
+func TableLines(headers []string, rows [][]string) []string {
 	widths := make([]int, len(headers))
 	for i, header := range headers {
 		widths[i] = len(header)
@@ -20,19 +28,18 @@ func PrintTable(w io.Writer, headers []string, rows [][]string) {
 		}
 	}
 
-	writeRow(w, headers, widths)
-	writeRow(w, make([]string, len(headers)), widths)
-
+	lines := []string{formatRow(headers, widths), formatRow(make([]string, len(headers)), widths)}
 	for _, row := range rows {
-		writeRow(w, row, widths)
+		lines = append(lines, formatRow(row, widths))
 	}
+	return lines
 }
 
-func writeRow(w io.Writer, cols []string, widths []int) {
+func formatRow(cols []string, widths []int) string {
 	parts := make([]string, len(cols))
 	for i, col := range cols {
 		pad := widths[i] - len(col)
 		parts[i] = col + strings.Repeat(" ", pad)
 	}
-	fmt.Fprintln(w, strings.Join(parts, "  "))
+	return strings.Join(parts, "  ")
 }
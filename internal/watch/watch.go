@@ -0,0 +1,248 @@
+// Package watch polls files for changes and reports them in debounced
+// batches. Polling keeps devx free of platform-specific notification APIs.
+package watch
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// skipDirs are never descended into: version control, devx state, and
+// dependency and cache directories of common toolchains.
+var skipDirs = map[string]bool{
+	".git": true, ".hg": true, ".svn": true, ".devx": true,
+	"node_modules": true, "bower_components": true,
+	".venv": true, "venv": true, "__pycache__": true, ".pytest_cache": true, ".mypy_cache": true, ".tox": true,
+	".gradle": true,
+}
+
+// Rule watches Path (a file or directory) on behalf of a service.
+type Rule struct {
+	Service string
+	Path    string
+	Action  string
+	Target  string
+	Include []string
+	Exclude []string
+}
+
+// Change is a file below a rule's Path that was created, modified or removed.
+type Change struct {
+	Rule *Rule
+	// Rel is the slash-separated path of the file relative to Rule.Path.
+	Rel     string
+	Removed bool
+}
+
+type Watcher struct {
+	Rules []Rule
+	// Interval is the time between polls and Debounce the quiet period after
+	// the last change before a batch is reported.
+	Interval time.Duration
+	Debounce time.Duration
+}
+
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Run polls until ctx is done, calling fn with each batch of changes. An
+// error returned by fn stops the watcher.
+func (w *Watcher) Run(ctx context.Context, fn func([]Change) error) error {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = 500 * time.Millisecond
+	}
+
+	previous := w.snapshot()
+	pending := map[string]bool{}
+	var lastChange time.Time
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current := w.snapshot()
+		for file, state := range current {
+			if old, ok := previous[file]; !ok || old != state {
+				pending[file] = true
+				lastChange = time.Now()
+			}
+		}
+		for file := range previous {
+			if _, ok := current[file]; !ok {
+				pending[file] = true
+				lastChange = time.Now()
+			}
+		}
+		previous = current
+
+		if len(pending) == 0 || time.Since(lastChange) < w.Debounce {
+			continue
+		}
+		batch := w.changes(pending, current)
+		pending = map[string]bool{}
+		if len(batch) == 0 {
+			continue
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+	}
+}
+
+// snapshot records the state of every file below the rule paths.
+func (w *Watcher) snapshot() map[string]fileState {
+	files := map[string]fileState{}
+	for _, rule := range w.Rules {
+		_ = filepath.WalkDir(rule.Path, func(file string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				if file != rule.Path && (skipDirs[d.Name()] || rule.excludesDir(relPath(rule.Path, file))) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if _, seen := files[file]; seen {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				files[file] = fileState{modTime: info.ModTime(), size: info.Size()}
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// changes maps changed files to the rules that cover them. Each service takes
+// the rule with the most specific path containing the file, so a narrower
+// rule overrides a broader one; the file is dropped if that rule filters it.
+func (w *Watcher) changes(files map[string]bool, current map[string]fileState) []Change {
+	var out []Change
+	for file := range files {
+		best := map[string]*Rule{}
+		for i := range w.Rules {
+			rule := &w.Rules[i]
+			if !within(rule.Path, file) {
+				continue
+			}
+			if cur, ok := best[rule.Service]; !ok || len(rule.Path) > len(cur.Path) {
+				best[rule.Service] = rule
+			}
+		}
+		for _, rule := range best {
+			rel := relPath(rule.Path, file)
+			if !rule.Matches(rel) {
+				continue
+			}
+			_, exists := current[file]
+			out = append(out, Change{Rule: rule, Rel: rel, Removed: !exists})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Rule.Service != out[j].Rule.Service {
+			return out[i].Rule.Service < out[j].Rule.Service
+		}
+		return out[i].Rel < out[j].Rel
+	})
+	return out
+}
+
+// Matches reports whether a path relative to the rule passes its include and
+// exclude globs.
+func (r *Rule) Matches(rel string) bool {
+	for _, pattern := range r.Exclude {
+		if Match(pattern, rel) {
+			return false
+		}
+	}
+	if len(r.Include) == 0 {
+		return true
+	}
+	for _, pattern := range r.Include {
+		if Match(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// excludesDir reports whether an exclude glob matches a directory relative to
+// the rule, in which case the walk skips everything below it.
+func (r *Rule) excludesDir(rel string) bool {
+	for _, pattern := range r.Exclude {
+		if Match(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match reports whether the slash-separated path name matches pattern. `**`
+// matches zero or more directories, and a pattern without `/` is matched
+// against the last element of name.
+func Match(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// within reports whether file is root or below it. Both are compared through
+// filepath.Rel so that a root of "." contains the unprefixed paths WalkDir
+// yields for it.
+func within(root, file string) bool {
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(os.PathSeparator))
+}
+
+func relPath(root, file string) string {
+	if file == root {
+		return filepath.Base(file)
+	}
+	rel, err := filepath.Rel(root, file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,174 @@
+package watch
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.go", "main.go", true},
+		{"*.go", "internal/app/main.go", true},
+		{"*.go", "main_test.txt", false},
+		{"src/*.ts", "src/index.ts", true},
+		{"src/*.ts", "src/lib/index.ts", false},
+		{"src/**/*.ts", "src/index.ts", true},
+		{"src/**/*.ts", "src/lib/deep/index.ts", true},
+		{"**/node_modules/**", "web/node_modules/react/index.js", true},
+		{"**/node_modules/**", "node_modules/react/index.js", true},
+		{"**/node_modules/**", "web/src/index.js", false},
+	}
+	for _, c := range cases {
+		if got := Match(c.pattern, c.name); got != c.want {
+			t.Errorf("Match(%q, %q) = %v, want %v", c.pattern, c.name, got, c.want)
+		}
+	}
+}
+
+func TestWatcherRun(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	if err := os.MkdirAll(src, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(dir, "go.mod"), "module x")
+	writeFile(t, filepath.Join(src, "old.txt"), "old")
+
+	w := &Watcher{
+		Rules: []Rule{
+			{Service: "api", Path: dir, Action: "rebuild"},
+			{Service: "api", Path: src, Action: "sync", Target: "/app", Exclude: []string{"*.tmp"}},
+		},
+		Interval: 10 * time.Millisecond,
+		Debounce: 30 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	batches := make(chan []Change, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run(ctx, func(changes []Change) error {
+			batches <- changes
+			cancel()
+			return nil
+		})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	writeFile(t, filepath.Join(dir, "go.mod"), "module y")
+	writeFile(t, filepath.Join(src, "new.txt"), "new")
+	writeFile(t, filepath.Join(src, "scratch.tmp"), "tmp")
+	if err := os.Remove(filepath.Join(src, "old.txt")); err != nil {
+		t.Fatal(err)
+	}
+
+	var batch []Change
+	select {
+	case batch = <-batches:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for changes")
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+
+	got := map[string]Change{}
+	for _, c := range batch {
+		got[c.Rel] = c
+	}
+	if len(got) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", batch)
+	}
+	if c := got["go.mod"]; c.Rule.Action != "rebuild" {
+		t.Errorf("expected go.mod to trigger rebuild, got %+v", c.Rule)
+	}
+	if c := got["new.txt"]; c.Rule.Action != "sync" || c.Removed {
+		t.Errorf("expected new.txt to be synced, got %+v", c)
+	}
+	if c := got["old.txt"]; !c.Removed {
+		t.Errorf("expected old.txt to be reported removed, got %+v", c)
+	}
+}
+
+func TestSnapshotSkipsDirs(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{
+		"main.js",
+		"node_modules/left-pad/index.js",
+		"dist/bundle.js",
+		"src/app.js",
+		"src/.venv/lib/site.py",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(dir, file)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(dir, file), "x")
+	}
+
+	w := &Watcher{Rules: []Rule{{Service: "web", Path: dir, Action: "rebuild", Exclude: []string{"dist/**"}}}}
+	var got []string
+	for file := range w.snapshot() {
+		got = append(got, relPath(dir, file))
+	}
+	sort.Strings(got)
+	if strings.Join(got, ",") != "main.js,src/app.js" {
+		t.Fatalf("unexpected snapshot: %v", got)
+	}
+}
+
+func TestChangesRelativeRoot(t *testing.T) {
+	dir := t.TempDir()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Chdir(wd) }()
+
+	if err := os.MkdirAll("src", 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join("src", "a.go"), "package a")
+
+	w := &Watcher{Rules: []Rule{
+		{Service: "api", Path: ".", Action: "rebuild"},
+		{Service: "api", Path: "src", Action: "sync", Target: "/app"},
+		{Service: "web", Path: ".", Action: "rebuild", Exclude: []string{"src/**"}},
+	}}
+	current := w.snapshot()
+	files := map[string]bool{}
+	for file := range current {
+		files[file] = true
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected one file in the snapshot, got %v", current)
+	}
+
+	got := w.changes(files, current)
+	if len(got) != 1 || got[0].Rule.Service != "api" || got[0].Rule.Action != "sync" || got[0].Rel != "a.go" {
+		t.Fatalf("expected src/a.go to be synced for api, got %+v", got)
+	}
+	if !within(".", "go.mod") || within(".", "../go.mod") || !within("src", "src/a.go") || within("src", "srcx/a.go") {
+		t.Error("unexpected within result for a relative root")
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}